@@ -0,0 +1,307 @@
+package backend
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	"go.uber.org/zap"
+)
+
+// The format of fetched content is determined from the extension of its file name. Compression formats and archive
+// formats are registered independently so that any combination of the two, e.g. '.tar.zst', is supported. Supporting
+// a new format only requires adding it to the relevant registry below.
+
+var ErrInvalidPackage = errors.New("invalid package")
+
+// decompressor returns a reader of the decompressed content of a stream.
+type decompressor func(src io.Reader) (io.Reader, error)
+
+// unarchiver returns a reader of the entries of an uncompressed archive stream.
+type unarchiver func(log *zap.Logger, src io.Reader) (archiveReader, error)
+
+// compressionFormats maps the file extensions of compression formats to their decompressor. The magic number with
+// which compressed streams start allows to detect the format of content that has no file name, such as the payload of
+// RPM packages.
+var compressionFormats = map[string]struct {
+	magic        []byte
+	decompressor decompressor
+}{
+	".bz2": {
+		magic:        []byte("BZh"),
+		decompressor: func(src io.Reader) (io.Reader, error) { return bzip2.NewReader(src), nil },
+	},
+	".gz": {
+		magic:        []byte{0x1f, 0x8b},
+		decompressor: func(src io.Reader) (io.Reader, error) { return gzip.NewReader(src) },
+	},
+	".xz": {
+		magic:        []byte{0xfd, '7', 'z', 'X', 'Z', 0x00},
+		decompressor: func(src io.Reader) (io.Reader, error) { return xz.NewReader(src) },
+	},
+	".zst": {
+		magic: []byte{0x28, 0xb5, 0x2f, 0xfd},
+		// A single decoder guarantees synchronous decoding so that no background work lingers once the stream is
+		// abandoned.
+		decompressor: func(src io.Reader) (io.Reader, error) {
+			return zstd.NewReader(src, zstd.WithDecoderConcurrency(1))
+		},
+	},
+}
+
+// archiveFormats maps the file extensions of archive formats to their unarchiver. Extensions that are shorthands for a
+// compressed archive, such as '.tgz', also record the extension of the compression format.
+var archiveFormats = map[string]struct {
+	unarchiver  unarchiver
+	compression string
+}{
+	".deb":  {unarchiver: newDebReader},
+	".rpm":  {unarchiver: newRPMReader},
+	".tar":  {unarchiver: newTARReader},
+	".tbz":  {unarchiver: newTARReader, compression: ".bz2"},
+	".tbz2": {unarchiver: newTARReader, compression: ".bz2"},
+	".tgz":  {unarchiver: newTARReader, compression: ".gz"},
+	".txz":  {unarchiver: newTARReader, compression: ".xz"},
+	".tzst": {unarchiver: newTARReader, compression: ".zst"},
+	".zip":  {unarchiver: newZIPReader},
+}
+
+// sniffCompression returns the extension of the compression format of the content starting with the given bytes, or an
+// empty string if it is not compressed with any known format.
+func sniffCompression(head []byte) string {
+	for ext, c := range compressionFormats {
+		if bytes.HasPrefix(head, c.magic) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// archiveReader iterates over the entries of an archive. Reads return the content of the current entry.
+type archiveReader interface {
+	io.ReadCloser
+	// Next advances to the next entry of the archive. It returns io.EOF once all entries have been read.
+	Next() (*archiveEntry, error)
+}
+
+type archiveEntry struct {
+	Name string
+	Mode fs.FileMode
+	// Linkname is the target of a symbolic link. For a regular file it designates a hard link to an earlier entry.
+	Linkname string
+}
+
+// contentFormat describes how fetched content is compressed and / or archived.
+type contentFormat struct {
+	compression string
+	unarchiver  unarchiver
+}
+
+func detectFormat(srcPath string) contentFormat {
+	var f contentFormat
+
+	name := strings.ToLower(srcPath)
+	if ext := path.Ext(name); compressionFormats[ext].decompressor != nil {
+		f.compression, name = ext, strings.TrimSuffix(name, ext)
+	}
+	if a, ok := archiveFormats[path.Ext(name)]; ok {
+		f.unarchiver = a.unarchiver
+		if f.compression == "" {
+			f.compression = a.compression
+		}
+	}
+	return f
+}
+
+func (f contentFormat) decompress(log *zap.Logger, src io.Reader) (io.Reader, error) {
+	if f.compression == "" {
+		return src, nil
+	}
+
+	log = log.With(zap.String("compression", f.compression))
+	log.Debug("Decompressing the fetched content.")
+	rd, err := compressionFormats[f.compression].decompressor(src)
+	if err != nil {
+		log.Error("Failed to decompress the fetched content.", zap.Error(err))
+		return nil, fmt.Errorf("failed to open %s decompressor for fetched content: %w", f.compression, err)
+	}
+	return rd, nil
+}
+
+// open returns a reader of the entries of the fetched content. Closing it does not close the fetched content.
+func (f contentFormat) open(log *zap.Logger, src io.Reader) (archiveReader, error) {
+	if f.unarchiver == nil {
+		return nil, fmt.Errorf("unrecognised archive format: %w", errors.ErrUnsupported)
+	}
+	rd, err := f.decompress(log, src)
+	if err != nil {
+		return nil, err
+	}
+	return f.unarchiver(log, rd)
+}
+
+// findArchiveFile returns the content and the path of the regular file in the archive whose path matches the pattern.
+// Patterns without globs are streamed directly from the archive. Otherwise the whole archive needs to be scanned to
+// guarantee that the match is unique, which requires the content of the match to be spooled to a temporary file.
+func findArchiveFile(ar archiveReader, p namePattern) (io.ReadCloser, string, error) {
+	var (
+		candidates []string
+		matches    []string
+		spool      *tempFile
+	)
+	for {
+		e, err := ar.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			if spool != nil {
+				_ = spool.Close()
+			}
+			return nil, "", fmt.Errorf("failed to search for path in fetched content: %w", err)
+		}
+		if !e.Mode.IsRegular() || e.Linkname != "" {
+			continue
+		}
+
+		name := path.Clean(e.Name)
+		candidates = append(candidates, name)
+		if !p.match(name) {
+			continue
+		}
+		if !p.isGlob() {
+			return io.NopCloser(ar), name, nil
+		}
+
+		if matches = append(matches, name); len(matches) == 1 {
+			if spool, _, err = spoolToTempFile(ar); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, "", fmt.Errorf("failed to find path in fetched content: %w", p.noMatchError(candidates))
+	case 1:
+		return spool, matches[0], nil
+	default:
+		_ = spool.Close()
+		return nil, "", fmt.Errorf("failed to find path in fetched content: %w", p.ambiguousMatchError(matches))
+	}
+}
+
+type tarReader struct {
+	*tar.Reader
+}
+
+func newTARReader(log *zap.Logger, src io.Reader) (archiveReader, error) {
+	log.Debug("Reading the fetched content as a TAR archive.")
+	return &tarReader{Reader: tar.NewReader(src)}, nil
+}
+
+func (r *tarReader) Next() (*archiveEntry, error) {
+	for {
+		hdr, err := r.Reader.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		e := &archiveEntry{Name: hdr.Name, Mode: fs.FileMode(hdr.Mode) & fs.ModePerm, Linkname: hdr.Linkname}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			e.Mode |= fs.ModeDir
+		case tar.TypeReg, tar.TypeRegA: //nolint:staticcheck // Older archives still use the deprecated type.
+			e.Linkname = ""
+		case tar.TypeLink:
+		case tar.TypeSymlink:
+			e.Mode |= fs.ModeSymlink
+		default:
+			continue
+		}
+		return e, nil
+	}
+}
+
+func (r *tarReader) Close() error { return nil }
+
+// The ZIP format stores its central directory at the end of an archive which prevents streamed extraction. Instead we
+// spool the fetched content to a temporary file which is removed once the archive reader is closed.
+type zipReader struct {
+	spool   *tempFile
+	files   []*zip.File
+	current io.ReadCloser
+}
+
+func newZIPReader(log *zap.Logger, src io.Reader) (archiveReader, error) {
+	log.Debug("Reading the fetched content as a ZIP archive.")
+
+	spool, size, err := spoolToTempFile(src)
+	if err != nil {
+		log.Error("Failed to spool fetched content to a temporary file.", zap.Error(err))
+		return nil, err
+	}
+	zr, err := zip.NewReader(spool, size)
+	if err != nil {
+		_ = spool.Close()
+		log.Error("Failed to open content with a ZIP reader.", zap.Error(err))
+		return nil, fmt.Errorf("failed to open fetched content as zip archive: %w", err)
+	}
+	return &zipReader{spool: spool, files: zr.File}, nil
+}
+
+func (r *zipReader) Next() (*archiveEntry, error) {
+	if err := r.closeCurrent(); err != nil {
+		return nil, err
+	}
+	if len(r.files) == 0 {
+		return nil, io.EOF
+	}
+	f := r.files[0]
+	r.files = r.files[1:]
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	r.current = rc
+
+	e := &archiveEntry{Name: f.Name, Mode: f.Mode()}
+	if e.Mode&fs.ModeSymlink != 0 {
+		target, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, err
+		}
+		e.Linkname = string(target)
+	}
+	return e, nil
+}
+
+func (r *zipReader) Read(p []byte) (int, error) {
+	if r.current == nil {
+		return 0, io.EOF
+	}
+	return r.current.Read(p)
+}
+
+func (r *zipReader) Close() error {
+	return errors.Join(r.closeCurrent(), r.spool.Close())
+}
+
+func (r *zipReader) closeCurrent() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
@@ -0,0 +1,192 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Debian packages are 'ar' archives of which the 'data.tar' member, possibly compressed, contains the package's files.
+func newDebReader(log *zap.Logger, src io.Reader) (archiveReader, error) {
+	log.Debug("Reading the fetched content as a Debian package.")
+
+	magic := make([]byte, 8)
+	if _, err := io.ReadFull(src, magic); err != nil || string(magic) != "!<arch>\n" {
+		return nil, fmt.Errorf("%w: not an ar archive", ErrInvalidPackage)
+	}
+
+	for {
+		// Each member has a fixed-size header of which the first 16 bytes hold the name and bytes 48 to 58 its size.
+		hdr := make([]byte, 60)
+		if _, err := io.ReadFull(src, hdr); errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: no data archive found", ErrInvalidPackage)
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+		}
+		name := strings.TrimSuffix(strings.TrimSpace(string(hdr[:16])), "/")
+		size, err := strconv.ParseInt(strings.TrimSpace(string(hdr[48:58])), 10, 64)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("%w: invalid size for member %q", ErrInvalidPackage, name)
+		}
+
+		if strings.HasPrefix(name, "data.tar") {
+			log.Debug("Found the package's data archive.", zap.String("member", name))
+			f := contentFormat{unarchiver: newTARReader}
+			if ext := path.Ext(name); ext != ".tar" {
+				if compressionFormats[ext].decompressor == nil {
+					return nil, fmt.Errorf("%w: unsupported compression of member %q", errors.ErrUnsupported, name)
+				}
+				f.compression = ext
+			}
+			return f.open(log, io.LimitReader(src, size))
+		}
+		// Members are aligned on an even offset.
+		if _, err = io.CopyN(io.Discard, src, size+size%2); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+		}
+	}
+}
+
+// RPM packages consist of a fixed-size lead, a signature header and a header, followed by the package's files as a
+// possibly compressed CPIO archive.
+func newRPMReader(log *zap.Logger, src io.Reader) (archiveReader, error) {
+	log.Debug("Reading the fetched content as an RPM package.")
+
+	br := bufio.NewReader(src)
+	lead := make([]byte, 96)
+	if _, err := io.ReadFull(br, lead); err != nil || !bytes.HasPrefix(lead, []byte{0xed, 0xab, 0xee, 0xdb}) {
+		return nil, fmt.Errorf("%w: not an rpm package", ErrInvalidPackage)
+	}
+
+	sigSize, err := skipRPMHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	// The signature header is padded to a multiple of 8 bytes.
+	if _, err = br.Discard(int((8 - sigSize%8) % 8)); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+	}
+	if _, err = skipRPMHeader(br); err != nil {
+		return nil, err
+	}
+
+	// The payload's compression is recorded in the header, however it is more easily detected from the payload itself.
+	head, err := br.Peek(8)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+	}
+	return contentFormat{compression: sniffCompression(head), unarchiver: newCPIOReader}.open(log, br)
+}
+
+// skipRPMHeader skips over an RPM header structure and returns its size.
+func skipRPMHeader(r io.Reader) (int64, error) {
+	intro := make([]byte, 16)
+	if _, err := io.ReadFull(r, intro); err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+	} else if !bytes.HasPrefix(intro, []byte{0x8e, 0xad, 0xe8, 0x01}) {
+		return 0, fmt.Errorf("%w: invalid rpm header", ErrInvalidPackage)
+	}
+
+	// Each index entry takes 16 bytes and is followed by the data store.
+	size := 16*int64(binary.BigEndian.Uint32(intro[8:12])) + int64(binary.BigEndian.Uint32(intro[12:16]))
+	if _, err := io.CopyN(io.Discard, r, size); err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+	}
+	return 16 + size, nil
+}
+
+// cpioReader reads archives in the 'new ASCII' CPIO format as used by RPM packages.
+type cpioReader struct {
+	r         io.Reader
+	remaining int64
+	padding   int64
+}
+
+const (
+	cpioHeaderSize = 110
+	cpioTrailer    = "TRAILER!!!"
+)
+
+func newCPIOReader(log *zap.Logger, src io.Reader) (archiveReader, error) {
+	log.Debug("Reading the package's payload as a CPIO archive.")
+	return &cpioReader{r: src}, nil
+}
+
+func (r *cpioReader) Next() (*archiveEntry, error) {
+	for {
+		if _, err := io.CopyN(io.Discard, r.r, r.remaining+r.padding); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+		}
+		r.remaining, r.padding = 0, 0
+
+		hdr := make([]byte, cpioHeaderSize)
+		if _, err := io.ReadFull(r.r, hdr); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+		} else if magic := string(hdr[:6]); magic != "070701" && magic != "070702" {
+			return nil, fmt.Errorf("%w: unsupported cpio format", ErrInvalidPackage)
+		}
+		// The header's fields are 8-character hexadecimal numbers following the 6-character magic.
+		field := func(idx int) (int64, error) {
+			return strconv.ParseInt(string(hdr[6+8*idx:14+8*idx]), 16, 64)
+		}
+		mode, modeErr := field(1)
+		size, sizeErr := field(6)
+		nameSize, nameErr := field(11)
+		if err := errors.Join(modeErr, sizeErr, nameErr); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+		}
+
+		// The name is NUL-terminated and, as the content, padded to a multiple of 4 bytes.
+		name := make([]byte, nameSize+(4-(cpioHeaderSize+nameSize)%4)%4)
+		if _, err := io.ReadFull(r.r, name); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+		}
+		e := &archiveEntry{Name: string(bytes.TrimRight(name, "\x00")), Mode: fs.FileMode(mode) & fs.ModePerm}
+		if e.Name == cpioTrailer {
+			return nil, io.EOF
+		}
+		r.remaining, r.padding = size, (4-size%4)%4
+
+		switch mode & 0o170000 {
+		case 0o040000:
+			e.Mode |= fs.ModeDir
+		case 0o100000:
+		case 0o120000:
+			e.Mode |= fs.ModeSymlink
+			target, err := io.ReadAll(r)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+			}
+			e.Linkname = string(target)
+		default:
+			continue
+		}
+		return e, nil
+	}
+}
+
+func (r *cpioReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.r.Read(p)
+	r.remaining -= int64(n)
+	if errors.Is(err, io.EOF) && r.remaining > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (r *cpioReader) Close() error { return nil }
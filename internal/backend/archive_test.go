@@ -0,0 +1,235 @@
+package backend
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+	"go.uber.org/zap"
+)
+
+// The standard library only provides a BZIP2 decoder so the compressed test content is recorded verbatim.
+var stdTestBinaryContentBZIP2 = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xdf, 0xf7, 0x04, 0x9e, 0x00, 0x00,
+	0x08, 0x11, 0x80, 0x00, 0x02, 0x3a, 0x25, 0x94, 0x20, 0x20, 0x00, 0x31, 0x03, 0x40, 0xd0, 0x29,
+	0xea, 0x69, 0x93, 0x4f, 0xd2, 0x53, 0xb8, 0xd3, 0xa0, 0x42, 0x24, 0xbe, 0x27, 0x82, 0xee, 0x48,
+	0xa7, 0x0a, 0x12, 0x1b, 0xfe, 0xe0, 0x93, 0xc0,
+}
+
+func TestDetectFormat(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		compression string
+		archive     bool
+	}{
+		"tool":               {},
+		"tool-1.2.3":         {},
+		"tool.exe":           {},
+		"tool.gz":            {compression: ".gz"},
+		"tool-1.2.3.XZ":      {compression: ".xz"},
+		"tool.bz2":           {compression: ".bz2"},
+		"tool.zst":           {compression: ".zst"},
+		"tool.zip":           {archive: true},
+		"tool.tar":           {archive: true},
+		"tool.tar.gz":        {compression: ".gz", archive: true},
+		"tool.tgz":           {compression: ".gz", archive: true},
+		"tool.tar.bz2":       {compression: ".bz2", archive: true},
+		"tool.tbz2":          {compression: ".bz2", archive: true},
+		"tool.tar.xz":        {compression: ".xz", archive: true},
+		"tool.txz":           {compression: ".xz", archive: true},
+		"tool.tar.zst":       {compression: ".zst", archive: true},
+		"tool.tzst":          {compression: ".zst", archive: true},
+		"tool_1.2.3.deb":     {archive: true},
+		"tool-1.2.3.x86.rpm": {archive: true},
+	}
+
+	for srcPath, testcase := range testcases {
+		f := detectFormat(srcPath)
+		assert.Equal(t, testcase.compression, f.compression, srcPath)
+		assert.Equal(t, testcase.archive, f.unarchiver != nil, srcPath)
+	}
+}
+
+func TestArchiveFormats(t *testing.T) {
+	t.Parallel()
+
+	tarball := writeTestTAR(t)
+	testcases := map[string]struct {
+		content     []byte
+		archivePath string
+	}{
+		"archive.tar":     {content: tarball, archivePath: "{platform}/{arch}/{tool}"},
+		"archive.tgz":     {content: compressTestContent(t, ".gz", tarball), archivePath: "{platform}/{arch}/{tool}"},
+		"archive.tar.xz":  {content: compressTestContent(t, ".xz", tarball), archivePath: "{platform}/{arch}/{tool}"},
+		"archive.tar.zst": {content: compressTestContent(t, ".zst", tarball), archivePath: "./{platform}/{arch}/{tool}"},
+		"archive.zip":     {content: writeTestZIP(t, []testArchiveEntry{{name: "linux/x86_64/test-tool", content: string(stdTestBinaryContent)}}), archivePath: "{platform}/{arch}/{tool}"},
+		"tool.deb":        {content: writeTestDeb(t, compressTestContent(t, ".xz", tarball)), archivePath: "{platform}/{arch}/{tool}"},
+		"tool.rpm":        {content: writeTestRPM(t, compressTestContent(t, ".zst", writeTestCPIO(t))), archivePath: "./usr/bin/{tool}"},
+		"tool.gz":         {content: compressTestContent(t, ".gz", stdTestBinaryContent)},
+		"tool.bz2":        {content: stdTestBinaryContentBZIP2},
+		"tool.xz":         {content: compressTestContent(t, ".xz", stdTestBinaryContent)},
+		"tool.zst":        {content: compressTestContent(t, ".zst", stdTestBinaryContent)},
+	}
+
+	for name := range testcases {
+		testcase := testcases[name]
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			conf := &CommonConfig{ArchivePathTemplate: testcase.archivePath}
+			b, err := conf.extractFromArchive(zap.NewNop(), stream(testcase.content), name, stdTestBinary)
+			require.NoError(t, err)
+			assert.Equal(t, stdTestBinaryContent, readContent(t, b))
+
+			conf.ArchivePathTemplate = "missing/{tool}"
+			_, err = conf.extractFromArchive(zap.NewNop(), stream(testcase.content), name, stdTestBinary)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestArchiveInvalidPackage(t *testing.T) {
+	t.Parallel()
+
+	conf := &CommonConfig{ArchivePathTemplate: "{tool}"}
+	for _, name := range []string{"tool.deb", "tool.rpm"} {
+		_, err := conf.extractFromArchive(zap.NewNop(), stream(stdTestBinaryContent), name, stdTestBinary)
+		require.ErrorIs(t, err, ErrInvalidPackage, name)
+	}
+}
+
+func writeTestTAR(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "linux/x86_64/", Typeflag: tar.TypeDir, Mode: 0o755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "linux/x86_64/test-tool", Size: int64(len(stdTestBinaryContent)), Mode: 0o755}))
+	_, err := tw.Write(stdTestBinaryContent)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func compressTestContent(t *testing.T, compression string, content []byte) []byte {
+	t.Helper()
+
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+		err error
+	)
+	switch compression {
+	case ".gz":
+		w = gzip.NewWriter(&buf)
+	case ".xz":
+		w, err = xz.NewWriter(&buf)
+	case ".zst":
+		w, err = zstd.NewWriter(&buf)
+	}
+	require.NoError(t, err)
+	_, err = w.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func writeTestDeb(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	buf.WriteString("!<arch>\n")
+	for _, member := range []struct {
+		name    string
+		content []byte
+	}{
+		{name: "debian-binary", content: []byte("2.0\n")},
+		{name: "control.tar.gz", content: []byte("odd")},
+		{name: "data.tar.xz", content: data},
+	} {
+		fmt.Fprintf(&buf, "%-16s%-12d%-6d%-6d%-8s%-10d`\n", member.name+"/", 0, 0, 0, "100644", len(member.content))
+		buf.Write(member.content)
+		if len(member.content)%2 == 1 {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+func writeTestCPIO(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writeEntry := func(name string, mode int, content []byte) {
+		fmt.Fprintf(&buf, "070701%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x", 0, mode, 0, 0, 1, 0, len(content), 0, 0, 0, 0, len(name)+1, 0)
+		buf.WriteString(name + "\x00")
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+		buf.Write(content)
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+	}
+	writeEntry("./usr/bin", 0o040755, nil)
+	writeEntry("./usr/bin/test-tool-link", 0o120777, []byte("test-tool"))
+	writeEntry("./usr/bin/test-tool", 0o100755, stdTestBinaryContent)
+	writeEntry(cpioTrailer, 0, nil)
+	return buf.Bytes()
+}
+
+func writeTestRPM(t *testing.T, payload []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	lead := make([]byte, 96)
+	copy(lead, []byte{0xed, 0xab, 0xee, 0xdb})
+	buf.Write(lead)
+
+	writeHeader := func(entries uint32, storeSize uint32) {
+		buf.Write([]byte{0x8e, 0xad, 0xe8, 0x01, 0, 0, 0, 0})
+		require.NoError(t, binary.Write(&buf, binary.BigEndian, []uint32{entries, storeSize}))
+		buf.Write(make([]byte, 16*entries+storeSize))
+	}
+	writeHeader(1, 5)
+	buf.Write(make([]byte, 3)) // Padding of the signature header to a multiple of 8 bytes.
+	writeHeader(2, 11)
+	buf.Write(payload)
+	return buf.Bytes()
+}
+
+func TestArchivePathPattern(t *testing.T) {
+	t.Parallel()
+
+	archive := writeTestTGZ(t, []testArchiveEntry{
+		{name: "test-tool-1.2.3-a1b2c3/", typeflag: tar.TypeDir},
+		{name: "test-tool-1.2.3-a1b2c3/bin/test-tool", content: string(stdTestBinaryContent)},
+		{name: "test-tool-1.2.3-a1b2c3/bin/test-tool-helper", content: "helper"},
+	})
+
+	conf := &CommonConfig{ArchivePathTemplate: "**/bin/{tool}"}
+	b, err := conf.extractFromArchive(zap.NewNop(), stream(archive), "archive.tgz", stdTestBinary)
+	require.NoError(t, err)
+	require.Implements(t, (*ArchiveEntryReader)(nil), b)
+	assert.Equal(t, "test-tool-1.2.3-a1b2c3/bin/test-tool", b.(ArchiveEntryReader).ArchiveEntry())
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
+
+	conf.ArchivePathTemplate = "*/bin/{tool}*"
+	_, err = conf.extractFromArchive(zap.NewNop(), stream(archive), "archive.tgz", stdTestBinary)
+	require.ErrorIs(t, err, ErrAmbiguousMatch)
+	assert.ErrorContains(t, err, "test-tool-1.2.3-a1b2c3/bin/test-tool, test-tool-1.2.3-a1b2c3/bin/test-tool-helper")
+
+	conf.ArchivePathTemplate = "{tool}"
+	_, err = conf.extractFromArchive(zap.NewNop(), stream(archive), "archive.tgz", stdTestBinary)
+	require.ErrorIs(t, err, ErrNoMatch)
+	assert.ErrorContains(t, err, "test-tool-1.2.3-a1b2c3/bin/test-tool-helper")
+}
@@ -1,18 +1,14 @@
 package backend
 
 import (
-	"archive/tar"
-	"archive/zip"
-	"bytes"
-	"compress/gzip"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"io/fs"
-	"regexp"
+	"os"
+	"path/filepath"
 	"strings"
 
-	"github.com/ulikunitz/xz"
 	"go.uber.org/zap"
 
 	"github.com/Helcaraxan/toolshare/internal/config"
@@ -20,30 +16,66 @@ import (
 
 type Storage interface {
 	fmt.Stringer
-	Fetch(binary config.Binary) ([]byte, error)
-	Store(binary config.Binary, content []byte) error
+	// Fetch returns a stream of the given binary's content. The caller is responsible for closing it. Cancelling the
+	// context aborts any in-flight download, including one that is still being streamed.
+	Fetch(ctx context.Context, binary config.Binary) (io.ReadCloser, error)
+	Store(ctx context.Context, binary config.Binary, content io.Reader) error
+	// Resolve returns the concrete location, e.g. a URL or an object key, from which the given binary is fetched.
+	Resolve(ctx context.Context, binary config.Binary) (string, error)
 }
 
 type BinaryProvider interface {
 	Storage
+	// Path returns the path of the executable for the given binary.
 	Path(binary config.Binary) string
+	// StorePath returns the path at which the given binary is stored. It differs from Path for bundles.
+	StorePath(binary config.Binary) string
+}
+
+// ReleaseLister is implemented by sources that can enumerate the released versions of the tool they provide.
+type ReleaseLister interface {
+	Releases(ctx context.Context) ([]Release, error)
+}
+
+type Release struct {
+	// Version is the release's tag with any 'v' prefix removed, in line with how versions are usually pinned.
+	Version    string `json:"version"`
+	Prerelease bool   `json:"prerelease,omitempty"`
 }
 
 var (
 	// To guarantee that implementations remain compatible with the interface.
 	_ BinaryProvider = &FileSystem{}
 
+	_ AssetFetcher = &GitHub{}
+	_ AssetFetcher = &GitLab{}
+	_ AssetFetcher = &Gitea{}
+
+	_ ReleaseLister = &GitHub{}
+	_ ReleaseLister = &GitLab{}
+	_ ReleaseLister = &Gitea{}
+
 	_ Storage = &FileSystem{}
 	_ Storage = &GCS{}
 	_ Storage = &GitHub{}
+	_ Storage = &GitLab{}
+	_ Storage = &Gitea{}
+	_ Storage = &GoModule{}
 	_ Storage = &HTTPS{}
+	_ Storage = &OCI{}
+	_ Storage = &ReleaseAsset{}
 	_ Storage = &S3{}
 
+	// ErrAlreadyExists is returned by storages that refuse to overwrite a binary that is already stored.
+	ErrAlreadyExists = errors.New("binary already exists")
+
 	errFailed = errors.New("failed")
 )
 
 type CommonConfig struct {
 	ArchivePathTemplate string           `json:"archive_path_template"`
+	ArchiveDirTemplate  string           `json:"archive_dir_template"`
+	EntrypointTemplate  string           `json:"entrypoint_template"`
 	Mappings            TemplateMappings `json:"template_mappings"`
 }
 
@@ -61,13 +93,18 @@ type TemplateMappings struct {
 }
 
 func (c *CommonConfig) instantiateTemplate(b config.Binary, tmpl string) string {
-	return strings.NewReplacer(
+	return strings.NewReplacer(c.templateVariables(b)...).Replace(tmpl)
+}
+
+// templateVariables returns the pairs of template variables and their values for the given binary.
+func (c *CommonConfig) templateVariables(b config.Binary) []string {
+	return []string{
 		"{arch}", c.arch(b),
 		"{exe}", c.exe(b),
 		"{platform}", c.platform(b),
 		"{tool}", b.Tool,
 		"{version}", b.Version,
-	).Replace(tmpl)
+	}
 }
 
 func (c *CommonConfig) platform(b config.Binary) string {
@@ -126,104 +163,135 @@ func (c *CommonConfig) exe(b config.Binary) string {
 	return ""
 }
 
-func (c *CommonConfig) extractFromArchive(log *zap.Logger, srcRaw []byte, srcPath string, b config.Binary) ([]byte, error) {
-	if c.ArchivePathTemplate == "" {
-		log.Debug("No archive path set. Using the fetched content as the tool binary itself.")
-		return srcRaw, nil
+func (c *CommonConfig) extractFromArchive(log *zap.Logger, src io.ReadCloser, srcPath string, b config.Binary) (io.ReadCloser, error) {
+	if c.IsBundle() {
+		return c.extractBundle(log, src, srcPath, b)
 	}
 
-	var (
-		err error
-		raw []byte
-		rd  io.Reader
-	)
+	format := detectFormat(srcPath)
+	if c.ArchivePathTemplate == "" {
+		if format.unarchiver != nil || format.compression == "" {
+			log.Debug("No archive path set. Using the fetched content as the tool binary itself.")
+			return src, nil
+		}
+		rd, err := format.decompress(log, src)
+		if err != nil {
+			_ = src.Close()
+			return nil, err
+		}
+		return &readCloser{Reader: rd, closers: []io.Closer{src}}, nil
+	}
 
 	archivePath := c.instantiateTemplate(b, c.ArchivePathTemplate)
 	log = log.With(zap.String("archive-path", archivePath))
 
-	switch {
-	case strings.HasSuffix(srcPath, ".zip"):
-		rd, err = c.extractFromArchiveZIP(log, srcRaw, archivePath)
-
-	case regexp.MustCompile(".tar(.(g|x)z)?$").MatchString(srcPath):
-		switch {
-		case strings.HasSuffix(srcPath, ".gz"):
-			log.Debug("Applying a GZIP decoder on the fetched content.")
-			rd, err = gzip.NewReader(bytes.NewBuffer(srcRaw))
-			if err != nil {
-				log.Error("Failed to open fetched content with a GZIP reader.", zap.Error(err))
-				return nil, fmt.Errorf("failed to open gzip reader for fetched content: %w", err)
-			}
-		case strings.HasSuffix(srcPath, "xz"):
-			log.Debug("Applying an XZ decoder on the fetched content.")
-			rd, err = xz.NewReader(bytes.NewBuffer(srcRaw))
-			if err != nil {
-				log.Error("Failed to open fetched content with an XZ reader.", zap.Error(err))
-				return nil, fmt.Errorf("failed to open xz reader for fetched content: %w", err)
-			}
-		}
-		rd, err = c.extractFromArchiveTAR(log, srcRaw, archivePath, rd)
-
-	default:
-		err = fmt.Errorf("unrecognised archive format: %w", errors.ErrUnsupported)
+	pattern, err := c.archivePathPattern(b)
+	if err != nil {
+		_ = src.Close()
+		log.Error("Invalid archive path template.", zap.Error(err))
+		return nil, err
 	}
+	ar, err := format.open(log, src)
 	if err != nil {
+		_ = src.Close()
 		return nil, err
 	}
-
-	raw, err = io.ReadAll(rd)
+	rc, entry, err := findArchiveFile(ar, pattern)
 	if err != nil {
-		log.Error("Failed to read binary from archive.", zap.Error(err))
+		_ = ar.Close()
+		_ = src.Close()
+		log.Error("Failed to find a single matching path in archive.", zap.Error(err))
 		return nil, err
 	}
-	log.Debug("Successfully read binary from archive.")
-	return raw, nil
+
+	log.Debug("Found binary in archive.", zap.String("archive-entry", entry))
+	return &archiveFile{readCloser: readCloser{Reader: rc, closers: []io.Closer{rc, ar, src}}, entry: entry}, nil
 }
 
-func (c *CommonConfig) extractFromArchiveZIP(log *zap.Logger, srcRaw []byte, archivePath string) (io.Reader, error) {
-	log.Debug("Reading the fetched content as a ZIP archive.")
-	var (
-		zr *zip.Reader
-		fl fs.File
-	)
-	zr, err := zip.NewReader(bytes.NewReader(srcRaw), int64(len(srcRaw)))
-	if err != nil {
-		log.Error("Failed to open content with a ZIP reader.", zap.Error(err))
-		return nil, fmt.Errorf("failed to open fetched content as zip archive: %w", err)
+// ArchiveEntryReader is implemented by the content of binaries that were extracted from a fetched archive.
+type ArchiveEntryReader interface {
+	io.ReadCloser
+	// ArchiveEntry returns the path of the archive entry from which the binary was extracted. This is the concrete path
+	// that matched the archive path template, which may contain glob patterns.
+	ArchiveEntry() string
+}
+
+// archiveFile is the content of a binary that was extracted from an archive.
+type archiveFile struct {
+	readCloser
+	entry string
+}
+
+func (f *archiveFile) ArchiveEntry() string { return f.entry }
+
+// readCloser combines a reader with the resources that need to be released once the reader has been consumed.
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *readCloser) Close() error {
+	var errs []error
+	for _, c := range r.closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	fl, err = zr.Open(archivePath)
+	return errors.Join(errs...)
+}
+
+// tempFile is a temporary file that is removed from disk when closed.
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	closeErr := f.File.Close()
+	if err := os.Remove(f.Name()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return closeErr
+}
+
+// SpoolDir returns the directory, inside the local cache, in which content is spooled to disk. The system's temporary
+// directory is avoided as it is memory-backed on many systems, which would defeat the point of spooling content.
+func SpoolDir() (string, error) {
+	dir := filepath.Join(config.StorageDir(), "tmp")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// createSpoolFile creates a temporary file in the spool directory that is removed from disk when closed.
+func createSpoolFile() (*tempFile, error) {
+	dir, err := SpoolDir()
 	if err != nil {
-		log.Error("Path not found in archive.", zap.Error(err))
-		return nil, fmt.Errorf("failed to find path inside fetched content: %w", err)
+		return nil, err
 	}
-	_, err = fl.Stat()
+	fd, err := os.CreateTemp(dir, config.DriverName+"-*")
 	if err != nil {
-		log.Error("Failed to open archive path for reading.", zap.Error(err))
-		return nil, fmt.Errorf("failed to read file information for path inside fetched content: %w", err)
+		return nil, err
 	}
-	return fl, nil
+	return &tempFile{File: fd}, nil
 }
 
-func (c *CommonConfig) extractFromArchiveTAR(log *zap.Logger, srcRaw []byte, archivePath string, rd io.Reader) (io.Reader, error) {
-	log.Debug("Reading the fetched content as a TAR archive.")
-	if rd == nil {
-		rd = bytes.NewBuffer(srcRaw)
+// spoolToTempFile copies the content of the reader to a temporary file which is returned rewound to its start,
+// together with the size of the content.
+func spoolToTempFile(src io.Reader) (*tempFile, int64, error) {
+	spool, err := createSpoolFile()
+	if err != nil {
+		return nil, 0, err
 	}
-	tr := tar.NewReader(rd)
 
-	hdr, err := tr.Next()
-	for err == nil {
-		if hdr.Name == archivePath {
-			break
-		}
-		hdr, err = tr.Next()
+	size, err := io.Copy(spool, src)
+	if err != nil {
+		_ = spool.Close()
+		return nil, 0, err
 	}
-	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
-		log.Error("Failed to search archive for path.", zap.Error(err))
-		return nil, fmt.Errorf("failed to search for path in fetched content: %w", err)
-	} else if hdr == nil {
-		log.Error("Path not found in archive.")
-		return nil, fmt.Errorf("failed to find path in fetched content: %w", err)
+	if _, err = spool.Seek(0, io.SeekStart); err != nil {
+		_ = spool.Close()
+		return nil, 0, err
 	}
-	return tr, nil
+	return spool, size, nil
 }
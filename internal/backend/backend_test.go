@@ -6,6 +6,7 @@ import (
 	"archive/zip"
 	"bytes"
 	"compress/gzip"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -27,6 +28,19 @@ var (
 	stdTestBinaryContent = []byte("tool-binary-content")
 )
 
+func stream(content []byte) io.ReadCloser {
+	return io.NopCloser(bytes.NewReader(content))
+}
+
+func readContent(t *testing.T, rc io.ReadCloser) []byte {
+	t.Helper()
+
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return b
+}
+
 //nolint:funlen // Testcase definition can´t be easily shortened.
 func TestInstantiateTemplate(t *testing.T) {
 	t.Parallel()
@@ -128,9 +142,9 @@ func TestArchiveExtractionNoTemplate(t *testing.T) {
 
 	conf := &CommonConfig{}
 
-	b, err := conf.extractFromArchive(zap.NewNop(), stdTestBinaryContent, "test-tool", config.Binary{})
+	b, err := conf.extractFromArchive(zap.NewNop(), stream(stdTestBinaryContent), "test-tool", config.Binary{})
 	require.NoError(t, err)
-	assert.Equal(t, stdTestBinaryContent, b)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
 }
 
 func TestArchiveExtractionUnknownFormat(t *testing.T) {
@@ -138,7 +152,7 @@ func TestArchiveExtractionUnknownFormat(t *testing.T) {
 
 	conf := &CommonConfig{ArchivePathTemplate: "foo/bar"}
 
-	b, err := conf.extractFromArchive(zap.NewNop(), nil, "archive.unknown", config.Binary{})
+	b, err := conf.extractFromArchive(zap.NewNop(), stream(nil), "archive.unknown", config.Binary{})
 	require.Error(t, err)
 	assert.Nil(t, b)
 }
@@ -151,13 +165,13 @@ func TestArchiveExtractionZIP(t *testing.T) {
 		conf        = &CommonConfig{ArchivePathTemplate: "{platform}/{arch}/{tool}"}
 	)
 
-	b, err := conf.extractFromArchive(zap.NewNop(), testArchive.Bytes(), "archive.zip", stdTestBinary)
+	b, err := conf.extractFromArchive(zap.NewNop(), stream(testArchive.Bytes()), "archive.zip", stdTestBinary)
 	require.Error(t, err)
 	assert.Nil(t, b)
 
 	archiveWriter := zip.NewWriter(&testArchive)
 	require.NoError(t, archiveWriter.Close())
-	b, err = conf.extractFromArchive(zap.NewNop(), testArchive.Bytes(), "archive.zip", stdTestBinary)
+	b, err = conf.extractFromArchive(zap.NewNop(), stream(testArchive.Bytes()), "archive.zip", stdTestBinary)
 	require.Error(t, err)
 	assert.Nil(t, b)
 
@@ -169,9 +183,9 @@ func TestArchiveExtractionZIP(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, archiveWriter.Close())
 
-	b, err = conf.extractFromArchive(zap.NewNop(), testArchive.Bytes(), "archive.zip", stdTestBinary)
+	b, err = conf.extractFromArchive(zap.NewNop(), stream(testArchive.Bytes()), "archive.zip", stdTestBinary)
 	require.NoError(t, err)
-	assert.Equal(t, stdTestBinaryContent, b)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
 }
 
 func TestArchiveExtractionGzipTAR(t *testing.T) {
@@ -182,20 +196,20 @@ func TestArchiveExtractionGzipTAR(t *testing.T) {
 		conf        = &CommonConfig{ArchivePathTemplate: "{platform}/{arch}/{tool}"}
 	)
 
-	b, err := conf.extractFromArchive(zap.NewNop(), testArchive.Bytes(), "archive.tar.gz", stdTestBinary)
+	b, err := conf.extractFromArchive(zap.NewNop(), stream(testArchive.Bytes()), "archive.tar.gz", stdTestBinary)
 	require.Error(t, err)
 	assert.Nil(t, b)
 
 	archiveWriter := tar.NewWriter(&testArchive)
 	require.NoError(t, archiveWriter.Close())
-	b, err = conf.extractFromArchive(zap.NewNop(), testArchive.Bytes(), "archive.tar.gz", stdTestBinary)
+	b, err = conf.extractFromArchive(zap.NewNop(), stream(testArchive.Bytes()), "archive.tar.gz", stdTestBinary)
 	require.Error(t, err)
 	assert.Nil(t, b)
 
 	testArchive.Reset()
 	archiveWriter = tar.NewWriter(gzip.NewWriter(&testArchive))
 	require.NoError(t, archiveWriter.Close())
-	b, err = conf.extractFromArchive(zap.NewNop(), testArchive.Bytes(), "archive.tar.gz", stdTestBinary)
+	b, err = conf.extractFromArchive(zap.NewNop(), stream(testArchive.Bytes()), "archive.tar.gz", stdTestBinary)
 	require.Error(t, err)
 	assert.Nil(t, b)
 
@@ -212,7 +226,7 @@ func TestArchiveExtractionGzipTAR(t *testing.T) {
 	require.NoError(t, archiveWriter.Close())
 	require.NoError(t, compressor.Close())
 
-	b, err = conf.extractFromArchive(zap.NewNop(), testArchive.Bytes(), "archive.tar.gz", stdTestBinary)
+	b, err = conf.extractFromArchive(zap.NewNop(), stream(testArchive.Bytes()), "archive.tar.gz", stdTestBinary)
 	require.NoError(t, err)
-	assert.Equal(t, stdTestBinaryContent, b)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
 }
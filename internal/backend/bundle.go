@@ -0,0 +1,328 @@
+package backend
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+)
+
+// Tools that consist of more than a single file, e.g. because they ship with shared libraries or a bundled runtime, are
+// installed as a bundle: a whole directory subtree of the fetched archive with an entrypoint inside it. Bundles are
+// exchanged between storages as a canonical uncompressed TAR stream. This stream only depends on the content of the
+// bundle so that its digest can be recorded and verified like that of a single binary.
+
+var (
+	ErrMissingEntrypoint = errors.New("bundle does not contain the entrypoint")
+	ErrUnsafeArchivePath = errors.New("archive entry points outside of the extraction directory")
+)
+
+// bundleModTime is the modification time recorded for all entries of a bundle to guarantee a deterministic stream.
+var bundleModTime = time.Unix(0, 0)
+
+// IsBundle reports whether binaries are installed as a bundle rather than as a single file.
+func (c *CommonConfig) IsBundle() bool {
+	return c.EntrypointTemplate != ""
+}
+
+// ArchiveDir returns the directory inside a fetched archive whose subtree constitutes the bundle for the given binary.
+// An empty string designates the root of the archive.
+func (c *CommonConfig) ArchiveDir(b config.Binary) string {
+	if c.ArchiveDirTemplate == "" {
+		return ""
+	}
+	return strings.Trim(path.Clean(c.instantiateTemplate(b, c.ArchiveDirTemplate)), "./")
+}
+
+// Entrypoint returns the path, relative to the root of the bundle, of the executable with which the tool is invoked.
+func (c *CommonConfig) Entrypoint(b config.Binary) string {
+	if c.EntrypointTemplate == "" {
+		return ""
+	}
+	return path.Clean(c.instantiateTemplate(b, c.EntrypointTemplate))
+}
+
+// extractBundle extracts the configured subtree of the fetched archive and returns it as a bundle stream.
+func (c *CommonConfig) extractBundle(log *zap.Logger, src io.ReadCloser, srcPath string, b config.Binary) (io.ReadCloser, error) {
+	defer src.Close()
+
+	subtree := c.ArchiveDir(b)
+	log = log.With(zap.String("archive-dir", subtree))
+
+	dir, err := SpoolDir()
+	if err != nil {
+		log.Error("Failed to create directory to extract the bundle in.", zap.Error(err))
+		return nil, err
+	}
+	root, err := os.MkdirTemp(dir, config.DriverName+"-bundle-*")
+	if err != nil {
+		log.Error("Failed to create temporary directory to extract the bundle to.", zap.Error(err))
+		return nil, err
+	}
+	defer os.RemoveAll(root)
+
+	ex := &subtreeExtractor{root: root, subtree: subtree}
+	ar, err := detectFormat(srcPath).open(log, src)
+	if err == nil {
+		err = ex.fromArchive(ar)
+		_ = ar.Close()
+	}
+	if err != nil {
+		log.Error("Failed to extract the bundle from the archive.", zap.Error(err))
+		return nil, err
+	} else if !ex.found {
+		log.Error("Directory not found in archive.")
+		return nil, fmt.Errorf("failed to find directory %q in fetched content: %w", subtree, os.ErrNotExist)
+	}
+
+	if err = checkEntrypoint(root, c.Entrypoint(b)); err != nil {
+		log.Error("The bundle does not contain the entrypoint.", zap.String("entrypoint", c.Entrypoint(b)), zap.Error(err))
+		return nil, err
+	}
+
+	bundle, err := createSpoolFile()
+	if err != nil {
+		log.Error("Failed to create temporary file to spool the bundle to.", zap.Error(err))
+		return nil, err
+	}
+	if err = writeBundle(bundle, root); err != nil {
+		_ = bundle.Close()
+		log.Error("Failed to write the bundle.", zap.Error(err))
+		return nil, err
+	}
+	if _, err = bundle.Seek(0, io.SeekStart); err != nil {
+		_ = bundle.Close()
+		return nil, err
+	}
+	log.Debug("Extracted bundle from archive.")
+	return bundle, nil
+}
+
+// checkEntrypoint verifies that the entrypoint of a bundle extracted at root is a regular file.
+func checkEntrypoint(root string, entrypoint string) error {
+	if !filepath.IsLocal(filepath.FromSlash(entrypoint)) {
+		return fmt.Errorf("%w: %q", ErrUnsafeArchivePath, entrypoint)
+	}
+	fi, err := os.Stat(filepath.Join(root, filepath.FromSlash(entrypoint)))
+	if err != nil || !fi.Mode().IsRegular() {
+		return fmt.Errorf("%w: %q", ErrMissingEntrypoint, entrypoint)
+	}
+	return nil
+}
+
+// writeBundle writes the content of the directory at root as a bundle stream. Entries are written in lexical order and
+// only their type, name, executable bit and content are recorded.
+func writeBundle(w io.Writer, root string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == root {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		hdr := &tar.Header{Name: filepath.ToSlash(rel), ModTime: bundleModTime, Mode: 0o644}
+		switch {
+		case fi.IsDir():
+			hdr.Typeflag, hdr.Name, hdr.Mode = tar.TypeDir, hdr.Name+"/", 0o755
+		case fi.Mode()&fs.ModeSymlink != 0:
+			hdr.Typeflag, hdr.Mode = tar.TypeSymlink, 0o777
+			if hdr.Linkname, err = os.Readlink(p); err != nil {
+				return err
+			}
+			hdr.Linkname = filepath.ToSlash(hdr.Linkname)
+		case fi.Mode().IsRegular():
+			hdr.Typeflag, hdr.Size = tar.TypeReg, fi.Size()
+			if fi.Mode()&0o111 != 0 {
+				hdr.Mode = 0o755
+			}
+		default:
+			return nil
+		}
+		if err = tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		fd, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer fd.Close()
+		_, err = io.Copy(tw, fd)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// readBundle extracts a bundle stream into the directory at root. The stream is consumed in its entirety, which
+// guarantees that any verification performed by the reader on reaching the end of the stream takes place.
+func readBundle(r io.Reader, root string) error {
+	ex := &subtreeExtractor{root: root}
+	if err := ex.fromArchive(&tarReader{Reader: tar.NewReader(r)}); err != nil {
+		return err
+	}
+	_, err := io.Copy(io.Discard, r)
+	return err
+}
+
+// subtreeExtractor writes the entries of an archive that are located within a given subtree to the root directory.
+// Entries that would end up outside of the root directory, such as those containing '..' elements or symbolic links to
+// outside locations, result in an error. So do entries located under a symbolic link to a directory, as chained links
+// could otherwise resolve to outside locations despite each of them pointing inside the root directory on their own.
+// This protects against 'zip-slip' attacks.
+type subtreeExtractor struct {
+	root    string
+	subtree string
+	found   bool
+}
+
+func (e *subtreeExtractor) fromArchive(ar archiveReader) error {
+	for {
+		entry, err := ar.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		if entry.Mode.IsRegular() && entry.Linkname != "" {
+			err = e.addHardLink(entry.Name, entry.Linkname)
+		} else {
+			err = e.add(entry.Name, entry.Mode, entry.Linkname, ar)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// relative returns the path of an archive entry relative to the subtree, or false if it is not part of the subtree.
+func (e *subtreeExtractor) relative(name string) (string, bool, error) {
+	name = path.Clean(name)
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", false, fmt.Errorf("%w: %q", ErrUnsafeArchivePath, name)
+	}
+	if e.subtree == "" {
+		return name, true, nil
+	}
+	if name == e.subtree {
+		e.found = true
+		return "", false, nil
+	}
+	rel, ok := strings.CutPrefix(name, e.subtree+"/")
+	return rel, ok, nil
+}
+
+func (e *subtreeExtractor) add(name string, mode fs.FileMode, linkname string, content io.Reader) error {
+	rel, ok, err := e.relative(name)
+	if err != nil || !ok {
+		return err
+	}
+	e.found = true
+	if err = e.checkParents(rel); err != nil {
+		return err
+	}
+	target := filepath.Join(e.root, filepath.FromSlash(rel))
+
+	if mode.IsDir() {
+		return os.MkdirAll(target, 0o755)
+	}
+	if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	// Never write through a pre-existing entry, which may be a symbolic link.
+	if err = os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if mode&fs.ModeSymlink != 0 {
+		if path.IsAbs(linkname) || !filepath.IsLocal(filepath.FromSlash(path.Join(path.Dir(rel), linkname))) {
+			return fmt.Errorf("%w: %q links to %q", ErrUnsafeArchivePath, name, linkname)
+		}
+		return os.Symlink(filepath.FromSlash(linkname), target)
+	}
+
+	perm := fs.FileMode(0o644)
+	if mode&0o111 != 0 {
+		perm = 0o755
+	}
+	fd, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(fd, content); err != nil {
+		_ = fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+func (e *subtreeExtractor) addHardLink(name string, linkname string) error {
+	rel, ok, err := e.relative(name)
+	if err != nil || !ok {
+		return err
+	}
+	linkRel, ok, err := e.relative(linkname)
+	if err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("%w: %q links to %q", ErrUnsafeArchivePath, name, linkname)
+	}
+	e.found = true
+	for _, p := range []string{rel, linkRel} {
+		if err = e.checkParents(p); err != nil {
+			return err
+		}
+	}
+
+	target := filepath.Join(e.root, filepath.FromSlash(rel))
+	if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	if err = os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.Link(filepath.Join(e.root, filepath.FromSlash(linkRel)), target)
+}
+
+// checkParents verifies that none of the already extracted parent directories of the entry at rel, relative to the
+// root, is a symbolic link. Writing through one would follow it, and any links it is chained with, wherever it leads.
+func (e *subtreeExtractor) checkParents(rel string) error {
+	dir := e.root
+	elems := strings.Split(rel, "/")
+	for _, elem := range elems[:len(elems)-1] {
+		dir = filepath.Join(dir, elem)
+		info, err := os.Lstat(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %q is located under a symbolic link", ErrUnsafeArchivePath, rel)
+		}
+	}
+	return nil
+}
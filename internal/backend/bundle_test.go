@@ -0,0 +1,163 @@
+package backend
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/logger"
+)
+
+type testArchiveEntry struct {
+	name     string
+	content  string
+	linkname string
+	typeflag byte
+}
+
+var stdTestBundleEntries = []testArchiveEntry{
+	{name: "test-tool/", typeflag: tar.TypeDir},
+	{name: "test-tool/bin/test-tool", content: "tool-binary-content"},
+	{name: "test-tool/lib/libtool.so", content: "tool-library-content"},
+	{name: "test-tool/lib/libtool.so.1", linkname: "libtool.so", typeflag: tar.TypeSymlink},
+	{name: "README.md", content: "readme"},
+}
+
+func writeTestTGZ(t *testing.T, entries []testArchiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	compressor := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(compressor)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0o755, Size: int64(len(e.content))}
+		if hdr.Typeflag == 0 {
+			hdr.Typeflag = tar.TypeReg
+		} else {
+			hdr.Size = 0
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, compressor.Close())
+	return buf.Bytes()
+}
+
+func writeTestZIP(t *testing.T, entries []testArchiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name}
+		content := e.content
+		switch e.typeflag {
+		case tar.TypeDir:
+			hdr.SetMode(os.ModeDir | 0o755)
+		case tar.TypeSymlink:
+			hdr.SetMode(os.ModeSymlink | 0o777)
+			content = e.linkname
+		default:
+			hdr.SetMode(0o755)
+		}
+		w, err := zw.CreateHeader(hdr)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestBundleExtraction(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Symbolic links require elevated privileges on Windows.")
+	}
+
+	conf := &CommonConfig{ArchiveDirTemplate: "{tool}", EntrypointTemplate: "bin/{tool}{exe}"}
+
+	fromTGZ, err := conf.extractFromArchive(zap.NewNop(), stream(writeTestTGZ(t, stdTestBundleEntries)), "archive.tar.gz", stdTestBinary)
+	require.NoError(t, err)
+	tgzBundle := readContent(t, fromTGZ)
+
+	fromZIP, err := conf.extractFromArchive(zap.NewNop(), stream(writeTestZIP(t, stdTestBundleEntries)), "archive.zip", stdTestBinary)
+	require.NoError(t, err)
+	assert.Equal(t, tgzBundle, readContent(t, fromZIP), "bundles should not depend on the archive format")
+
+	td := t.TempDir()
+	fs := NewFileSystem(logger.NewTestBuilder(), &FileSystemConfig{
+		FilePathTemplate:         filepath.Join(td, stdTestTemplate),
+		BundleEntrypointTemplate: conf.EntrypointTemplate,
+	})
+	require.NoError(t, fs.Store(context.Background(), stdTestBinary, bytes.NewReader(tgzBundle)))
+
+	bundleDir := filepath.Join(td, "test-tool_v1.2.3_linux_x86_64")
+	assert.Equal(t, bundleDir, fs.StorePath(stdTestBinary))
+	assert.Equal(t, filepath.Join(bundleDir, "bin", "test-tool"), fs.Path(stdTestBinary))
+
+	content, err := os.ReadFile(fs.Path(stdTestBinary))
+	require.NoError(t, err)
+	assert.Equal(t, stdTestBinaryContent, content)
+	link, err := os.Readlink(filepath.Join(bundleDir, "lib", "libtool.so.1"))
+	require.NoError(t, err)
+	assert.Equal(t, "libtool.so", link)
+	assert.NoFileExists(t, filepath.Join(bundleDir, "README.md"))
+
+	// Storing the bundle again replaces the previous one.
+	require.NoError(t, fs.Store(context.Background(), stdTestBinary, bytes.NewReader(tgzBundle)))
+
+	b, err := fs.Fetch(context.Background(), stdTestBinary)
+	require.NoError(t, err)
+	assert.Equal(t, tgzBundle, readContent(t, b), "bundles should round-trip through the local cache unchanged")
+
+	missing := &CommonConfig{ArchiveDirTemplate: "other-tool", EntrypointTemplate: "bin/{tool}"}
+	_, err = missing.extractFromArchive(zap.NewNop(), stream(writeTestTGZ(t, stdTestBundleEntries)), "archive.tar.gz", stdTestBinary)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	noEntrypoint := &CommonConfig{ArchiveDirTemplate: "{tool}", EntrypointTemplate: "{tool}"}
+	_, err = noEntrypoint.extractFromArchive(zap.NewNop(), stream(writeTestTGZ(t, stdTestBundleEntries)), "archive.tar.gz", stdTestBinary)
+	require.ErrorIs(t, err, ErrMissingEntrypoint)
+}
+
+func TestBundleExtractionUnsafe(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string][]testArchiveEntry{
+		"ParentDirectory":     {{name: "test-tool/../../escaped", content: "evil"}},
+		"AbsolutePath":        {{name: "/tmp/escaped", content: "evil"}},
+		"SymlinkOutside":      {{name: "test-tool/lib", linkname: "../../..", typeflag: tar.TypeSymlink}},
+		"SymlinkAbsolute":     {{name: "test-tool/lib", linkname: "/etc", typeflag: tar.TypeSymlink}},
+		"HardlinkOutsideTree": {{name: "test-tool/passwd", linkname: "README.md", typeflag: tar.TypeLink}},
+		"ChainedSymlinks": {
+			{name: "test-tool/a/b", linkname: "..", typeflag: tar.TypeSymlink},
+			{name: "test-tool/a/b/c", linkname: "..", typeflag: tar.TypeSymlink},
+			{name: "test-tool/a/b/c/escaped", content: "evil"},
+		},
+	}
+
+	for name := range testcases {
+		unsafeEntries := testcases[name]
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			conf := &CommonConfig{ArchiveDirTemplate: "{tool}", EntrypointTemplate: "bin/{tool}"}
+			entries := append([]testArchiveEntry{{name: "README.md", content: "readme"}}, unsafeEntries...)
+			_, err := conf.extractFromArchive(zap.NewNop(), stream(writeTestTGZ(t, entries)), "archive.tar.gz", stdTestBinary)
+			require.ErrorIs(t, err, ErrUnsafeArchivePath)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"errors"
 	"io"
 	"os"
@@ -16,6 +17,10 @@ type FileSystemConfig struct {
 	CommonConfig
 
 	FilePathTemplate string `json:"file_path_template"`
+
+	// BundleEntrypointTemplate is set when the storage holds bundles rather than single binaries. Bundles are unpacked
+	// into a directory at the templated file path and the binary's path is that of the entrypoint inside of it.
+	BundleEntrypointTemplate string `json:"-"`
 }
 
 func (c FileSystemConfig) String() string {
@@ -36,28 +41,42 @@ func NewFileSystem(logBuilder logger.Builder, c *FileSystemConfig) *FileSystem {
 }
 
 func (s *FileSystem) Path(b config.Binary) string {
+	p := s.instantiateTemplate(b, s.FilePathTemplate)
+	if s.BundleEntrypointTemplate != "" {
+		p = filepath.Join(p, filepath.FromSlash(s.instantiateTemplate(b, s.BundleEntrypointTemplate)))
+	}
+	return p
+}
+
+// StorePath returns the path at which the binary, or the directory of the bundle containing it, is stored.
+func (s *FileSystem) StorePath(b config.Binary) string {
 	return s.instantiateTemplate(b, s.FilePathTemplate)
 }
 
-func (s *FileSystem) Fetch(b config.Binary) ([]byte, error) {
+func (s *FileSystem) Fetch(_ context.Context, b config.Binary) (io.ReadCloser, error) {
 	p := s.instantiateTemplate(b, s.FilePathTemplate)
 	log := s.log.With(zap.Stringer("tool", b), zap.String("local-path", p))
+	if s.BundleEntrypointTemplate != "" {
+		return s.fetchBundle(log, p)
+	}
 	fd, err := os.Open(p)
 	if err != nil {
 		log.Error("Failed to open tool binary file.", zap.Error(err))
 		return nil, err
 	}
-	raw, err := io.ReadAll(fd)
-	if err != nil {
-		log.Error("Failed to read content of tool binary file.", zap.Error(err))
-		return nil, err
-	}
-	return s.extractFromArchive(log, raw, p, b)
+	return s.extractFromArchive(log, fd, p, b)
+}
+
+func (s *FileSystem) Resolve(_ context.Context, b config.Binary) (string, error) {
+	return s.instantiateTemplate(b, s.FilePathTemplate), nil
 }
 
-func (s *FileSystem) Store(b config.Binary, content []byte) error {
+func (s *FileSystem) Store(ctx context.Context, b config.Binary, content io.Reader) error {
 	localPath := s.instantiateTemplate(b, s.FilePathTemplate)
 	log := s.log.With(zap.Stringer("tool", b), zap.String("local-path", localPath))
+	if s.BundleEntrypointTemplate != "" {
+		return s.storeBundle(ctx, log, b, localPath, content)
+	}
 
 	if _, err := os.Stat(localPath); err != nil && !errors.Is(err, os.ErrNotExist) {
 		log.Error("Unable to check for a pre-existing tool binary.", zap.Error(err))
@@ -70,11 +89,21 @@ func (s *FileSystem) Store(b config.Binary, content []byte) error {
 		return err
 	}
 
+	// The binary is streamed to a temporary file next to its final path which is only renamed once the full content
+	// has been successfully written. This guarantees that a partial binary never ends up at the final path.
 	toolBin, err := os.CreateTemp(toolDir, b.Tool+"-*")
 	if err != nil {
 		log.Error("Failed to open temporary file to store tool binary.", zap.Error(err))
 		return err
-	} else if _, err = toolBin.Write(content); err != nil {
+	}
+	defer func() {
+		if err = os.Remove(toolBin.Name()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warn("Failed to clean up temporary tool binary file.", zap.Error(err))
+		}
+	}()
+
+	if _, err = io.Copy(toolBin, content); err != nil {
+		_ = toolBin.Close()
 		log.Error("Failed to write tool binary to temp file.", zap.Error(err))
 		return err
 	} else if err = toolBin.Close(); err != nil {
@@ -83,6 +112,9 @@ func (s *FileSystem) Store(b config.Binary, content []byte) error {
 	} else if err = os.Chmod(toolBin.Name(), 0o755); err != nil {
 		log.Error("Failed to make temporary tool binary file executable.", zap.Error(err))
 		return err
+	} else if err = ctx.Err(); err != nil {
+		log.Debug("Tool binary storage was cancelled.", zap.Error(err))
+		return err
 	}
 
 	if err = os.Rename(toolBin.Name(), localPath); err != nil {
@@ -92,3 +124,70 @@ func (s *FileSystem) Store(b config.Binary, content []byte) error {
 	log.Debug("Successfully stored tool binary.")
 	return nil
 }
+
+func (s *FileSystem) fetchBundle(log *zap.Logger, bundleDir string) (io.ReadCloser, error) {
+	if _, err := os.Stat(bundleDir); err != nil {
+		log.Error("Failed to open tool bundle directory.", zap.Error(err))
+		return nil, err
+	}
+
+	bundle, err := createSpoolFile()
+	if err != nil {
+		log.Error("Failed to create temporary file to spool the bundle to.", zap.Error(err))
+		return nil, err
+	}
+	if err = writeBundle(bundle, bundleDir); err != nil {
+		_ = bundle.Close()
+		log.Error("Failed to write the bundle.", zap.Error(err))
+		return nil, err
+	}
+	if _, err = bundle.Seek(0, io.SeekStart); err != nil {
+		_ = bundle.Close()
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// storeBundle unpacks a bundle into a temporary directory next to its final location which, as for single binaries,
+// only replaces any previous content once the bundle has been unpacked in its entirety.
+func (s *FileSystem) storeBundle(ctx context.Context, log *zap.Logger, b config.Binary, bundleDir string, content io.Reader) error {
+	parentDir := filepath.Dir(bundleDir)
+	if err := os.MkdirAll(parentDir, 0o755); err != nil {
+		log.Error("Failed to create directory to store tool bundle.", zap.Error(err))
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp(parentDir, b.Tool+"-*")
+	if err != nil {
+		log.Error("Failed to create temporary directory to store tool bundle.", zap.Error(err))
+		return err
+	}
+	defer func() {
+		if err = os.RemoveAll(tmpDir); err != nil {
+			log.Warn("Failed to clean up temporary tool bundle directory.", zap.Error(err))
+		}
+	}()
+
+	entrypoint := s.instantiateTemplate(b, s.BundleEntrypointTemplate)
+	if err = readBundle(content, tmpDir); err != nil {
+		log.Error("Failed to unpack tool bundle to temporary directory.", zap.Error(err))
+		return err
+	} else if err = checkEntrypoint(tmpDir, entrypoint); err != nil {
+		log.Error("The tool bundle does not contain the entrypoint.", zap.String("entrypoint", entrypoint), zap.Error(err))
+		return err
+	} else if err = ctx.Err(); err != nil {
+		log.Debug("Tool bundle storage was cancelled.", zap.Error(err))
+		return err
+	}
+
+	if err = os.RemoveAll(bundleDir); err != nil {
+		log.Error("Failed to remove previous tool bundle.", zap.Error(err))
+		return err
+	}
+	if err = os.Rename(tmpDir, bundleDir); err != nil {
+		log.Error("Failed to move temporary tool bundle to final path.", zap.Error(err))
+		return err
+	}
+	log.Debug("Successfully stored tool bundle.")
+	return nil
+}
@@ -1,9 +1,14 @@
 package backend
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -19,17 +24,32 @@ func TestFileSystem(t *testing.T) {
 
 	assert.Equal(t, filepath.Join(td, "test-tool_v1.2.3_linux_x86_64"), fs.Path(stdTestBinary))
 
-	b, err := fs.Fetch(stdTestBinary)
+	b, err := fs.Fetch(context.Background(), stdTestBinary)
 	require.ErrorIs(t, err, os.ErrNotExist)
 	assert.Nil(t, b)
 
-	err = fs.Store(stdTestBinary, stdTestBinaryContent)
+	err = fs.Store(context.Background(), stdTestBinary, bytes.NewReader(stdTestBinaryContent))
 	require.NoError(t, err)
 
-	b, err = fs.Fetch(stdTestBinary)
+	b, err = fs.Fetch(context.Background(), stdTestBinary)
 	require.NoError(t, err)
-	assert.Equal(t, stdTestBinaryContent, b)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
 
-	err = fs.Store(stdTestBinary, stdTestBinaryContent)
+	err = fs.Store(context.Background(), stdTestBinary, bytes.NewReader(stdTestBinaryContent))
 	require.NoError(t, err)
 }
+
+func TestFileSystemStoreInterrupted(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	fs := NewFileSystem(logger.NewTestBuilder(), &FileSystemConfig{FilePathTemplate: filepath.Join(td, stdTestTemplate)})
+
+	errInterrupted := errors.New("interrupted")
+	err := fs.Store(context.Background(), stdTestBinary, io.MultiReader(bytes.NewReader(stdTestBinaryContent), iotest.ErrReader(errInterrupted)))
+	require.ErrorIs(t, err, errInterrupted)
+
+	entries, err := os.ReadDir(td)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "no partial binary should remain after an interrupted store")
+}
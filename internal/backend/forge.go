@@ -0,0 +1,190 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/logger"
+)
+
+// forgeAPI performs requests against the REST API of a self-hosted software forge such as GitLab or Gitea. The token,
+// if any, is only sent to the forge itself and never to other hosts that release assets may link to.
+type forgeAPI struct {
+	client     *http.Client
+	baseURL    *url.URL
+	authHeader string
+	authValue  string
+}
+
+func newForgeAPI(log *zap.Logger, baseURL string, tokenEnvs []string, authHeader string, authPrefix string) (*forgeAPI, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		log.Error("Invalid base URL.", zap.String("base-url", baseURL), zap.Error(err))
+		return nil, fmt.Errorf("invalid base url %q: %w", baseURL, err)
+	}
+
+	api := &forgeAPI{
+		client:     http.DefaultClient,
+		baseURL:    u,
+		authHeader: authHeader,
+	}
+	for _, env := range tokenEnvs {
+		if token := os.Getenv(env); token != "" {
+			log.Debug("Authenticating against the API.", zap.String("token-env", env))
+			api.authValue = authPrefix + token
+			break
+		}
+	}
+	return api, nil
+}
+
+// endpoint returns the URL of an API endpoint. Path elements are escaped individually so that they may contain
+// slashes, as required for example for GitLab project paths.
+func (a *forgeAPI) endpoint(apiPrefix string, elems ...string) string {
+	escaped := make([]string, 0, len(elems))
+	for _, e := range elems {
+		escaped = append(escaped, url.PathEscape(e))
+	}
+	return a.baseURL.String() + apiPrefix + "/" + strings.Join(escaped, "/")
+}
+
+func (a *forgeAPI) get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if a.authValue != "" && req.URL.Host == a.baseURL.Host {
+		req.Header.Set(a.authHeader, a.authValue)
+	}
+	return a.client.Do(req)
+}
+
+// getJSON decodes the response of a GET request into the target. It returns false without an error if the requested
+// resource does not exist.
+func (a *forgeAPI) getJSON(ctx context.Context, u string, target interface{}) (bool, error) {
+	r, err := a.get(ctx, u)
+	if err != nil {
+		return false, err
+	}
+	defer r.Body.Close()
+
+	switch r.StatusCode {
+	case http.StatusOK:
+		if err = json.NewDecoder(r.Body).Decode(target); err != nil {
+			return false, fmt.Errorf("failed to decode response from %q: %w", u, err)
+		}
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("request to %q returned %s: %w", u, r.Status, ErrHTTPStatusCode)
+	}
+}
+
+// forgePageSize is the number of items requested per page from paginated endpoints. Servers may cap it to a lower
+// value, so pages are requested until an empty one is returned.
+const forgePageSize = 50
+
+// getAllPages decodes all items returned by a paginated endpoint. The name of the query parameter that sets the page
+// size differs between forges.
+func getAllPages[T any](ctx context.Context, a *forgeAPI, u string, pageSizeParam string) ([]T, error) {
+	var all []T
+	for page := 1; ; page++ {
+		var items []T
+		if _, err := a.getJSON(ctx, fmt.Sprintf("%s?page=%d&%s=%d", u, page, pageSizeParam, forgePageSize), &items); err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			return all, nil
+		}
+		all = append(all, items...)
+	}
+}
+
+// releaseTags returns the tags under which the release of a version may have been published, both with and without a
+// 'v' prefix.
+func releaseTags(version string) []string {
+	if strings.HasPrefix(version, "v") {
+		return []string{version, strings.TrimPrefix(version, "v")}
+	}
+	return []string{version, "v" + version}
+}
+
+// releaseVersion returns the version corresponding to a release tag.
+func releaseVersion(tag string) string {
+	if len(tag) > 1 && tag[0] == 'v' && tag[1] >= '0' && tag[1] <= '9' {
+		return tag[1:]
+	}
+	return tag
+}
+
+// download opens the content at the given URL, authenticating the request if it targets the forge itself.
+func (a *forgeAPI) download(ctx context.Context, u string) (io.ReadCloser, error) {
+	r, err := a.get(ctx, u)
+	if err != nil {
+		return nil, err
+	} else if r.StatusCode != http.StatusOK {
+		_ = r.Body.Close()
+		return nil, fmt.Errorf("download of %q returned %s: %w", u, r.Status, ErrHTTPStatusCode)
+	}
+	return r.Body, nil
+}
+
+// AssetFetcher is implemented by sources that download binaries from the release assets of a software forge. It allows
+// downloading an asset from the URL to which the source resolved it with the same authentication as all other requests
+// to the forge, which is required for the assets of private repositories.
+type AssetFetcher interface {
+	FetchAsset(ctx context.Context, u string) (io.ReadCloser, error)
+}
+
+// ReleaseAsset fetches binaries from a single release asset, e.g. one recorded in a lock file, through the forge source
+// that resolved it.
+type ReleaseAsset struct {
+	log     *zap.Logger
+	url     string
+	fetcher AssetFetcher
+
+	CommonConfig
+}
+
+func NewReleaseAsset(logBuilder logger.Builder, c CommonConfig, u string, fetcher AssetFetcher) *ReleaseAsset {
+	return &ReleaseAsset{
+		log:          logBuilder.Domain(logger.HTTPSDomain),
+		url:          u,
+		fetcher:      fetcher,
+		CommonConfig: c,
+	}
+}
+
+func (s *ReleaseAsset) String() string {
+	return s.url
+}
+
+func (s *ReleaseAsset) Fetch(ctx context.Context, b config.Binary) (io.ReadCloser, error) {
+	log := s.log.With(zap.Stringer("tool", b), zap.String("url", s.url))
+
+	rc, err := s.fetcher.FetchAsset(ctx, s.url)
+	if err != nil {
+		log.Error("Failed to download the release asset.", zap.Error(err))
+		return nil, err
+	}
+	return s.extractFromArchive(log, rc, s.url, b)
+}
+
+func (s *ReleaseAsset) Resolve(_ context.Context, _ config.Binary) (string, error) {
+	return s.url, nil
+}
+
+func (s *ReleaseAsset) Store(_ context.Context, _ config.Binary, _ io.Reader) error {
+	s.log.Error("Cannot perform 'store' operations on a release asset.")
+	return errFailed
+}
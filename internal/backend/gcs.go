@@ -1,7 +1,6 @@
 package backend
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -28,9 +27,8 @@ func (c GCSConfig) String() string {
 }
 
 type GCS struct {
-	log     *zap.Logger
-	timeout time.Duration
-	client  *storage.Client
+	log    *zap.Logger
+	client *storage.Client
 
 	GCSConfig
 }
@@ -48,13 +46,12 @@ func NewGCS(logBuilder logger.Builder, c *GCSConfig) *GCS {
 
 	return &GCS{
 		log:       log,
-		timeout:   time.Minute,
 		client:    client,
 		GCSConfig: *c,
 	}
 }
 
-func (s *GCS) Fetch(b config.Binary) ([]byte, error) {
+func (s *GCS) Fetch(ctx context.Context, b config.Binary) (io.ReadCloser, error) {
 	bucketPath := s.instantiateTemplate(b, s.GCSPathTemplate)
 	log := s.log.With(
 		zap.Stringer("tool", b),
@@ -62,7 +59,7 @@ func (s *GCS) Fetch(b config.Binary) ([]byte, error) {
 	)
 
 	obj := s.client.Bucket(s.GCSBucket).Object(bucketPath)
-	src, err := obj.NewReader(context.Background()) // Background context as we don't want to interrupt a download.
+	src, err := obj.NewReader(ctx)
 	if err != nil {
 		if errors.Is(err, storage.ErrObjectNotExist) {
 			log.Error("No binary found.")
@@ -71,47 +68,45 @@ func (s *GCS) Fetch(b config.Binary) ([]byte, error) {
 		}
 		return nil, err
 	}
-	defer src.Close()
-
-	raw, err := io.ReadAll(src)
-	if err != nil {
-		return nil, err
-	}
-	s.log.Debug("Finished downloading blob from GCS.")
-	return s.extractFromArchive(log, raw, bucketPath, b)
+	s.log.Debug("Opened blob from GCS for download.")
+	return s.extractFromArchive(log, src, bucketPath, b)
 }
 
-func (s *GCS) Store(b config.Binary, content []byte) (err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
-	defer cancel()
+func (s *GCS) Resolve(_ context.Context, b config.Binary) (string, error) {
+	return fmt.Sprintf("gs://%s/%s", s.GCSBucket, s.instantiateTemplate(b, s.GCSPathTemplate)), nil
+}
 
+func (s *GCS) Store(ctx context.Context, b config.Binary, content io.Reader) error {
 	log := s.log.With(zap.Stringer("tool", b))
 
 	bucketPath := s.instantiateTemplate(b, s.GCSPathTemplate)
 	log = log.With(zap.String("artefact-path", bucketPath))
 
 	obj := s.client.Bucket(s.GCSBucket).Object(bucketPath)
-	if _, err = obj.Attrs(ctx); err == nil {
+	if _, err := obj.Attrs(ctx); err == nil {
 		log.Error("Can not store new binary as one already exists.")
-		return errFailed
+		return ErrAlreadyExists
 	} else if !errors.Is(err, storage.ErrObjectNotExist) {
 		log.Error("Can not check if a binary already exists.", zap.Error(err))
 		return err
 	}
 
-	dst := obj.NewWriter(context.Background()) // Background context as we don't want to interrupt an upload.
-	defer func() {
-		closeErr := dst.Close()
-		if err == nil && closeErr != nil {
-			log.Error("Failed to correctly close remote object.", zap.Error(err))
-			err = closeErr
-		}
-	}()
+	// Cancelling the context aborts the upload without leaving a partial object behind, whereas closing the writer
+	// commits whatever was written so far.
+	uploadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	if _, err = io.Copy(dst, bytes.NewReader(content)); err != nil {
+	dst := obj.NewWriter(uploadCtx)
+	if _, err := io.Copy(dst, content); err != nil {
+		cancel()
+		_ = dst.Close()
 		log.Error("Failed to upload tool binary.", zap.Error(err))
 		return err
 	}
+	if err := dst.Close(); err != nil {
+		log.Error("Failed to correctly close remote object.", zap.Error(err))
+		return err
+	}
 	log.Debug("Finished uploading the binary as blob to GCS.")
 	return nil
 }
@@ -1,8 +1,9 @@
 package backend
 
 import (
+	"bytes"
+	"context"
 	"testing"
-	"time"
 
 	"github.com/fsouza/fake-gcs-server/fakestorage"
 	"github.com/stretchr/testify/assert"
@@ -19,26 +20,25 @@ func TestGCS(t *testing.T) {
 	fakeGCS.CreateBucketWithOpts(fakestorage.CreateBucketOpts{Name: bucketName})
 
 	gcs := &GCS{
-		log:     zap.NewNop(),
-		timeout: 10 * time.Second,
-		client:  fakeGCS.Client(),
+		log:    zap.NewNop(),
+		client: fakeGCS.Client(),
 		GCSConfig: GCSConfig{
 			GCSBucket:       bucketName,
 			GCSPathTemplate: stdTestTemplate,
 		},
 	}
 
-	b, err := gcs.Fetch(stdTestBinary)
+	b, err := gcs.Fetch(context.Background(), stdTestBinary)
 	require.Error(t, err)
 	assert.Nil(t, b)
 
-	err = gcs.Store(stdTestBinary, stdTestBinaryContent)
+	err = gcs.Store(context.Background(), stdTestBinary, bytes.NewReader(stdTestBinaryContent))
 	require.NoError(t, err)
 
-	err = gcs.Store(stdTestBinary, stdTestBinaryContent)
-	require.Error(t, err)
+	err = gcs.Store(context.Background(), stdTestBinary, bytes.NewReader(stdTestBinaryContent))
+	require.ErrorIs(t, err, ErrAlreadyExists)
 
-	b, err = gcs.Fetch(stdTestBinary)
+	b, err = gcs.Fetch(context.Background(), stdTestBinary)
 	require.NoError(t, err)
-	assert.Equal(t, stdTestBinaryContent, b)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
 }
@@ -0,0 +1,196 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/logger"
+)
+
+var (
+	ErrInvalidGiteaRepo         = errors.New("repo slug is invalid")
+	ErrUnknownGiteaRelease      = errors.New("gitea release does not exist")
+	ErrUnknownGiteaReleaseAsset = errors.New("gitea release does not contain asset")
+)
+
+// GiteaConfig configures a source on a Gitea server or on any of its forks that retain its API, such as Forgejo.
+type GiteaConfig struct {
+	CommonConfig
+
+	GiteaRepo                 string `json:"gitea_repo"`
+	GiteaReleaseAssetTemplate string `json:"gitea_release_asset_template"`
+	GiteaBaseURL              string `json:"gitea_base_url"`
+	GiteaTokenEnv             string `json:"gitea_token_env"`
+}
+
+func (c GiteaConfig) String() string {
+	return fmt.Sprintf("%s/%s", c.GiteaBaseURL, c.GiteaRepo)
+}
+
+const giteaAPIPrefix = "/api/v1"
+
+// Environment variables from which a Gitea token is read, in order of precedence, when a source does not specify its
+// own environment variable or when that variable is not set.
+var giteaTokenEnvs = []string{"GITEA_TOKEN", "FORGEJO_TOKEN"}
+
+type Gitea struct {
+	log *zap.Logger
+	api *forgeAPI
+
+	GiteaConfig
+}
+
+type giteaRelease struct {
+	TagName    string              `json:"tag_name"`
+	Draft      bool                `json:"draft"`
+	Prerelease bool                `json:"prerelease"`
+	Assets     []giteaReleaseAsset `json:"assets"`
+}
+
+type giteaReleaseAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+func NewGitea(logBuilder logger.Builder, c *GiteaConfig) *Gitea {
+	log := logBuilder.Domain(logger.GiteaDomain).With(zap.Stringer("gitea-repo", c))
+
+	tokenEnvs := giteaTokenEnvs
+	if c.GiteaTokenEnv != "" {
+		tokenEnvs = append([]string{c.GiteaTokenEnv}, tokenEnvs...)
+	}
+	api, err := newForgeAPI(log, c.GiteaBaseURL, tokenEnvs, "Authorization", "token ")
+	if err != nil {
+		log.Error("Failed to initialise new Gitea client.", zap.Error(err))
+		panic(err)
+	}
+
+	return &Gitea{
+		log:         log,
+		api:         api,
+		GiteaConfig: *c,
+	}
+}
+
+func (s *Gitea) Fetch(ctx context.Context, b config.Binary) (io.ReadCloser, error) {
+	log := s.log.With(zap.Stringer("tool", b))
+	a, err := s.getReleaseAsset(ctx, log, b)
+	if err != nil {
+		return nil, err
+	}
+	log = log.With(zap.String("release-asset", a.Name), zap.String("url", a.BrowserDownloadURL))
+
+	log.Debug("Downloading the release asset.")
+	r, err := s.api.get(ctx, a.BrowserDownloadURL)
+	if err != nil {
+		log.Error("Failed to download the release asset.", zap.Error(err))
+		return nil, fmt.Errorf("failed to download asset %q from release %q in repository %q: %w", a.Name, b.Version, s.GiteaRepo, err)
+	} else if r.StatusCode != http.StatusOK {
+		_ = r.Body.Close()
+		log.Error("Download of the release asset returned a non-200 code.", zap.Int("http-code", r.StatusCode))
+		return nil, ErrHTTPStatusCode
+	}
+	return s.extractFromArchive(log, r.Body, a.Name, b)
+}
+
+func (s *Gitea) Resolve(ctx context.Context, b config.Binary) (string, error) {
+	a, err := s.getReleaseAsset(ctx, s.log.With(zap.Stringer("tool", b)), b)
+	if err != nil {
+		return "", err
+	}
+	return a.BrowserDownloadURL, nil
+}
+
+// FetchAsset downloads the release asset at the given URL. The forge's token, if any, is only sent along if the asset
+// is hosted by the forge itself.
+func (s *Gitea) FetchAsset(ctx context.Context, u string) (io.ReadCloser, error) {
+	return s.api.download(ctx, u)
+}
+
+func (s *Gitea) Store(_ context.Context, _ config.Binary, _ io.Reader) error {
+	s.log.Error("Cannot perform 'store' operations on a Gitea backend.")
+	return errFailed
+}
+
+// Releases lists the published releases of the repository from newest to oldest. Drafts are omitted.
+func (s *Gitea) Releases(ctx context.Context) ([]Release, error) {
+	owner, repo, err := s.repo(s.log)
+	if err != nil {
+		return nil, err
+	}
+
+	listed, err := getAllPages[giteaRelease](ctx, s.api, s.api.endpoint(giteaAPIPrefix, "repos", owner, repo, "releases"), "limit")
+	if err != nil {
+		s.log.Error("Failed to list the repository's releases.", zap.Error(err))
+		return nil, fmt.Errorf("unable to list releases for %q: %w", s.GiteaRepo, err)
+	}
+
+	var releases []Release
+	for _, r := range listed {
+		if !r.Draft {
+			releases = append(releases, Release{Version: releaseVersion(r.TagName), Prerelease: r.Prerelease})
+		}
+	}
+	return releases, nil
+}
+
+func (s *Gitea) repo(log *zap.Logger) (string, string, error) {
+	owner, repo, ok := strings.Cut(s.GiteaRepo, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		log.Error("Invalid repo slug.", zap.String("slug", s.GiteaRepo))
+		return "", "", ErrInvalidGiteaRepo
+	}
+	return owner, repo, nil
+}
+
+func (s *Gitea) getReleaseAsset(ctx context.Context, log *zap.Logger, b config.Binary) (*giteaReleaseAsset, error) {
+	owner, repo, err := s.repo(log)
+	if err != nil {
+		return nil, err
+	}
+
+	var release *giteaRelease
+	for _, tag := range releaseTags(b.Version) {
+		var r giteaRelease
+		found, err := s.api.getJSON(ctx, s.api.endpoint(giteaAPIPrefix, "repos", owner, repo, "releases", "tags", tag), &r)
+		if err != nil {
+			log.Error("Failed to retrieve release.", zap.String("tag", tag), zap.Error(err))
+			return nil, fmt.Errorf("unable to request release %q for %q: %w", tag, s.GiteaRepo, err)
+		} else if found {
+			log.Debug("Found targeted release by tag.", zap.String("tag", tag))
+			release = &r
+			break
+		}
+		log.Debug("No release found for tag.", zap.String("tag", tag))
+	}
+	if release == nil {
+		log.Error("The targeted release was not found within the Gitea repository.")
+		return nil, ErrUnknownGiteaRelease
+	}
+
+	pattern, err := s.namePattern(b, s.GiteaReleaseAssetTemplate)
+	if err != nil {
+		log.Error("Invalid release asset template.", zap.Error(err))
+		return nil, err
+	}
+	log = log.With(zap.Stringer("release-asset", pattern))
+
+	names := make([]string, 0, len(release.Assets))
+	for _, a := range release.Assets {
+		names = append(names, a.Name)
+	}
+	idx, err := pattern.selectMatch(names)
+	if err != nil {
+		log.Error("No single release asset matches within the release.", zap.Error(err))
+		return nil, releaseAssetError(ErrUnknownGiteaReleaseAsset, err)
+	}
+	log.Debug("Found targeted release asset.", zap.String("asset", names[idx]))
+	return &release.Assets[idx], nil
+}
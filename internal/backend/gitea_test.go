@@ -0,0 +1,127 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/logger"
+)
+
+func TestGitea(t *testing.T) {
+	t.Setenv("GITEA_TOKEN", "")
+	t.Setenv("FORGEJO_TOKEN", "secret")
+
+	var gitea *httptest.Server
+	gitea = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/v1/repos/owner/repo/releases/tags/v1.2.3":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"tag_name": "v1.2.3",
+				"assets": []map[string]interface{}{
+					{
+						"id":                   1,
+						"name":                 "test-tool_v1.2.3_linux_x86_64",
+						"browser_download_url": gitea.URL + "/owner/repo/releases/download/v1.2.3/test-tool_v1.2.3_linux_x86_64",
+					},
+					{
+						"id":                   2,
+						"name":                 "test-tool_v1.2.3_linux_x86_64.sha256",
+						"browser_download_url": gitea.URL + "/owner/repo/releases/download/v1.2.3/test-tool_v1.2.3_linux_x86_64.sha256",
+					},
+				},
+			})
+		case "/api/v1/repos/owner/repo/releases":
+			// The server caps the page size below the requested one.
+			pages := [][]map[string]interface{}{
+				{{"tag_name": "v2.0.0-rc1", "prerelease": true}, {"tag_name": "v1.3.0", "draft": true}},
+				{{"tag_name": "v1.2.3"}},
+			}
+			if page, _ := strconv.Atoi(r.URL.Query().Get("page")); page >= 1 && page <= len(pages) {
+				_ = json.NewEncoder(w).Encode(pages[page-1])
+				return
+			}
+			_, _ = w.Write([]byte("[]"))
+		case "/owner/repo/releases/download/v1.2.3/test-tool_v1.2.3_linux_x86_64":
+			_, _ = w.Write(stdTestBinaryContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(gitea.Close)
+
+	gt := NewGitea(logger.NewTestBuilder(), &GiteaConfig{
+		GiteaRepo:                 "owner/repo",
+		GiteaReleaseAssetTemplate: stdTestTemplate,
+		GiteaBaseURL:              gitea.URL + "/",
+	})
+
+	b, err := gt.Fetch(context.Background(), stdTestBinary)
+	require.NoError(t, err)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
+
+	u, err := gt.Resolve(context.Background(), stdTestBinary)
+	require.NoError(t, err)
+	assert.Equal(t, gitea.URL+"/owner/repo/releases/download/v1.2.3/test-tool_v1.2.3_linux_x86_64", u)
+
+	// Release assets recorded in lock files are downloaded with the same authentication.
+	b, err = NewReleaseAsset(logger.NewTestBuilder(), CommonConfig{}, u, gt).Fetch(context.Background(), stdTestBinary)
+	require.NoError(t, err)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
+
+	releases, err := gt.Releases(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Release{{Version: "2.0.0-rc1", Prerelease: true}, {Version: "1.2.3"}}, releases)
+
+	missingAsset := stdTestBinary
+	missingAsset.Platform = config.PlatformDarwin
+	_, err = gt.Fetch(context.Background(), missingAsset)
+	require.ErrorIs(t, err, ErrUnknownGiteaReleaseAsset)
+
+	missingRelease := stdTestBinary
+	missingRelease.Version = "2.0.0"
+	_, err = gt.Resolve(context.Background(), missingRelease)
+	require.ErrorIs(t, err, ErrUnknownGiteaRelease)
+
+	globbed := NewGitea(logger.NewTestBuilder(), &GiteaConfig{
+		GiteaRepo:                 "owner/repo",
+		GiteaReleaseAssetTemplate: "{tool}_*_{platform}_{arch}",
+		GiteaBaseURL:              gitea.URL,
+	})
+	u, err = globbed.Resolve(context.Background(), stdTestBinary)
+	require.NoError(t, err)
+	assert.Equal(t, gitea.URL+"/owner/repo/releases/download/v1.2.3/test-tool_v1.2.3_linux_x86_64", u)
+
+	ambiguous := NewGitea(logger.NewTestBuilder(), &GiteaConfig{
+		GiteaRepo:                 "owner/repo",
+		GiteaReleaseAssetTemplate: "{tool}_{version}_*",
+		GiteaBaseURL:              gitea.URL,
+	})
+	_, err = ambiguous.Resolve(context.Background(), stdTestBinary)
+	require.ErrorIs(t, err, ErrAmbiguousMatch)
+	assert.ErrorContains(t, err, "test-tool_v1.2.3_linux_x86_64, test-tool_v1.2.3_linux_x86_64.sha256")
+
+	invalidRepo := NewGitea(logger.NewTestBuilder(), &GiteaConfig{
+		GiteaRepo:                 "owner/repo/extra",
+		GiteaReleaseAssetTemplate: stdTestTemplate,
+		GiteaBaseURL:              gitea.URL,
+	})
+	_, err = invalidRepo.Resolve(context.Background(), stdTestBinary)
+	require.ErrorIs(t, err, ErrInvalidGiteaRepo)
+
+	err = gt.Store(context.Background(), stdTestBinary, bytes.NewReader(stdTestBinaryContent))
+	require.Error(t, err)
+}
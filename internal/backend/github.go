@@ -1,12 +1,13 @@
 package backend
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
 	"strings"
 	"time"
 
@@ -30,6 +31,7 @@ type GitHubConfig struct {
 	GitHubSlug                 string `json:"github_slug"`
 	GitHubReleaseAssetTemplate string `json:"github_release_asset_template"`
 	GitHubBaseURL              string `json:"github_base_url"`
+	GitHubTokenEnv             string `json:"github_token_env"`
 }
 
 func (c GitHubConfig) String() string {
@@ -40,10 +42,19 @@ func (c GitHubConfig) String() string {
 	return fmt.Sprintf("%s/%s", githubBase, c.GitHubSlug)
 }
 
+// Environment variables from which a GitHub token is read, in order of precedence, when a source does not specify its
+// own environment variable or when that variable is not set.
+var gitHubTokenEnvs = []string{"GITHUB_TOKEN", "GH_TOKEN"}
+
+const (
+	maxGitHubRateLimitRetries = 3
+	defaultGitHubRetryAfter   = time.Minute
+)
+
 type GitHub struct {
-	log     *zap.Logger
-	timeout time.Duration
-	client  *github.Client
+	log           *zap.Logger
+	client        *github.Client
+	authenticated bool
 
 	GitHubConfig
 }
@@ -56,6 +67,12 @@ func NewGitHub(logBuilder logger.Builder, c *GitHubConfig) *GitHub {
 
 	log := logBuilder.Domain(logger.GitHubDomain).With(zap.Stringer("github-repo", c))
 	client = github.NewClient(http.DefaultClient)
+
+	token, tokenEnv := gitHubToken(c)
+	if token != "" {
+		log.Debug("Authenticating against GitHub.", zap.String("token-env", tokenEnv))
+		client = client.WithAuthToken(token)
+	}
 	if c.GitHubBaseURL != "" {
 		client, err = client.WithEnterpriseURLs(c.GitHubBaseURL, c.GitHubBaseURL)
 		if err != nil {
@@ -65,96 +82,326 @@ func NewGitHub(logBuilder logger.Builder, c *GitHubConfig) *GitHub {
 	}
 
 	return &GitHub{
-		log:          log,
-		timeout:      time.Minute,
-		client:       client,
-		GitHubConfig: *c,
+		log:           log,
+		client:        client,
+		authenticated: token != "",
+		GitHubConfig:  *c,
 	}
 }
 
-func (s *GitHub) Fetch(b config.Binary) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
-	defer cancel()
+func gitHubToken(c *GitHubConfig) (string, string) {
+	envs := gitHubTokenEnvs
+	if c.GitHubTokenEnv != "" {
+		envs = append([]string{c.GitHubTokenEnv}, envs...)
+	}
+	for _, env := range envs {
+		if token := os.Getenv(env); token != "" {
+			return token, env
+		}
+	}
+	return "", ""
+}
 
+func (s *GitHub) Fetch(ctx context.Context, b config.Binary) (io.ReadCloser, error) {
 	log := s.log.With(zap.Stringer("tool", b))
-	repoSlug := strings.Split(s.GitHubSlug, "/")
-	if len(repoSlug) != 2 {
-		log.Error("Invalid repo slug.", zap.String("slug", s.GitHubSlug))
-		return nil, ErrInvalidGitHubSlug
+	repoSlug, a, err := s.getReleaseAsset(ctx, log, b)
+	if err != nil {
+		return nil, err
 	}
+	log = log.With(zap.String("release-asset", a.GetName()))
 
-	gr, err := s.getRelease(ctx, log, repoSlug, b.Version)
+	log.Debug("Downloading the release asset.")
+	var dl io.ReadCloser
+	err = s.withRateLimitRetry(ctx, log, func() (err error) {
+		dl, _, err = s.client.Repositories.DownloadReleaseAsset(ctx, repoSlug[0], repoSlug[1], a.GetID(), http.DefaultClient)
+		return err
+	})
 	if err != nil {
-		return nil, err
+		log.Error("Could not get download handle for the release asset.", zap.Error(err))
+		return nil, fmt.Errorf("failed to get link to asset %q from release %q in repository %q: %w", a.GetName(), b.Version, s.GitHubSlug, err)
 	}
 
-	assetName := s.instantiateTemplate(b, s.GitHubReleaseAssetTemplate)
-	log = log.With(zap.String("release-asset", assetName))
+	return s.extractFromArchive(log, dl, a.GetName(), b)
+}
 
-	var a *github.ReleaseAsset
-	for _, c := range gr.Assets {
-		if c.GetName() == assetName {
-			log.Debug("Found targeted release asset.")
-			a = c
-			break
-		}
+func (s *GitHub) Resolve(ctx context.Context, b config.Binary) (string, error) {
+	_, a, err := s.getReleaseAsset(ctx, s.log.With(zap.Stringer("tool", b)), b)
+	if err != nil {
+		return "", err
 	}
-	if a == nil {
-		log.Error("The targeted release asset was not found within the release.")
-		return nil, ErrUnknownGitHubReleaseAsset
+	return a.GetBrowserDownloadURL(), nil
+}
+
+// FetchAsset downloads the release asset at the given browser download URL. GitHub does not accept tokens on these URLs,
+// so authenticated downloads, which are required for the assets of private repositories, go through the API instead.
+// Unauthenticated downloads use the URL directly so as not to count against the API's rate limit.
+func (s *GitHub) FetchAsset(ctx context.Context, u string) (io.ReadCloser, error) {
+	log := s.log.With(zap.String("url", u))
+
+	if !s.authenticated {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		} else if r.StatusCode != http.StatusOK {
+			_ = r.Body.Close()
+			log.Error("Download of the release asset returned a non-200 code.", zap.Int("http-code", r.StatusCode))
+			return nil, ErrHTTPStatusCode
+		}
+		return r.Body, nil
 	}
 
-	log.Debug("Downloading the release asset.")
-	dl, _, err := s.client.Repositories.DownloadReleaseAsset(ctx, repoSlug[0], repoSlug[1], a.GetID(), http.DefaultClient)
+	// Browser download URLs are of the form '<host>/<owner>/<repo>/releases/download/<tag>/<asset>'.
+	parsed, err := url.Parse(u)
 	if err != nil {
-		log.Error("Could not get download handle for the release asset.", zap.Error(err))
-		return nil, fmt.Errorf("failed to get link to asset %q from release %q in repository %q: %w", assetName, b.Version, s.GitHubSlug, err)
+		return nil, fmt.Errorf("invalid release asset url %q: %w", u, err)
 	}
-	buf := &bytes.Buffer{}
-	if _, err = io.Copy(buf, dl); err != nil {
-		log.Error("Download failed.", zap.Error(err))
-		return nil, fmt.Errorf("failed to download asset %q from release %q in repository %q: %w", assetName, b.Version, s.GitHubSlug, err)
+	elems := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	n := len(elems)
+	if n < 6 || elems[n-4] != "releases" || elems[n-3] != "download" {
+		log.Error("URL is not a browser download URL of a release asset.")
+		return nil, fmt.Errorf("%w: %q", ErrUnknownGitHubReleaseAsset, u)
+	}
+	owner, repo, tag, name := elems[n-6], elems[n-5], elems[n-2], elems[n-1]
+
+	var gr *github.RepositoryRelease
+	err = s.withRateLimitRetry(ctx, log, func() (err error) {
+		gr, _, err = s.client.Repositories.GetReleaseByTag(ctx, owner, repo, tag)
+		return err
+	})
+	if err != nil {
+		log.Error("Failed to retrieve the release of the asset.", zap.Error(err))
+		return nil, fmt.Errorf("unable to request release %q for %q: %w", tag, owner+"/"+repo, err)
 	}
-	log.Debug("Finished downloading the release asset.")
 
-	return s.extractFromArchive(log, buf.Bytes(), assetName, b)
+	for _, a := range gr.Assets {
+		if a.GetName() != name {
+			continue
+		}
+		var dl io.ReadCloser
+		err = s.withRateLimitRetry(ctx, log, func() (err error) {
+			dl, _, err = s.client.Repositories.DownloadReleaseAsset(ctx, owner, repo, a.GetID(), http.DefaultClient)
+			return err
+		})
+		if err != nil {
+			log.Error("Could not get download handle for the release asset.", zap.Error(err))
+			return nil, fmt.Errorf("failed to get link to asset %q from release %q in repository %q: %w", name, tag, owner+"/"+repo, err)
+		}
+		return dl, nil
+	}
+	log.Error("The release does not contain the asset.")
+	return nil, fmt.Errorf("%w: %q", ErrUnknownGitHubReleaseAsset, u)
 }
 
-func (s *GitHub) Store(_ config.Binary, _ []byte) error {
+func (s *GitHub) Store(_ context.Context, _ config.Binary, _ io.Reader) error {
 	s.log.Error("Cannot perform 'store' operations on a GitHub backend.")
 	return errFailed
 }
 
+// Releases lists the published releases of the repository from newest to oldest. Drafts are omitted.
+func (s *GitHub) Releases(ctx context.Context) ([]Release, error) {
+	repoSlug, err := s.repoSlug(s.log)
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []Release
+	err = s.forEachRelease(ctx, s.log, repoSlug, func(r *github.RepositoryRelease) bool {
+		if !r.GetDraft() {
+			releases = append(releases, Release{Version: releaseVersion(r.GetTagName()), Prerelease: r.GetPrerelease()})
+		}
+		return true
+	})
+	if err != nil {
+		s.log.Error("Failed to list the repository's releases.", zap.Error(err))
+		return nil, err
+	}
+	return releases, nil
+}
+
+// LatestRelease returns the version of the repository's latest release, i.e. the newest one that is neither a draft nor
+// a pre-release, together with the names of its assets.
+func (s *GitHub) LatestRelease(ctx context.Context) (string, []string, error) {
+	repoSlug, err := s.repoSlug(s.log)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var gr *github.RepositoryRelease
+	err = s.withRateLimitRetry(ctx, s.log, func() (err error) {
+		gr, _, err = s.client.Repositories.GetLatestRelease(ctx, repoSlug[0], repoSlug[1])
+		return err
+	})
+	if err != nil {
+		s.log.Error("Failed to retrieve the repository's latest release.", zap.Error(err))
+		return "", nil, fmt.Errorf("unable to request latest release for %q: %w", s.GitHubSlug, err)
+	}
+
+	names := make([]string, 0, len(gr.Assets))
+	for _, a := range gr.Assets {
+		names = append(names, a.GetName())
+	}
+	return releaseVersion(gr.GetTagName()), names, nil
+}
+
+func (s *GitHub) repoSlug(log *zap.Logger) ([]string, error) {
+	repoSlug := strings.Split(s.GitHubSlug, "/")
+	if len(repoSlug) != 2 {
+		log.Error("Invalid repo slug.", zap.String("slug", s.GitHubSlug))
+		return nil, ErrInvalidGitHubSlug
+	}
+	return repoSlug, nil
+}
+
+func (s *GitHub) getReleaseAsset(ctx context.Context, log *zap.Logger, b config.Binary) ([]string, *github.ReleaseAsset, error) {
+	repoSlug, err := s.repoSlug(log)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gr, err := s.getRelease(ctx, log, repoSlug, b.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pattern, err := s.namePattern(b, s.GitHubReleaseAssetTemplate)
+	if err != nil {
+		log.Error("Invalid release asset template.", zap.Error(err))
+		return nil, nil, err
+	}
+	log = log.With(zap.Stringer("release-asset", pattern))
+
+	names := make([]string, 0, len(gr.Assets))
+	for _, a := range gr.Assets {
+		names = append(names, a.GetName())
+	}
+	idx, err := pattern.selectMatch(names)
+	if err != nil {
+		log.Error("No single release asset matches within the release.", zap.Error(err))
+		return nil, nil, releaseAssetError(ErrUnknownGitHubReleaseAsset, err)
+	}
+	log.Debug("Found targeted release asset.", zap.String("asset", names[idx]))
+	return repoSlug, gr.Assets[idx], nil
+}
+
+// getRelease retrieves the release for the given version. Releases are looked up directly by their tag name, both with
+// and without a 'v' prefix. Only if that fails are all releases scanned for one with a matching tag.
 func (s *GitHub) getRelease(ctx context.Context, log *zap.Logger, repoSlug []string, version string) (*github.RepositoryRelease, error) {
-	page := 1
+	for _, tag := range releaseTags(version) {
+		var gr *github.RepositoryRelease
+		err := s.withRateLimitRetry(ctx, log, func() (err error) {
+			gr, _, err = s.client.Repositories.GetReleaseByTag(ctx, repoSlug[0], repoSlug[1], tag)
+			return err
+		})
+		var respErr *github.ErrorResponse
+		switch {
+		case err == nil:
+			log.Debug("Found targeted release by tag.", zap.String("tag", tag))
+			return gr, nil
+		case errors.As(err, &respErr) && respErr.Response.StatusCode == http.StatusNotFound:
+			log.Debug("No release found for tag.", zap.String("tag", tag))
+		default:
+			return nil, fmt.Errorf("unable to request release %q for %q: %w", tag, s.GitHubSlug, err)
+		}
+	}
+
+	log.Debug("Falling back to scanning all releases for one matching the version.")
+	return s.scanReleases(ctx, log, repoSlug, version)
+}
+
+func (s *GitHub) scanReleases(ctx context.Context, log *zap.Logger, repoSlug []string, version string) (*github.RepositoryRelease, error) {
 	var gr *github.RepositoryRelease
+	err := s.forEachRelease(ctx, log, repoSlug, func(r *github.RepositoryRelease) bool {
+		if strings.TrimLeft(r.GetTagName(), "v") == strings.TrimLeft(version, "v") {
+			gr = r
+			log.Debug("Found targeted release.")
+		}
+		return gr == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if gr == nil {
+		log.Error("The targeted release was not found within the GitHub repository.")
+		return nil, ErrUnknownGitHubRelease
+	}
+	return gr, nil
+}
+
+// forEachRelease pages through the repository's releases from newest to oldest until visit returns false.
+func (s *GitHub) forEachRelease(ctx context.Context, log *zap.Logger, repoSlug []string, visit func(*github.RepositoryRelease) bool) error {
+	page := 1
 	for {
-		releases, resp, listErr := s.client.Repositories.ListReleases(ctx, repoSlug[0], repoSlug[1], &github.ListOptions{
-			Page:    page,
-			PerPage: 50,
+		var (
+			releases []*github.RepositoryRelease
+			resp     *github.Response
+		)
+		listErr := s.withRateLimitRetry(ctx, log, func() (err error) {
+			releases, resp, err = s.client.Repositories.ListReleases(ctx, repoSlug[0], repoSlug[1], &github.ListOptions{
+				Page:    page,
+				PerPage: 50,
+			})
+			return err
 		})
 		if listErr != nil {
-			return nil, fmt.Errorf("unable to request releases page %d for %q: %w", page, s.GitHubSlug, listErr)
+			return fmt.Errorf("unable to request releases page %d for %q: %w", page, s.GitHubSlug, listErr)
 		} else if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("failed to list releases page %d for %q: %s: %w", page, s.GitHubSlug, resp.Status, ErrGitHubAPIError)
+			return fmt.Errorf("failed to list releases page %d for %q: %s: %w", page, s.GitHubSlug, resp.Status, ErrGitHubAPIError)
 		}
 		log.Debug("Retrieved GitHub releases.", zap.Int("release-count", len(releases)))
 		for _, r := range releases {
-			if strings.TrimLeft(r.GetTagName(), "v") == strings.TrimLeft(version, "v") {
-				gr = r
-				log.Debug("Found targeted release.")
-				break
+			if !visit(r) {
+				return nil
 			}
 		}
-		if gr != nil || resp.NextPage == 0 {
-			break
+		if resp.NextPage == 0 {
+			return nil
 		}
 		page = resp.NextPage
 	}
+}
 
-	if gr == nil {
-		log.Error("The targeted release was not found within the GitHub repository.")
-		return nil, ErrUnknownGitHubRelease
+// withRateLimitRetry performs a GitHub API call. When the call hits GitHub's secondary rate limit it is retried after
+// waiting for the duration requested by GitHub. Exceeding the primary rate limit is not retried as its reset may be up
+// to an hour away.
+func (s *GitHub) withRateLimitRetry(ctx context.Context, log *zap.Logger, call func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := call()
+
+		var (
+			abuseErr *github.AbuseRateLimitError
+			rateErr  *github.RateLimitError
+		)
+		switch {
+		case errors.As(err, &abuseErr) && attempt <= maxGitHubRateLimitRetries:
+			wait := defaultGitHubRetryAfter
+			if abuseErr.RetryAfter != nil {
+				wait = *abuseErr.RetryAfter
+			}
+			log.Warn("Hit GitHub's secondary rate limit. Waiting before retrying.", zap.Duration("retry-after", wait), zap.Int("attempt", attempt))
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+
+		case errors.As(err, &rateErr):
+			if s.authenticated {
+				log.Error("Exceeded GitHub's API rate limit.", zap.Time("reset", rateErr.Rate.Reset.Time))
+			} else {
+				log.Sugar().Errorf("Exceeded GitHub's API rate limit for unauthenticated requests. Set one of %s or configure 'github_token_env' for the source to authenticate.", strings.Join(gitHubTokenEnvs, " or "))
+			}
+			return err
+
+		default:
+			return err
+		}
 	}
-	return gr, nil
 }
@@ -1,9 +1,12 @@
 package backend
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
+	"strings"
 	"testing"
-	"time"
 
 	"github.com/google/go-github/v66/github"
 	"github.com/migueleliasweb/go-github-mock/src/mock"
@@ -24,21 +27,25 @@ func TestGitHub(t *testing.T) {
 		return &c
 	}
 
-	fakeGH := mock.NewMockedHTTPClient(
-		mock.WithRequestMatch(
-			mock.GetReposReleasesByOwnerByRepo,
-			[]github.RepositoryRelease{
+	releases := []github.RepositoryRelease{
+		{
+			Name:    strPtr("Best Release"),
+			TagName: strPtr("v1.2.3"),
+			Assets: []*github.ReleaseAsset{
 				{
-					Name:    strPtr("Best Release"),
-					TagName: strPtr("v1.2.3"),
-					Assets: []*github.ReleaseAsset{
-						{
-							ID:   strInt64(123456),
-							Name: strPtr("test-tool_v1.2.3_linux_x86_64"),
-						},
-					},
+					ID:                 strInt64(123456),
+					Name:               strPtr("test-tool_v1.2.3_linux_x86_64"),
+					BrowserDownloadURL: strPtr("https://github.com/foo/bar/releases/download/v1.2.3/test-tool_v1.2.3_linux_x86_64"),
 				},
 			},
+		},
+	}
+
+	fakeGH := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetReposReleasesByOwnerByRepo,
+			releases, // Fetch
+			releases, // Resolve
 		),
 		mock.WithRequestMatchHandler(
 			mock.GetReposReleasesAssetsByOwnerByRepoByAssetId,
@@ -49,19 +56,198 @@ func TestGitHub(t *testing.T) {
 	)
 
 	gh := &GitHub{
-		log:     zap.NewNop(),
-		timeout: 10 * time.Second,
-		client:  github.NewClient(fakeGH),
+		log:    zap.NewNop(),
+		client: github.NewClient(fakeGH),
 		GitHubConfig: GitHubConfig{
 			GitHubSlug:                 "foo/bar",
 			GitHubReleaseAssetTemplate: stdTestTemplate,
 		},
 	}
 
-	b, err := gh.Fetch(stdTestBinary)
+	b, err := gh.Fetch(context.Background(), stdTestBinary)
 	require.NoError(t, err)
-	assert.Equal(t, stdTestBinaryContent, b)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
 
-	err = gh.Store(stdTestBinary, stdTestBinaryContent)
+	u, err := gh.Resolve(context.Background(), stdTestBinary)
+	require.NoError(t, err)
+	assert.Equal(t, "https://github.com/foo/bar/releases/download/v1.2.3/test-tool_v1.2.3_linux_x86_64", u)
+
+	err = gh.Store(context.Background(), stdTestBinary, bytes.NewReader(stdTestBinaryContent))
 	require.Error(t, err)
 }
+
+func TestGitHubReleaseByTag(t *testing.T) {
+	t.Parallel()
+
+	var tagCalls int
+	fakeGH := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetReposReleasesTagsByOwnerByRepoByTag,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tagCalls++
+				if tagCalls == 1 {
+					// The first request hits the secondary rate limit and should be retried.
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = w.Write([]byte(`{"message": "You have exceeded a secondary rate limit.", "documentation_url": "https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-secondary-rate-limits"}`))
+					return
+				}
+				assert.True(t, strings.HasSuffix(r.URL.Path, "/tags/v1.2.3"), r.URL.Path)
+				_ = json.NewEncoder(w).Encode(github.RepositoryRelease{
+					TagName: github.String("v1.2.3"),
+					Assets: []*github.ReleaseAsset{{
+						ID:                 github.Int64(123456),
+						Name:               github.String("test-tool_v1.2.3_linux_x86_64"),
+						BrowserDownloadURL: github.String("https://github.com/foo/bar/releases/download/v1.2.3/test-tool_v1.2.3_linux_x86_64"),
+					}},
+				})
+			}),
+		),
+	)
+
+	gh := &GitHub{
+		log:    zap.NewNop(),
+		client: github.NewClient(fakeGH),
+		GitHubConfig: GitHubConfig{
+			GitHubSlug:                 "foo/bar",
+			GitHubReleaseAssetTemplate: stdTestTemplate,
+		},
+	}
+
+	// No mock is registered for listing releases so this only succeeds via the direct lookup by tag.
+	u, err := gh.Resolve(context.Background(), stdTestBinary)
+	require.NoError(t, err)
+	assert.Equal(t, "https://github.com/foo/bar/releases/download/v1.2.3/test-tool_v1.2.3_linux_x86_64", u)
+	assert.Equal(t, 2, tagCalls)
+}
+
+func TestGitHubFetchAsset(t *testing.T) {
+	t.Parallel()
+
+	fakeGH := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetReposReleasesTagsByOwnerByRepoByTag,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.True(t, strings.HasSuffix(r.URL.Path, "/repos/foo/bar/releases/tags/v1.2.3"), r.URL.Path)
+				_ = json.NewEncoder(w).Encode(github.RepositoryRelease{
+					TagName: github.String("v1.2.3"),
+					Assets: []*github.ReleaseAsset{
+						{ID: github.Int64(1), Name: github.String("test-tool_v1.2.3_linux_x86_64.sha256")},
+						{ID: github.Int64(2), Name: github.String("test-tool_v1.2.3_linux_x86_64")},
+					},
+				})
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposReleasesAssetsByOwnerByRepoByAssetId,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.True(t, strings.HasSuffix(r.URL.Path, "/repos/foo/bar/releases/assets/2"), r.URL.Path)
+				_, _ = w.Write(stdTestBinaryContent)
+			}),
+		),
+	)
+
+	// Authenticated downloads of browser download URLs go through the API.
+	gh := &GitHub{
+		log:           zap.NewNop(),
+		client:        github.NewClient(fakeGH),
+		authenticated: true,
+		GitHubConfig:  GitHubConfig{GitHubSlug: "foo/bar"},
+	}
+	rc, err := gh.FetchAsset(context.Background(), "https://github.com/foo/bar/releases/download/v1.2.3/test-tool_v1.2.3_linux_x86_64")
+	require.NoError(t, err)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, rc))
+
+	_, err = gh.FetchAsset(context.Background(), "https://github.com/foo/bar/releases/download/v1.2.3/test-tool_v1.2.3_darwin_x86_64")
+	require.ErrorIs(t, err, ErrUnknownGitHubReleaseAsset)
+
+	_, err = gh.FetchAsset(context.Background(), "https://example.com/test-tool_v1.2.3_linux_x86_64")
+	require.ErrorIs(t, err, ErrUnknownGitHubReleaseAsset)
+}
+
+func TestGitHubReleases(t *testing.T) {
+	t.Parallel()
+
+	fakeGH := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchPages(
+			mock.GetReposReleasesByOwnerByRepo,
+			[]github.RepositoryRelease{
+				{TagName: github.String("v2.0.0-rc1"), Prerelease: github.Bool(true)},
+				{TagName: github.String("v1.3.0"), Draft: github.Bool(true)},
+			},
+			[]github.RepositoryRelease{
+				{TagName: github.String("v1.2.3")},
+				{TagName: github.String("release-1.2.2")},
+			},
+		),
+	)
+
+	gh := &GitHub{
+		log:          zap.NewNop(),
+		client:       github.NewClient(fakeGH),
+		GitHubConfig: GitHubConfig{GitHubSlug: "foo/bar"},
+	}
+
+	releases, err := gh.Releases(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Release{
+		{Version: "2.0.0-rc1", Prerelease: true},
+		{Version: "1.2.3"},
+		{Version: "release-1.2.2"},
+	}, releases)
+}
+
+func TestGitHubLatestRelease(t *testing.T) {
+	t.Parallel()
+
+	fakeGH := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetReposReleasesLatestByOwnerByRepo,
+			github.RepositoryRelease{
+				TagName: github.String("v1.2.3"),
+				Assets: []*github.ReleaseAsset{
+					{Name: github.String("test-tool_v1.2.3_linux_x86_64")},
+					{Name: github.String("checksums.txt")},
+				},
+			},
+		),
+	)
+
+	gh := &GitHub{
+		log:          zap.NewNop(),
+		client:       github.NewClient(fakeGH),
+		GitHubConfig: GitHubConfig{GitHubSlug: "foo/bar"},
+	}
+
+	version, assets, err := gh.LatestRelease(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3", version)
+	assert.Equal(t, []string{"test-tool_v1.2.3_linux_x86_64", "checksums.txt"}, assets)
+}
+
+func TestGitHubToken(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("TOOLSHARE_TEST_GITHUB_TOKEN", "")
+
+	token, _ := gitHubToken(&GitHubConfig{})
+	assert.Empty(t, token)
+
+	t.Setenv("GH_TOKEN", "gh-token")
+	token, env := gitHubToken(&GitHubConfig{})
+	assert.Equal(t, "gh-token", token)
+	assert.Equal(t, "GH_TOKEN", env)
+
+	t.Setenv("GITHUB_TOKEN", "github-token")
+	token, _ = gitHubToken(&GitHubConfig{})
+	assert.Equal(t, "github-token", token)
+
+	// A source-specific token takes precedence but only if it is set.
+	token, _ = gitHubToken(&GitHubConfig{GitHubTokenEnv: "TOOLSHARE_TEST_GITHUB_TOKEN"})
+	assert.Equal(t, "github-token", token)
+
+	t.Setenv("TOOLSHARE_TEST_GITHUB_TOKEN", "source-token")
+	token, env = gitHubToken(&GitHubConfig{GitHubTokenEnv: "TOOLSHARE_TEST_GITHUB_TOKEN"})
+	assert.Equal(t, "source-token", token)
+	assert.Equal(t, "TOOLSHARE_TEST_GITHUB_TOKEN", env)
+}
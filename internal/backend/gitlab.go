@@ -0,0 +1,195 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/logger"
+)
+
+var (
+	ErrUnknownGitLabRelease      = errors.New("gitlab release does not exist")
+	ErrUnknownGitLabReleaseAsset = errors.New("gitlab release does not contain asset")
+)
+
+type GitLabConfig struct {
+	CommonConfig
+
+	GitLabProject              string `json:"gitlab_project"`
+	GitLabReleaseAssetTemplate string `json:"gitlab_release_asset_template"`
+	GitLabBaseURL              string `json:"gitlab_base_url"`
+	GitLabTokenEnv             string `json:"gitlab_token_env"`
+}
+
+func (c GitLabConfig) String() string {
+	return fmt.Sprintf("%s/%s", c.baseURL(), c.GitLabProject)
+}
+
+func (c GitLabConfig) baseURL() string {
+	if c.GitLabBaseURL == "" {
+		return defaultGitLabBaseURL
+	}
+	return c.GitLabBaseURL
+}
+
+const (
+	defaultGitLabBaseURL = "https://gitlab.com"
+	gitLabAPIPrefix      = "/api/v4"
+)
+
+// Environment variable from which a GitLab token is read when a source does not specify its own environment variable
+// or when that variable is not set.
+var gitLabTokenEnvs = []string{"GITLAB_TOKEN"}
+
+// GitLab fetches tools from the release assets of a GitLab project. Release assets on GitLab are links that can either
+// point to files uploaded to the project, to its package registry or to arbitrary external locations.
+type GitLab struct {
+	log *zap.Logger
+	api *forgeAPI
+
+	GitLabConfig
+}
+
+type gitLabRelease struct {
+	TagName         string `json:"tag_name"`
+	UpcomingRelease bool   `json:"upcoming_release"`
+	Assets          struct {
+		Links []gitLabReleaseLink `json:"links"`
+	} `json:"assets"`
+}
+
+type gitLabReleaseLink struct {
+	Name           string `json:"name"`
+	URL            string `json:"url"`
+	DirectAssetURL string `json:"direct_asset_url"`
+}
+
+func NewGitLab(logBuilder logger.Builder, c *GitLabConfig) *GitLab {
+	log := logBuilder.Domain(logger.GitLabDomain).With(zap.Stringer("gitlab-project", c))
+
+	tokenEnvs := gitLabTokenEnvs
+	if c.GitLabTokenEnv != "" {
+		tokenEnvs = append([]string{c.GitLabTokenEnv}, tokenEnvs...)
+	}
+	api, err := newForgeAPI(log, c.baseURL(), tokenEnvs, "Authorization", "Bearer ")
+	if err != nil {
+		log.Error("Failed to initialise new GitLab client.", zap.Error(err))
+		panic(err)
+	}
+
+	return &GitLab{
+		log:          log,
+		api:          api,
+		GitLabConfig: *c,
+	}
+}
+
+func (s *GitLab) Fetch(ctx context.Context, b config.Binary) (io.ReadCloser, error) {
+	log := s.log.With(zap.Stringer("tool", b))
+	l, err := s.getReleaseLink(ctx, log, b)
+	if err != nil {
+		return nil, err
+	}
+	u := l.downloadURL()
+	log = log.With(zap.String("release-asset", l.Name), zap.String("url", u))
+
+	log.Debug("Downloading the release asset.")
+	r, err := s.api.get(ctx, u)
+	if err != nil {
+		log.Error("Failed to download the release asset.", zap.Error(err))
+		return nil, fmt.Errorf("failed to download asset %q from release %q in project %q: %w", l.Name, b.Version, s.GitLabProject, err)
+	} else if r.StatusCode != http.StatusOK {
+		_ = r.Body.Close()
+		log.Error("Download of the release asset returned a non-200 code.", zap.Int("http-code", r.StatusCode))
+		return nil, ErrHTTPStatusCode
+	}
+	return s.extractFromArchive(log, r.Body, l.Name, b)
+}
+
+func (s *GitLab) Resolve(ctx context.Context, b config.Binary) (string, error) {
+	l, err := s.getReleaseLink(ctx, s.log.With(zap.Stringer("tool", b)), b)
+	if err != nil {
+		return "", err
+	}
+	return l.downloadURL(), nil
+}
+
+// FetchAsset downloads the release asset at the given URL. The forge's token, if any, is only sent along if the asset
+// is hosted by the forge itself.
+func (s *GitLab) FetchAsset(ctx context.Context, u string) (io.ReadCloser, error) {
+	return s.api.download(ctx, u)
+}
+
+func (s *GitLab) Store(_ context.Context, _ config.Binary, _ io.Reader) error {
+	s.log.Error("Cannot perform 'store' operations on a GitLab backend.")
+	return errFailed
+}
+
+// Releases lists the releases of the project from newest to oldest. Upcoming releases are reported as pre-releases.
+func (s *GitLab) Releases(ctx context.Context) ([]Release, error) {
+	listed, err := getAllPages[gitLabRelease](ctx, s.api, s.api.endpoint(gitLabAPIPrefix, "projects", s.GitLabProject, "releases"), "per_page")
+	if err != nil {
+		s.log.Error("Failed to list the project's releases.", zap.Error(err))
+		return nil, fmt.Errorf("unable to list releases for %q: %w", s.GitLabProject, err)
+	}
+
+	releases := make([]Release, 0, len(listed))
+	for _, r := range listed {
+		releases = append(releases, Release{Version: releaseVersion(r.TagName), Prerelease: r.UpcomingRelease})
+	}
+	return releases, nil
+}
+
+func (s *GitLab) getReleaseLink(ctx context.Context, log *zap.Logger, b config.Binary) (*gitLabReleaseLink, error) {
+	var release *gitLabRelease
+	for _, tag := range releaseTags(b.Version) {
+		var r gitLabRelease
+		found, err := s.api.getJSON(ctx, s.api.endpoint(gitLabAPIPrefix, "projects", s.GitLabProject, "releases", tag), &r)
+		if err != nil {
+			log.Error("Failed to retrieve release.", zap.String("tag", tag), zap.Error(err))
+			return nil, fmt.Errorf("unable to request release %q for %q: %w", tag, s.GitLabProject, err)
+		} else if found {
+			log.Debug("Found targeted release by tag.", zap.String("tag", tag))
+			release = &r
+			break
+		}
+		log.Debug("No release found for tag.", zap.String("tag", tag))
+	}
+	if release == nil {
+		log.Error("The targeted release was not found within the GitLab project.")
+		return nil, ErrUnknownGitLabRelease
+	}
+
+	pattern, err := s.namePattern(b, s.GitLabReleaseAssetTemplate)
+	if err != nil {
+		log.Error("Invalid release asset template.", zap.Error(err))
+		return nil, err
+	}
+	log = log.With(zap.Stringer("release-asset", pattern))
+
+	names := make([]string, 0, len(release.Assets.Links))
+	for _, l := range release.Assets.Links {
+		names = append(names, l.Name)
+	}
+	idx, err := pattern.selectMatch(names)
+	if err != nil {
+		log.Error("No single release asset matches within the release.", zap.Error(err))
+		return nil, releaseAssetError(ErrUnknownGitLabReleaseAsset, err)
+	}
+	log.Debug("Found targeted release asset.", zap.String("asset", names[idx]))
+	return &release.Assets.Links[idx], nil
+}
+
+// downloadURL prefers the permanent direct asset URL that GitLab provides for links over the link's target itself.
+func (l *gitLabReleaseLink) downloadURL() string {
+	if l.DirectAssetURL != "" {
+		return l.DirectAssetURL
+	}
+	return l.URL
+}
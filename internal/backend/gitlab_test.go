@@ -0,0 +1,96 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/logger"
+)
+
+func TestGitLab(t *testing.T) {
+	t.Setenv("TOOLSHARE_TEST_GITLAB_TOKEN", "secret")
+
+	// Assets that are linked from a release but hosted elsewhere must never receive the GitLab token.
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		_, _ = w.Write(stdTestBinaryContent)
+	}))
+	t.Cleanup(external.Close)
+
+	var gitlab *httptest.Server
+	gitlab = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/group%2Fsub%2Fproject/releases/1.2.3":
+			// The release is tagged without a 'v' prefix to exercise the fallback on the alternative tag.
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"tag_name": "1.2.3",
+				"assets": map[string]interface{}{
+					"links": []map[string]string{
+						{
+							"name":             "test-tool_v1.2.3_linux_x86_64",
+							"url":              gitlab.URL + "/group/sub/project/-/package_files/1/download",
+							"direct_asset_url": gitlab.URL + "/group/sub/project/-/releases/1.2.3/downloads/test-tool_v1.2.3_linux_x86_64",
+						},
+						{
+							"name": "test-tool_v1.2.3_darwin_arm64",
+							"url":  external.URL + "/test-tool_v1.2.3_darwin_arm64",
+						},
+					},
+				},
+			})
+		case "/group/sub/project/-/releases/1.2.3/downloads/test-tool_v1.2.3_linux_x86_64":
+			_, _ = w.Write(stdTestBinaryContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(gitlab.Close)
+
+	gl := NewGitLab(logger.NewTestBuilder(), &GitLabConfig{
+		GitLabProject:              "group/sub/project",
+		GitLabReleaseAssetTemplate: stdTestTemplate,
+		GitLabBaseURL:              gitlab.URL,
+		GitLabTokenEnv:             "TOOLSHARE_TEST_GITLAB_TOKEN",
+	})
+
+	b, err := gl.Fetch(context.Background(), stdTestBinary)
+	require.NoError(t, err)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
+
+	u, err := gl.Resolve(context.Background(), stdTestBinary)
+	require.NoError(t, err)
+	assert.Equal(t, gitlab.URL+"/group/sub/project/-/releases/1.2.3/downloads/test-tool_v1.2.3_linux_x86_64", u)
+
+	externalBinary := stdTestBinary
+	externalBinary.Platform = config.PlatformDarwin
+	externalBinary.Arch = config.ArchARM64
+	b, err = gl.Fetch(context.Background(), externalBinary)
+	require.NoError(t, err)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
+
+	missingAsset := stdTestBinary
+	missingAsset.Platform = config.PlatformWindows
+	_, err = gl.Fetch(context.Background(), missingAsset)
+	require.ErrorIs(t, err, ErrUnknownGitLabReleaseAsset)
+
+	missingRelease := stdTestBinary
+	missingRelease.Version = "v2.0.0"
+	_, err = gl.Resolve(context.Background(), missingRelease)
+	require.ErrorIs(t, err, ErrUnknownGitLabRelease)
+
+	err = gl.Store(context.Background(), stdTestBinary, bytes.NewReader(stdTestBinaryContent))
+	require.Error(t, err)
+}
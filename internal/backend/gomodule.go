@@ -0,0 +1,229 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/logger"
+)
+
+var (
+	ErrGoBuildFailed       = errors.New("failed to build go module")
+	ErrNoGoToolchain       = errors.New("no go toolchain available")
+	ErrUnsupportedGoTarget = errors.New("unsupported platform or architecture")
+)
+
+// GoModuleConfig configures a source that builds a tool from a Go module at the requested version.
+type GoModuleConfig struct {
+	CommonConfig
+
+	GoModulePath    string `json:"go_module_path"`
+	GoModulePackage string `json:"go_module_package"`
+	GoModuleProxy   string `json:"go_module_proxy"`
+}
+
+func (c GoModuleConfig) String() string {
+	return "go://" + c.importPath()
+}
+
+// importPath returns the import path of the package to build, which is relative to the module's root.
+func (c GoModuleConfig) importPath() string {
+	return path.Join(c.GoModulePath, c.GoModulePackage)
+}
+
+// GoToolchain returns the path to the 'go' binary with which modules are built.
+type GoToolchain func(ctx context.Context) (string, error)
+
+// GoModule builds tools by downloading a module from a Go module proxy and cross-compiling the targeted package for the
+// requested platform and architecture. Builds are performed in a throw-away GOPATH and never touch the user's own Go
+// setup.
+type GoModule struct {
+	log       *zap.Logger
+	toolchain GoToolchain
+
+	GoModuleConfig
+}
+
+func NewGoModule(logBuilder logger.Builder, c *GoModuleConfig) *GoModule {
+	return &GoModule{
+		log:            logBuilder.Domain(logger.GoModuleDomain).With(zap.Stringer("go-module", c)),
+		GoModuleConfig: *c,
+	}
+}
+
+// SetToolchain configures the toolchain with which modules are built. Without a toolchain fetching binaries fails.
+func (s *GoModule) SetToolchain(toolchain GoToolchain) {
+	s.toolchain = toolchain
+}
+
+func (s *GoModule) Fetch(ctx context.Context, b config.Binary) (io.ReadCloser, error) {
+	log := s.log.With(zap.Stringer("tool", b))
+
+	goos, goarch, err := goTarget(b)
+	if err != nil {
+		log.Error("Go does not support the requested platform or architecture.", zap.Error(err))
+		return nil, err
+	}
+
+	if s.toolchain == nil {
+		log.Error("No go toolchain is available to build the module with.")
+		return nil, ErrNoGoToolchain
+	}
+	goBin, err := s.toolchain(ctx)
+	if err != nil {
+		log.Error("Failed to obtain the go toolchain.", zap.Error(err))
+		return nil, fmt.Errorf("%w: %w", ErrNoGoToolchain, err)
+	}
+	log = log.With(zap.String("go-binary", goBin))
+
+	dir, err := SpoolDir()
+	if err != nil {
+		log.Error("Failed to create directory for the temporary GOPATH.", zap.Error(err))
+		return nil, err
+	}
+	gopath, err := os.MkdirTemp(dir, config.DriverName+"-gopath-*")
+	if err != nil {
+		log.Error("Failed to create a temporary GOPATH.", zap.Error(err))
+		return nil, err
+	}
+	cleanup := tempDir(gopath)
+
+	target := s.importPath() + "@" + goModuleVersion(b.Version)
+	log = log.With(zap.String("target", target))
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, goBin, "install", "-trimpath", target)
+	cmd.Dir = gopath
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.Env = append(os.Environ(),
+		"CGO_ENABLED=0",
+		"GOARCH="+goarch,
+		"GOBIN=",
+		"GOENV=off",
+		"GOFLAGS=-modcacherw",
+		"GOMODCACHE="+filepath.Join(gopath, "pkg", "mod"),
+		"GOOS="+goos,
+		"GOPATH="+gopath,
+		"GOTOOLCHAIN=local",
+	)
+	if s.GoModuleProxy != "" {
+		cmd.Env = append(cmd.Env, "GOPROXY="+s.GoModuleProxy)
+	}
+
+	log.Debug("Building the module.")
+	if err = cmd.Run(); err != nil {
+		_ = cleanup.Close()
+		log.Error("Failed to build the module.", zap.Error(err), zap.String("output", output.String()))
+		return nil, fmt.Errorf("%w: %s: %w\n%s", ErrGoBuildFailed, target, err, output.String())
+	}
+
+	binPath, err := findBuiltBinary(filepath.Join(gopath, "bin"))
+	if err != nil {
+		_ = cleanup.Close()
+		log.Error("Failed to find the built binary.", zap.Error(err))
+		return nil, err
+	}
+	fd, err := os.Open(binPath)
+	if err != nil {
+		_ = cleanup.Close()
+		log.Error("Failed to open the built binary.", zap.Error(err))
+		return nil, err
+	}
+	log.Debug("Built the module.", zap.String("binary", binPath))
+	return &readCloser{Reader: fd, closers: []io.Closer{fd, cleanup}}, nil
+}
+
+// Resolve returns a location of the form 'go://<module>@<version>[/<package>]'.
+func (s *GoModule) Resolve(_ context.Context, b config.Binary) (string, error) {
+	location := "go://" + s.GoModulePath + "@" + goModuleVersion(b.Version)
+	if pkg := strings.Trim(path.Clean("/"+s.GoModulePackage), "/"); pkg != "" {
+		location += "/" + pkg
+	}
+	return location, nil
+}
+
+func (s *GoModule) Store(_ context.Context, _ config.Binary, _ io.Reader) error {
+	s.log.Error("Cannot perform 'store' operations on a Go module backend.")
+	return errFailed
+}
+
+// ParseGoModuleLocation is the inverse of the locations returned by GoModule.Resolve. It returns the module path,
+// version and package of the location.
+func ParseGoModuleLocation(location string) (string, string, string) {
+	module, rest, _ := strings.Cut(strings.TrimPrefix(location, "go://"), "@")
+	version, pkg, _ := strings.Cut(rest, "/")
+	return module, version, pkg
+}
+
+// Go module versions always carry a 'v' prefix which tool versions commonly omit.
+func goModuleVersion(version string) string {
+	if strings.HasPrefix(version, "v") {
+		return version
+	}
+	return "v" + version
+}
+
+func goTarget(b config.Binary) (string, string, error) {
+	var goos, goarch string
+	switch b.Platform {
+	case config.PlatformDarwin, config.PlatformLinux, config.PlatformWindows:
+		goos = string(b.Platform)
+	default:
+		return "", "", fmt.Errorf("%w: platform %q", ErrUnsupportedGoTarget, b.Platform)
+	}
+	switch b.Arch {
+	case config.ArchARM32:
+		goarch = "arm"
+	case config.ArchARM64:
+		goarch = "arm64"
+	case config.ArchX64:
+		goarch = "amd64"
+	case config.ArchX86:
+		goarch = "386"
+	default:
+		return "", "", fmt.Errorf("%w: architecture %q", ErrUnsupportedGoTarget, b.Arch)
+	}
+	return goos, goarch, nil
+}
+
+// findBuiltBinary returns the single binary installed by 'go install'. Cross-compiled binaries are installed into a
+// '<goos>_<goarch>' sub-directory.
+func findBuiltBinary(binDir string) (string, error) {
+	var binaries []string
+	err := filepath.WalkDir(binDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			binaries = append(binaries, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(binaries) != 1 {
+		return "", fmt.Errorf("%w: expected a single binary to be installed but found %d", ErrGoBuildFailed, len(binaries))
+	}
+	return binaries[0], nil
+}
+
+// tempDir is a temporary directory that is removed, with all its content, when closed.
+type tempDir string
+
+func (d tempDir) Close() error {
+	return os.RemoveAll(string(d))
+}
@@ -0,0 +1,97 @@
+package backend
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/logger"
+)
+
+func TestGoModule(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("No go toolchain available.")
+	}
+	t.Setenv("GONOSUMDB", "example.com")
+
+	proxy := writeGoModuleProxy(t, "example.com/hello", "v1.0.0", map[string]string{
+		"go.mod":              "module example.com/hello\n\ngo 1.21\n",
+		"cmd/hello/main.go":   "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Print(\"hello\") }\n",
+		"internal/lib/lib.go": "package lib\n",
+	})
+
+	gm := NewGoModule(logger.NewTestBuilder(), &GoModuleConfig{
+		GoModulePath:    "example.com/hello",
+		GoModulePackage: "cmd/hello",
+		GoModuleProxy:   "file://" + filepath.ToSlash(proxy),
+	})
+	native := config.Binary{Tool: "hello", Version: "1.0.0", Platform: config.CurrentPlatform(), Arch: config.CurrentArch()}
+
+	_, err = gm.Fetch(context.Background(), native)
+	require.ErrorIs(t, err, ErrNoGoToolchain)
+
+	gm.SetToolchain(func(context.Context) (string, error) { return goBin, nil })
+
+	u, err := gm.Resolve(context.Background(), native)
+	require.NoError(t, err)
+	assert.Equal(t, "go://example.com/hello@v1.0.0/cmd/hello", u)
+
+	rc, err := gm.Fetch(context.Background(), native)
+	require.NoError(t, err)
+	binPath := filepath.Join(t.TempDir(), "hello"+(&CommonConfig{}).exe(native))
+	require.NoError(t, os.WriteFile(binPath, readContent(t, rc), 0o755))
+	out, err := exec.Command(binPath).Output()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(out))
+
+	// Cross-compiled binaries are installed in a different location than native ones.
+	cross := native
+	cross.Platform, cross.Arch = config.PlatformWindows, config.ArchARM64
+	if native.Platform == config.PlatformWindows {
+		cross.Platform = config.PlatformLinux
+	}
+	rc, err = gm.Fetch(context.Background(), cross)
+	require.NoError(t, err)
+	assert.NotEmpty(t, readContent(t, rc))
+
+	missing := native
+	missing.Version = "v2.0.0"
+	_, err = gm.Fetch(context.Background(), missing)
+	require.ErrorIs(t, err, ErrGoBuildFailed)
+}
+
+// writeGoModuleProxy lays out a single module version in a directory that can be used as a 'file://' GOPROXY.
+func writeGoModuleProxy(t *testing.T, module string, version string, files map[string]string) string {
+	t.Helper()
+
+	proxy := t.TempDir()
+	dir := filepath.Join(proxy, filepath.FromSlash(module), "@v")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "list"), []byte(version+"\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, version+".info"), []byte(`{"Version":"`+version+`","Time":"2024-01-01T00:00:00Z"}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, version+".mod"), []byte(files["go.mod"]), 0o644))
+
+	fd, err := os.Create(filepath.Join(dir, version+".zip"))
+	require.NoError(t, err)
+	defer fd.Close()
+
+	zw := zip.NewWriter(fd)
+	for name, content := range files {
+		w, err := zw.Create(module + "@" + version + "/" + name)
+		require.NoError(t, err)
+		_, err = io.WriteString(w, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return proxy
+}
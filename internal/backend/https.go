@@ -1,14 +1,15 @@
 package backend
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
-	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/httpauth"
 	"github.com/Helcaraxan/toolshare/internal/logger"
 )
 
@@ -20,7 +21,8 @@ var (
 type HTTPSConfig struct {
 	CommonConfig
 
-	HTTPSURLTemplate string `json:"https_url_template"`
+	HTTPSURLTemplate string           `json:"https_url_template"`
+	HTTPSAuth        *httpauth.Config `json:"https_auth"`
 }
 
 func (c HTTPSConfig) String() string {
@@ -28,8 +30,8 @@ func (c HTTPSConfig) String() string {
 }
 
 type HTTPS struct {
-	log     *zap.Logger
-	timeout time.Duration
+	log    *zap.Logger
+	client *http.Client
 
 	HTTPSConfig
 }
@@ -37,32 +39,95 @@ type HTTPS struct {
 func NewHTTPS(logBuilder logger.Builder, c *HTTPSConfig) *HTTPS {
 	return &HTTPS{
 		log:         logBuilder.Domain(logger.HTTPSDomain),
-		timeout:     time.Minute,
+		client:      c.HTTPSAuth.Client(),
 		HTTPSConfig: *c,
 	}
 }
 
-func (s *HTTPS) Fetch(b config.Binary) ([]byte, error) {
+func (s *HTTPS) Fetch(ctx context.Context, b config.Binary) (io.ReadCloser, error) {
 	u := s.instantiateTemplate(b, s.HTTPSURLTemplate)
 	log := s.log.With(zap.Stringer("tool", b), zap.String("url", u))
 
-	r, err := http.Get(u)
+	r, err := s.do(ctx, log, http.MethodGet, u, nil)
 	if err != nil {
 		log.Error("Failed to download tool source URL.", zap.Error(err))
 		return nil, err
 	} else if r.StatusCode != http.StatusOK {
+		_ = r.Body.Close()
 		log.Error("Download of tool source URL returned a non-200 code.", zap.Int("http-code", r.StatusCode))
 		return nil, ErrHTTPStatusCode
 	}
-	raw, err := io.ReadAll(r.Body)
+	return s.extractFromArchive(log, r.Body, u, b)
+}
+
+func (s *HTTPS) Resolve(_ context.Context, b config.Binary) (string, error) {
+	return s.instantiateTemplate(b, s.HTTPSURLTemplate), nil
+}
+
+func (s *HTTPS) Store(ctx context.Context, b config.Binary, content io.Reader) error {
+	u := s.instantiateTemplate(b, s.HTTPSURLTemplate)
+	log := s.log.With(zap.Stringer("tool", b), zap.String("url", u))
+
+	r, err := s.do(ctx, log, http.MethodHead, u, nil)
 	if err != nil {
-		log.Error("Failed to read full file from remote URL.", zap.Error(err))
-		return nil, err
+		log.Error("Failed to check if a binary already exists.", zap.Error(err))
+		return err
+	}
+	_ = r.Body.Close()
+	switch r.StatusCode {
+	case http.StatusOK:
+		log.Error("Can not store a binary as one already exists.")
+		return ErrAlreadyExists
+	case http.StatusNotFound:
+	default:
+		log.Error("Check for an existing binary returned an unexpected code.", zap.Int("http-code", r.StatusCode))
+		return ErrHTTPStatusCode
+	}
+
+	// Uploads require the length of the content to be known, as S3-compatible servers reject chunked uploads, and need to
+	// be replayable to follow redirects. Rather than buffering the binary in memory we spool it to disk.
+	spool, size, err := spoolToTempFile(content)
+	if err != nil {
+		log.Error("Failed to spool binary to a temporary file before upload.", zap.Error(err))
+		return err
+	}
+	defer spool.Close()
+
+	r, err = s.do(ctx, log, http.MethodPut, u, io.NewSectionReader(spool, 0, size))
+	if err != nil {
+		log.Error("Failed to upload binary.", zap.Error(err))
+		return err
+	}
+	_ = r.Body.Close()
+	switch r.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		log.Debug("Finished uploading the binary.")
+		return nil
+	default:
+		log.Error("Upload of binary returned an unexpected code.", zap.Int("http-code", r.StatusCode))
+		return ErrHTTPStatusCode
 	}
-	return s.extractFromArchive(log, raw, u, b)
 }
 
-func (s *HTTPS) Store(b config.Binary, content []byte) error {
-	// We deliberately do not support storage through HTTP as we do not yet provide a HTTP authentication mechanism.
-	return ErrUnsupported
+func (s *HTTPS) do(ctx context.Context, log *zap.Logger, method string, u string, body *io.SectionReader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		log.Error("Failed to create request for URL.", zap.Error(err))
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = body.Size()
+		req.GetBody = func() (io.ReadCloser, error) {
+			if body.Size() == 0 {
+				return http.NoBody, nil
+			}
+			return io.NopCloser(io.NewSectionReader(body, 0, body.Size())), nil
+		}
+		req.Body, _ = req.GetBody()
+	}
+	if err = s.HTTPSAuth.Apply(req); err != nil {
+		log.Error("Failed to authenticate request.", zap.Error(err))
+		return nil, err
+	}
+	return s.client.Do(req)
 }
@@ -1,6 +1,8 @@
 package backend
 
 import (
+	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -9,18 +11,22 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/Helcaraxan/toolshare/internal/httpauth"
 	"github.com/Helcaraxan/toolshare/internal/logger"
 )
 
 func TestHTTPS(t *testing.T) {
-	t.Skip() // Skipping until we've implemented the actual HTTP backend.
-
-	t.Parallel()
+	t.Setenv("TOOLSHARE_TEST_HTTPS_TOKEN", "secret")
 
 	serverStorage := map[string][]byte{}
 	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
 		switch r.Method {
-		case http.MethodGet:
+		case http.MethodGet, http.MethodHead:
 			b, ok := serverStorage[r.URL.Path]
 			if !ok {
 				w.WriteHeader(http.StatusNotFound)
@@ -34,20 +40,79 @@ func TestHTTPS(t *testing.T) {
 				w.WriteHeader(http.StatusInternalServerError)
 			} else {
 				serverStorage[r.URL.Path] = b
+				w.WriteHeader(http.StatusCreated)
 			}
 		}
 	}))
 	t.Cleanup(testServer.Close)
 
-	https := NewHTTPS(logger.NewTestBuilder(), &HTTPSConfig{HTTPSURLTemplate: testServer.URL + "/" + stdTestTemplate})
+	https := NewHTTPS(logger.NewTestBuilder(), &HTTPSConfig{
+		HTTPSURLTemplate: testServer.URL + "/" + stdTestTemplate,
+		HTTPSAuth:        &httpauth.Config{BearerTokenEnv: "TOOLSHARE_TEST_HTTPS_TOKEN"},
+	})
 
-	_, err := https.Fetch(stdTestBinary)
+	_, err := https.Fetch(context.Background(), stdTestBinary)
 	require.Error(t, err)
 
-	err = https.Store(stdTestBinary, stdTestBinaryContent)
+	err = https.Store(context.Background(), stdTestBinary, bytes.NewReader(stdTestBinaryContent))
+	require.NoError(t, err)
+
+	err = https.Store(context.Background(), stdTestBinary, bytes.NewReader(stdTestBinaryContent))
+	require.ErrorIs(t, err, ErrAlreadyExists)
+
+	b, err := https.Fetch(context.Background(), stdTestBinary)
+	require.NoError(t, err)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
+
+	unauthenticated := NewHTTPS(logger.NewTestBuilder(), &HTTPSConfig{HTTPSURLTemplate: testServer.URL + "/" + stdTestTemplate})
+	_, err = unauthenticated.Fetch(context.Background(), stdTestBinary)
+	require.ErrorIs(t, err, ErrHTTPStatusCode)
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	t.Setenv("TOOLSHARE_TEST_HTTPS_API_KEY", "secret")
+
+	var leakedKey bool
+	var uploaded []byte
+	var uploadLength int64
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leakedKey = leakedKey || r.Header.Get("X-Api-Key") != ""
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write(stdTestBinaryContent)
+		case http.MethodPut:
+			uploadLength = r.ContentLength
+			uploaded, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	t.Cleanup(target.Close)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("X-Api-Key") != "secret":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			http.Redirect(w, r, target.URL+r.URL.Path, http.StatusTemporaryRedirect)
+		}
+	}))
+	t.Cleanup(testServer.Close)
+
+	https := NewHTTPS(logger.NewTestBuilder(), &HTTPSConfig{
+		HTTPSURLTemplate: testServer.URL + "/" + stdTestTemplate,
+		HTTPSAuth:        &httpauth.Config{Headers: map[string]string{"X-Api-Key": "TOOLSHARE_TEST_HTTPS_API_KEY"}},
+	})
+
+	b, err := https.Fetch(context.Background(), stdTestBinary)
 	require.NoError(t, err)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
 
-	b, err := https.Fetch(stdTestBinary)
+	err = https.Store(context.Background(), stdTestBinary, bytes.NewReader(stdTestBinaryContent))
 	require.NoError(t, err)
-	assert.Equal(t, stdTestBinaryContent, b)
+	assert.Equal(t, stdTestBinaryContent, uploaded)
+	assert.Equal(t, int64(len(stdTestBinaryContent)), uploadLength)
+
+	assert.False(t, leakedKey, "custom header was sent to the host redirected to")
 }
@@ -0,0 +1,325 @@
+package backend
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+)
+
+// Projects name their release assets in many different ways. A template for them is inferred from the name of the asset
+// that targets a given binary by locating the binary's version, platform and architecture within it. The names of the
+// assets that target other platforms and architectures then determine the template mappings.
+
+// platformAliases and archAliases list the lowercase names under which platforms and architectures commonly appear in
+// the names of release assets.
+var (
+	platformAliases = map[config.Platform][]string{
+		config.PlatformDarwin:  {"darwin", "macos", "osx", "mac"},
+		config.PlatformLinux:   {"linux"},
+		config.PlatformWindows: {"windows", "win64", "win32", "win"},
+	}
+	archAliases = map[config.Arch][]string{
+		config.ArchARM32: {"arm32", "armv7", "armv7l", "armhf", "armv6", "arm"},
+		config.ArchARM64: {"arm64", "aarch64"},
+		config.ArchX86:   {"x86", "386", "i386", "i686", "32bit"},
+		config.ArchX64:   {"x86_64", "amd64", "x64", "64bit"},
+	}
+)
+
+// ignoredAssetSuffixes are those of assets that accompany binaries, such as checksums and signatures, or that package
+// them for system package managers.
+var ignoredAssetSuffixes = []string{
+	".apk", ".asc", ".b3", ".deb", ".dmg", ".intoto.jsonl", ".json", ".md5", ".msi", ".pem", ".pkg", ".rpm", ".sbom",
+	".sha1", ".sha256", ".sha256sum", ".sha512", ".sig", ".spdx", ".txt",
+}
+
+type span struct {
+	start int
+	end   int
+}
+
+func (s span) overlaps(o span) bool {
+	return s.start < o.end && o.start < s.end
+}
+
+// InferReleaseAssetTemplate returns a template matching the release asset that provides the given binary amongst the
+// names of a release's assets, together with the archive path template and the template mappings that go with it.
+// Mappings are only inferred for the platforms and architectures whose assets follow the same naming scheme.
+func InferReleaseAssetTemplate(b config.Binary, assets []string) (string, CommonConfig, error) {
+	var candidates, matches []string
+	for _, a := range assets {
+		if ignoredAsset(a) {
+			continue
+		}
+		candidates = append(candidates, a)
+
+		_, hasPlatform := findToken(a, platformAliases[b.Platform])
+		_, hasArch := findToken(a, archAliases[b.Arch])
+		if hasPlatform && hasArch {
+			matches = append(matches, a)
+		}
+	}
+	if len(matches) == 0 {
+		return "", CommonConfig{}, fmt.Errorf("no release asset targets %s/%s: %w: %s", b.Platform, b.Arch, ErrNoMatch, listCandidates(assets))
+	}
+	sortAssets(candidates)
+	sortAssets(matches)
+	asset := matches[0]
+
+	var c CommonConfig
+	arch, _ := findToken(asset, archAliases[b.Arch])
+	versions := versionSpans(asset, b.Version)
+	platform := platformSegment(asset, b, arch, versions, candidates)
+
+	if name := asset[platform.start:platform.end]; name != string(b.Platform) {
+		c.Mappings.setPlatform(b.Platform, name)
+	}
+	for p, name := range variantNames(asset, platform, candidates, platformAliases) {
+		if p != string(b.Platform) && name != p {
+			c.Mappings.setPlatform(config.Platform(p), name)
+		}
+	}
+	if name := asset[arch.start:arch.end]; name != string(b.Arch) {
+		c.Mappings.setArch(b.Arch, name)
+	}
+	for a, name := range variantNames(asset, arch, candidates, archAliases) {
+		if a != string(b.Arch) && name != a {
+			c.Mappings.setArch(config.Arch(a), name)
+		}
+	}
+
+	replacements := map[span]string{platform: "{platform}", arch: "{arch}"}
+	for _, v := range versions {
+		if !v.overlaps(platform) && !v.overlaps(arch) {
+			replacements[v] = "{version}"
+		}
+	}
+	if b.Platform == config.PlatformWindows && strings.HasSuffix(strings.ToLower(asset), ".exe") {
+		replacements[span{len(asset) - len(".exe"), len(asset)}] = "{exe}"
+	}
+
+	if detectFormat(asset).unarchiver != nil {
+		c.ArchivePathTemplate = "**/{tool}{exe}"
+	}
+	return replaceSpans(asset, replacements), c, nil
+}
+
+// platformSegment returns the span of the asset's name that designates its platform. It is widened beyond the platform's
+// name if assets for other platforms differ in more than just that, as is the case for target triples such as
+// 'x86_64-unknown-linux-gnu' and 'x86_64-apple-darwin'.
+func platformSegment(asset string, b config.Binary, arch span, versions []span, candidates []string) span {
+	segment, _ := findToken(asset, platformAliases[b.Platform])
+	widened := segment
+
+	for _, p := range slices.Sorted(maps.Keys(platformAliases)) {
+		if p == b.Platform {
+			continue
+		}
+		aliases := platformAliases[p]
+		for _, other := range candidates {
+			if _, ok := findToken(other, aliases); !ok || !strings.Contains(other, asset[arch.start:arch.end]) {
+				continue
+			}
+			own, theirs := differingSegments(asset, other)
+			if _, ok := findToken(other[theirs.start:theirs.end], aliases); !ok {
+				continue
+			}
+			if own.start > segment.start || own.end < segment.end || own.overlaps(arch) {
+				continue
+			}
+			if overlapsAny(own, versions) {
+				continue
+			}
+			if widened == segment || own.end-own.start < widened.end-widened.start {
+				widened = own
+			}
+		}
+	}
+	return widened
+}
+
+// variantNames returns the names that replace the given span of the asset's name in those of other candidates, indexed
+// by the platform or architecture whose aliases they contain.
+func variantNames[K ~string](asset string, s span, candidates []string, aliases map[K][]string) map[string]string {
+	prefix, suffix := asset[:s.start], asset[s.end:]
+
+	names := map[string]string{}
+	for _, other := range candidates {
+		if len(other) <= len(prefix)+len(suffix) || !strings.HasPrefix(other, prefix) || !strings.HasSuffix(other, suffix) {
+			continue
+		}
+		name := other[len(prefix) : len(other)-len(suffix)]
+		for k, a := range aliases {
+			if _, ok := findToken(name, a); ok {
+				if _, seen := names[string(k)]; !seen {
+					names[string(k)] = name
+				}
+			}
+		}
+	}
+	return names
+}
+
+// differingSegments returns the spans of a and b that remain once their longest common prefix and suffix, delimited by
+// separators, have been removed.
+func differingSegments(a string, b string) (span, span) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for prefix > 0 && isAlphanumeric(a[prefix-1]) {
+		prefix--
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for suffix > 0 && isAlphanumeric(a[len(a)-suffix]) {
+		suffix--
+	}
+	return span{prefix, len(a) - suffix}, span{prefix, len(b) - suffix}
+}
+
+// findToken returns the span of the first occurrence of any of the aliases in the name, ignoring case. Occurrences need
+// to be delimited by separators, i.e. characters other than letters and digits. A '_64' or '-64' following an occurrence
+// is not considered to be a separator so that 'x86' does not match 'x86_64'.
+func findToken(name string, aliases []string) (span, bool) {
+	lower := strings.ToLower(name)
+
+	var (
+		found bool
+		first span
+	)
+	for _, alias := range aliases {
+		for offset := 0; offset < len(lower); {
+			idx := strings.Index(lower[offset:], alias)
+			if idx < 0 {
+				break
+			}
+			s := span{offset + idx, offset + idx + len(alias)}
+			offset = s.start + 1
+
+			if s.start > 0 && isAlphanumeric(lower[s.start-1]) {
+				continue
+			}
+			if rest := lower[s.end:]; rest != "" && (isAlphanumeric(rest[0]) || strings.HasPrefix(rest, "_64") || strings.HasPrefix(rest, "-64")) {
+				continue
+			}
+			if !found || s.start < first.start {
+				found, first = true, s
+			}
+		}
+	}
+	return first, found
+}
+
+// versionSpans returns the spans of all occurrences of the version in the name.
+func versionSpans(name string, version string) []span {
+	var spans []span
+	for offset := 0; version != "" && offset < len(name); {
+		idx := strings.Index(name[offset:], version)
+		if idx < 0 {
+			break
+		}
+		spans = append(spans, span{offset + idx, offset + idx + len(version)})
+		offset += idx + len(version)
+	}
+	return spans
+}
+
+func overlapsAny(s span, spans []span) bool {
+	for _, o := range spans {
+		if s.overlaps(o) {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceSpans replaces non-overlapping spans of the name.
+func replaceSpans(name string, replacements map[span]string) string {
+	spans := make([]span, 0, len(replacements))
+	for s := range replacements {
+		spans = append(spans, s)
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var (
+		sb   strings.Builder
+		last int
+	)
+	for _, s := range spans {
+		sb.WriteString(name[last:s.start])
+		sb.WriteString(replacements[s])
+		last = s.end
+	}
+	sb.WriteString(name[last:])
+	return sb.String()
+}
+
+// sortAssets orders assets by how directly they provide a binary: plain binaries come before archives which come before
+// compressed binaries. Shorter names come first otherwise, as longer ones tend to denote special-purpose variants.
+func sortAssets(assets []string) {
+	rank := func(name string) int {
+		switch f := detectFormat(name); {
+		case f.unarchiver != nil:
+			return 1
+		case f.compression != "":
+			return 2
+		default:
+			return 0
+		}
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		if ri, rj := rank(assets[i]), rank(assets[j]); ri != rj {
+			return ri < rj
+		}
+		if len(assets[i]) != len(assets[j]) {
+			return len(assets[i]) < len(assets[j])
+		}
+		return assets[i] < assets[j]
+	})
+}
+
+func ignoredAsset(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range ignoredAssetSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isAlphanumeric(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func (m *TemplateMappings) setPlatform(p config.Platform, name string) {
+	switch p {
+	case config.PlatformDarwin:
+		m.Darwin = &name
+	case config.PlatformLinux:
+		m.Linux = &name
+	case config.PlatformWindows:
+		m.Windows = &name
+	}
+}
+
+func (m *TemplateMappings) setArch(a config.Arch, name string) {
+	switch a {
+	case config.ArchARM32:
+		m.ARM32 = &name
+	case config.ArchARM64:
+		m.ARM64 = &name
+	case config.ArchX86:
+		m.X86 = &name
+	case config.ArchX64:
+		m.X8664 = &name
+	}
+}
@@ -0,0 +1,99 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+)
+
+func TestInferReleaseAssetTemplate(t *testing.T) {
+	t.Parallel()
+
+	strPtr := func(s string) *string { return &s }
+
+	testcases := map[string]struct {
+		binary           config.Binary
+		assets           []string
+		expectedTemplate string
+		expectedConfig   CommonConfig
+	}{
+		"Archives": {
+			binary: config.Binary{Tool: "gh", Version: "2.63.0", Platform: config.PlatformLinux, Arch: config.ArchX64},
+			assets: []string{
+				"gh_2.63.0_checksums.txt",
+				"gh_2.63.0_linux_386.tar.gz",
+				"gh_2.63.0_linux_amd64.deb",
+				"gh_2.63.0_linux_amd64.rpm",
+				"gh_2.63.0_linux_amd64.tar.gz",
+				"gh_2.63.0_linux_arm64.tar.gz",
+				"gh_2.63.0_linux_armv6.tar.gz",
+				"gh_2.63.0_macOS_amd64.zip",
+				"gh_2.63.0_windows_amd64.msi",
+				"gh_2.63.0_windows_amd64.zip",
+			},
+			expectedTemplate: "gh_{version}_{platform}_{arch}.tar.gz",
+			expectedConfig: CommonConfig{
+				ArchivePathTemplate: "**/{tool}{exe}",
+				Mappings:            TemplateMappings{ARM32: strPtr("armv6"), X86: strPtr("386"), X8664: strPtr("amd64")},
+			},
+		},
+		"TargetTriples": {
+			binary: config.Binary{Tool: "rg", Version: "14.1.1", Platform: config.PlatformDarwin, Arch: config.ArchARM64},
+			assets: []string{
+				"ripgrep-14.1.1-aarch64-apple-darwin.tar.gz",
+				"ripgrep-14.1.1-aarch64-apple-darwin.tar.gz.sha256",
+				"ripgrep-14.1.1-aarch64-unknown-linux-gnu.tar.gz",
+				"ripgrep-14.1.1-x86_64-apple-darwin.tar.gz",
+				"ripgrep-14.1.1-x86_64-pc-windows-msvc.zip",
+				"ripgrep-14.1.1-x86_64-unknown-linux-musl.tar.gz",
+				"ripgrep_14.1.1-1_amd64.deb",
+			},
+			expectedTemplate: "ripgrep-{version}-{arch}-{platform}.tar.gz",
+			expectedConfig: CommonConfig{
+				ArchivePathTemplate: "**/{tool}{exe}",
+				Mappings:            TemplateMappings{Darwin: strPtr("apple-darwin"), Linux: strPtr("unknown-linux-gnu"), ARM64: strPtr("aarch64")},
+			},
+		},
+		"Binaries": {
+			binary: config.Binary{Tool: "jq", Version: "1.7.1", Platform: config.PlatformLinux, Arch: config.ArchX64},
+			assets: []string{
+				"jq-linux-amd64",
+				"jq-linux-arm64",
+				"jq-linux-i386",
+				"jq-macos-amd64",
+				"jq-windows-amd64.exe",
+				"sha256sum.txt",
+			},
+			expectedTemplate: "jq-{platform}-{arch}",
+			expectedConfig: CommonConfig{
+				Mappings: TemplateMappings{Darwin: strPtr("macos"), X86: strPtr("i386"), X8664: strPtr("amd64")},
+			},
+		},
+		"WindowsExecutable": {
+			binary:           config.Binary{Tool: "jq", Version: "1.7.1", Platform: config.PlatformWindows, Arch: config.ArchX64},
+			assets:           []string{"jq-linux-amd64", "jq-windows-amd64.exe"},
+			expectedTemplate: "jq-{platform}-{arch}{exe}",
+			expectedConfig:   CommonConfig{Mappings: TemplateMappings{X8664: strPtr("amd64")}},
+		},
+	}
+
+	for name, testcase := range testcases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			template, c, err := InferReleaseAssetTemplate(testcase.binary, testcase.assets)
+			require.NoError(t, err)
+			assert.Equal(t, testcase.expectedTemplate, template)
+			assert.Equal(t, testcase.expectedConfig, c)
+		})
+	}
+
+	_, _, err := InferReleaseAssetTemplate(
+		config.Binary{Tool: "jq", Version: "1.7.1", Platform: config.PlatformDarwin, Arch: config.ArchARM32},
+		[]string{"jq-linux-amd64", "jq-macos-arm64"},
+	)
+	require.ErrorIs(t, err, ErrNoMatch)
+}
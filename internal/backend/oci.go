@@ -0,0 +1,238 @@
+package backend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/logger"
+)
+
+var ErrInvalidOCIArtifact = errors.New("oci artifact does not contain exactly one layer")
+
+// OCIConfig configures a storage in an OCI registry. Each binary is stored as an artifact with a single layer
+// containing the binary. By default the tool is the repository and '{version}-{platform}-{arch}' is the tag.
+type OCIConfig struct {
+	CommonConfig
+
+	OCIRegistry           string `json:"oci_registry"`
+	OCIRepositoryTemplate string `json:"oci_repository_template"`
+	OCITagTemplate        string `json:"oci_tag_template"`
+}
+
+func (c OCIConfig) String() string {
+	return fmt.Sprintf("oci://%s/%s:%s", c.OCIRegistry, c.repositoryTemplate(), c.tagTemplate())
+}
+
+func (c OCIConfig) repositoryTemplate() string {
+	if c.OCIRepositoryTemplate == "" {
+		return DefaultOCIRepositoryTemplate
+	}
+	return c.OCIRepositoryTemplate
+}
+
+func (c OCIConfig) tagTemplate() string {
+	if c.OCITagTemplate == "" {
+		return defaultOCITagTemplate
+	}
+	return c.OCITagTemplate
+}
+
+const (
+	DefaultOCIRepositoryTemplate = "{tool}"
+	defaultOCITagTemplate        = "{version}-{platform}-{arch}"
+
+	ociConfigMediaType types.MediaType = "application/vnd.toolshare.binary.config.v1+json"
+	ociLayerMediaType  types.MediaType = "application/vnd.toolshare.binary.layer.v1"
+
+	// The annotation used by ORAS and other OCI artifact tooling to record the file name of a layer.
+	ociTitleAnnotation = "org.opencontainers.image.title"
+)
+
+// Tags may only contain a limited set of characters. Any others, such as the '+' in semver build metadata, are
+// replaced with an underscore.
+var ociInvalidTagChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+type OCI struct {
+	log *zap.Logger
+
+	OCIConfig
+}
+
+func NewOCI(logBuilder logger.Builder, c *OCIConfig) *OCI {
+	return &OCI{
+		log:       logBuilder.Domain(logger.OCIDomain).With(zap.Stringer("oci-registry", c)),
+		OCIConfig: *c,
+	}
+}
+
+func (s *OCI) Fetch(ctx context.Context, b config.Binary) (io.ReadCloser, error) {
+	ref, err := s.reference(b)
+	if err != nil {
+		return nil, err
+	}
+	log := s.log.With(zap.Stringer("tool", b), zap.Stringer("reference", ref))
+
+	img, err := remote.Image(ref, s.options(ctx)...)
+	if err != nil {
+		log.Error("Failed to retrieve the artifact.", zap.Error(err))
+		return nil, err
+	}
+	m, err := img.Manifest()
+	if err != nil {
+		log.Error("Failed to read the artifact's manifest.", zap.Error(err))
+		return nil, err
+	} else if len(m.Layers) != 1 {
+		log.Error("The artifact does not contain exactly one layer.", zap.Int("layer-count", len(m.Layers)))
+		return nil, ErrInvalidOCIArtifact
+	}
+
+	layer, err := img.LayerByDigest(m.Layers[0].Digest)
+	if err != nil {
+		log.Error("Failed to retrieve the artifact's layer.", zap.Error(err))
+		return nil, err
+	}
+	rc, err := layer.Compressed()
+	if err != nil {
+		log.Error("Failed to download the artifact's layer.", zap.Error(err))
+		return nil, err
+	}
+
+	// Artifacts pushed by other tools may contain an archive, in which case the file name recorded for the layer is
+	// required to determine the archive's format.
+	srcPath := m.Layers[0].Annotations[ociTitleAnnotation]
+	if srcPath == "" {
+		srcPath = ref.String()
+	}
+	return s.extractFromArchive(log, rc, srcPath, b)
+}
+
+// Resolve returns the digest-pinned reference of the binary's artifact such that it is immune to tags being moved.
+func (s *OCI) Resolve(ctx context.Context, b config.Binary) (string, error) {
+	ref, err := s.reference(b)
+	if err != nil {
+		return "", err
+	}
+
+	desc, err := remote.Head(ref, s.options(ctx)...)
+	if err != nil {
+		s.log.Error("Failed to retrieve the artifact's digest.", zap.Stringer("reference", ref), zap.Error(err))
+		return "", err
+	}
+	return fmt.Sprintf("oci://%s@%s", ref.Context().Name(), desc.Digest), nil
+}
+
+func (s *OCI) Store(ctx context.Context, b config.Binary, content io.Reader) error {
+	ref, err := s.reference(b)
+	if err != nil {
+		return err
+	}
+	log := s.log.With(zap.Stringer("tool", b), zap.Stringer("reference", ref))
+
+	var terr *transport.Error
+	if _, err = remote.Head(ref, s.options(ctx)...); err == nil {
+		log.Error("Can not store a binary as one already exists.")
+		return ErrAlreadyExists
+	} else if !errors.As(err, &terr) || terr.StatusCode != http.StatusNotFound {
+		log.Error("Failed to check if a binary already exists.", zap.Error(err))
+		return err
+	}
+
+	// Layers need to be digested before they are uploaded. Rather than buffering the binary in memory we spool it to
+	// disk and digest it on the way.
+	digest := sha256.New()
+	spool, size, err := spoolToTempFile(io.TeeReader(content, digest))
+	if err != nil {
+		log.Error("Failed to spool binary to a temporary file before upload.", zap.Error(err))
+		return err
+	}
+	defer spool.Close()
+
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer: &spooledLayer{
+			content: io.NewSectionReader(spool, 0, size),
+			digest:  v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(digest.Sum(nil))},
+		},
+		Annotations: map[string]string{ociTitleAnnotation: s.instantiateTemplate(b, "{tool}{exe}")},
+	})
+	if err != nil {
+		log.Error("Failed to assemble the artifact.", zap.Error(err))
+		return err
+	}
+	img = mutate.ConfigMediaType(mutate.MediaType(img, types.OCIManifestSchema1), ociConfigMediaType)
+
+	if err = remote.Write(ref, img, s.options(ctx)...); err != nil {
+		log.Error("Failed to push the artifact.", zap.Error(err))
+		return err
+	}
+	log.Debug("Finished uploading the binary.")
+	return nil
+}
+
+// spooledLayer is an artifact layer whose content is read from disk. As with static layers the content is stored as-is,
+// so its digest and diff ID are identical.
+type spooledLayer struct {
+	content *io.SectionReader
+	digest  v1.Hash
+}
+
+func (l *spooledLayer) Digest() (v1.Hash, error) { return l.digest, nil }
+func (l *spooledLayer) DiffID() (v1.Hash, error) { return l.digest, nil }
+func (l *spooledLayer) Size() (int64, error)     { return l.content.Size(), nil }
+
+func (l *spooledLayer) MediaType() (types.MediaType, error) { return ociLayerMediaType, nil }
+
+func (l *spooledLayer) Compressed() (io.ReadCloser, error) {
+	return io.NopCloser(io.NewSectionReader(l.content, 0, l.content.Size())), nil
+}
+
+func (l *spooledLayer) Uncompressed() (io.ReadCloser, error) { return l.Compressed() }
+
+// reference returns the artifact reference for the given binary. A tag template that instantiates to a digest, as is
+// the case for binaries recorded in lock files, results in a digest reference.
+func (s *OCI) reference(b config.Binary) (name.Reference, error) {
+	repo := strings.ToLower(strings.Trim(s.instantiateTemplate(b, s.repositoryTemplate()), "/"))
+	repo = strings.TrimSuffix(s.OCIRegistry, "/") + "/" + repo
+
+	var (
+		ref name.Reference
+		err error
+	)
+	if tag := s.instantiateTemplate(b, s.tagTemplate()); strings.HasPrefix(tag, "sha256:") {
+		ref, err = name.NewDigest(repo + "@" + tag)
+	} else {
+		ref, err = name.NewTag(repo + ":" + ociInvalidTagChars.ReplaceAllString(tag, "_"))
+	}
+	if err != nil {
+		s.log.Error("Invalid artifact reference.", zap.Stringer("tool", b), zap.Error(err))
+		return nil, fmt.Errorf("invalid oci reference for %q: %w", b, err)
+	}
+	return ref, nil
+}
+
+// options configures registry requests to use the credentials from the user's Docker configuration, including any
+// configured credential helpers.
+func (s *OCI) options(ctx context.Context) []remote.Option {
+	return []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithUserAgent(config.DriverName),
+	}
+}
@@ -0,0 +1,108 @@
+package backend
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Helcaraxan/toolshare/internal/logger"
+)
+
+func TestOCI(t *testing.T) {
+	reg := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	t.Cleanup(testServer.Close)
+	host := strings.TrimPrefix(testServer.URL, "http://")
+
+	dockerConfig := t.TempDir()
+	auth := base64.StdEncoding.EncodeToString([]byte("user:secret"))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dockerConfig, "config.json"),
+		[]byte(fmt.Sprintf(`{"auths": {%q: {"auth": %q}}}`, host, auth)),
+		0o600,
+	))
+	t.Setenv("DOCKER_CONFIG", dockerConfig)
+
+	oci := NewOCI(logger.NewTestBuilder(), &OCIConfig{OCIRegistry: host, OCIRepositoryTemplate: "tools/{tool}"})
+
+	_, err := oci.Fetch(context.Background(), stdTestBinary)
+	require.Error(t, err)
+
+	err = oci.Store(context.Background(), stdTestBinary, bytes.NewReader(stdTestBinaryContent))
+	require.NoError(t, err)
+
+	err = oci.Store(context.Background(), stdTestBinary, bytes.NewReader(stdTestBinaryContent))
+	require.ErrorIs(t, err, ErrAlreadyExists)
+
+	b, err := oci.Fetch(context.Background(), stdTestBinary)
+	require.NoError(t, err)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
+
+	// The resolved reference is pinned to the artifact's digest.
+	ref, err := oci.Resolve(context.Background(), stdTestBinary)
+	require.NoError(t, err)
+	assert.Regexp(t, fmt.Sprintf("^oci://%s/tools/test-tool@sha256:[0-9a-f]{64}$", host), ref)
+
+	_, digest, _ := strings.Cut(ref, "@")
+	pinned := NewOCI(logger.NewTestBuilder(), &OCIConfig{OCIRegistry: host, OCIRepositoryTemplate: "tools/test-tool", OCITagTemplate: digest})
+	b, err = pinned.Fetch(context.Background(), stdTestBinary)
+	require.NoError(t, err)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
+
+	// Artifacts pushed by other tools may contain an archive that is identified by the layer's title annotation.
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "bin/test-tool", Size: int64(len(stdTestBinaryContent)), Mode: 0o755}))
+	_, err = tw.Write(stdTestBinaryContent)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(archive.Bytes(), "application/vnd.oci.image.layer.v1.tar"),
+		Annotations: map[string]string{ociTitleAnnotation: "test-tool.tar"},
+	})
+	require.NoError(t, err)
+	tag, err := name.NewTag(host + "/archived/test-tool:v1.2.3")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(tag, img, oci.options(context.Background())...))
+
+	archived := NewOCI(logger.NewTestBuilder(), &OCIConfig{
+		CommonConfig:          CommonConfig{ArchivePathTemplate: "bin/{tool}"},
+		OCIRegistry:           host,
+		OCIRepositoryTemplate: "archived/{tool}",
+		OCITagTemplate:        "{version}",
+	})
+	b, err = archived.Fetch(context.Background(), stdTestBinary)
+	require.NoError(t, err)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
+
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	_, err = oci.Fetch(context.Background(), stdTestBinary)
+	require.Error(t, err)
+}
@@ -0,0 +1,187 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+)
+
+var (
+	ErrAmbiguousMatch  = errors.New("several candidates match")
+	ErrInvalidTemplate = errors.New("invalid glob pattern in template")
+	ErrNoMatch         = errors.New("no candidate matches")
+)
+
+// maxListedCandidates caps the number of candidates listed in errors, e.g. for archives with many files.
+const maxListedCandidates = 25
+
+// namePattern matches names, such as those of release assets or archive entries, against an instantiated template.
+// Templates may contain glob patterns which are applied after the substitution of template variables:
+//
+//   - '*' matches any sequence of characters except '/'.
+//   - '**' matches any sequence of characters including '/'. When followed by a '/' it also matches no directory at all.
+//   - '?' matches any single character except '/'.
+//   - '[...]' matches a character from the class, which is negated by a leading '!' or '^'.
+//
+// The values of template variables are always matched literally. A template without glob patterns only matches names
+// that are equal to the instantiated template.
+type namePattern struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func (c *CommonConfig) namePattern(b config.Binary, tmpl string) (namePattern, error) {
+	p := namePattern{pattern: c.instantiateTemplate(b, tmpl)}
+
+	var (
+		sb   strings.Builder
+		glob bool
+		vars = c.templateVariables(b)
+	)
+	sb.WriteString("^")
+	for idx := 0; idx < len(tmpl); {
+		if tmpl[idx] == '{' {
+			if v, n := matchTemplateVariable(tmpl[idx:], vars); n > 0 {
+				sb.WriteString(regexp.QuoteMeta(v))
+				idx += n
+				continue
+			}
+		}
+
+		switch rest := tmpl[idx:]; {
+		case strings.HasPrefix(rest, "**/"):
+			sb.WriteString("(?:.*/)?")
+			idx += 3
+		case strings.HasPrefix(rest, "**"):
+			sb.WriteString(".*")
+			idx += 2
+		case rest[0] == '*':
+			sb.WriteString("[^/]*")
+			idx++
+		case rest[0] == '?':
+			sb.WriteString("[^/]")
+			idx++
+		case rest[0] == '[' && globClassEnd(rest) > 0:
+			end := globClassEnd(rest)
+			class := rest[1:end]
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			idx += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(rest[:1]))
+			idx++
+			continue
+		}
+		glob = true
+	}
+	sb.WriteString("$")
+
+	if glob {
+		// Character classes are copied as-is and may hence be invalid, e.g. because of a reversed range such as '[z-a]'.
+		re, err := regexp.Compile(sb.String())
+		if err != nil {
+			return namePattern{}, fmt.Errorf("%w %q: %w", ErrInvalidTemplate, tmpl, err)
+		}
+		p.re = re
+	}
+	return p, nil
+}
+
+// archivePathPattern returns the pattern for paths inside a fetched archive at which the given binary is found.
+func (c *CommonConfig) archivePathPattern(b config.Binary) (namePattern, error) {
+	return c.namePattern(b, path.Clean(c.ArchivePathTemplate))
+}
+
+func matchTemplateVariable(s string, vars []string) (string, int) {
+	for idx := 0; idx < len(vars); idx += 2 {
+		if strings.HasPrefix(s, vars[idx]) {
+			return vars[idx+1], len(vars[idx])
+		}
+	}
+	return "", 0
+}
+
+// globClassEnd returns the index of the ']' closing the character class at the start of s, or 0 if it is not closed. A
+// ']' directly following the opening bracket, or its negation, is part of the class.
+func globClassEnd(s string) int {
+	start := 1
+	if start < len(s) && (s[start] == '!' || s[start] == '^') {
+		start++
+	}
+	if start >= len(s) {
+		return 0
+	}
+	if idx := strings.IndexByte(s[start+1:], ']'); idx >= 0 {
+		return start + 1 + idx
+	}
+	return 0
+}
+
+func (p namePattern) String() string {
+	return p.pattern
+}
+
+func (p namePattern) isGlob() bool {
+	return p.re != nil
+}
+
+func (p namePattern) match(name string) bool {
+	if p.re == nil {
+		return name == p.pattern
+	}
+	return p.re.MatchString(name)
+}
+
+// selectMatch returns the index of the single name that matches the pattern.
+func (p namePattern) selectMatch(names []string) (int, error) {
+	match := -1
+	var matches []string
+	for idx, name := range names {
+		if p.match(name) {
+			match = idx
+			matches = append(matches, name)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return -1, p.noMatchError(names)
+	case 1:
+		return match, nil
+	default:
+		return -1, p.ambiguousMatchError(matches)
+	}
+}
+
+func (p namePattern) noMatchError(candidates []string) error {
+	return fmt.Errorf("%w %q among: %s", ErrNoMatch, p.pattern, listCandidates(candidates))
+}
+
+func (p namePattern) ambiguousMatchError(matches []string) error {
+	return fmt.Errorf("%w %q: %s", ErrAmbiguousMatch, p.pattern, listCandidates(matches))
+}
+
+func listCandidates(candidates []string) string {
+	if len(candidates) == 0 {
+		return "<none>"
+	}
+	if len(candidates) > maxListedCandidates {
+		return fmt.Sprintf("%s and %d more", strings.Join(candidates[:maxListedCandidates], ", "), len(candidates)-maxListedCandidates)
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// releaseAssetError qualifies the failure to match a single release asset. The absence of any match is reported with the
+// forge-specific error for unknown assets.
+func releaseAssetError(errUnknownAsset error, err error) error {
+	if errors.Is(err, ErrNoMatch) {
+		return fmt.Errorf("%w: %w", errUnknownAsset, err)
+	}
+	return err
+}
@@ -0,0 +1,108 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+)
+
+func TestNamePattern(t *testing.T) {
+	t.Parallel()
+
+	b := config.Binary{Tool: "test-tool", Version: "1.2.3+build.4", Platform: config.PlatformLinux, Arch: config.ArchX64}
+
+	testcases := map[string]struct {
+		template   string
+		glob       bool
+		matches    []string
+		mismatches []string
+	}{
+		"Exact": {
+			template:   "{tool}_{version}_{platform}",
+			matches:    []string{"test-tool_1.2.3+build.4_linux"},
+			mismatches: []string{"test-tool_1.2.3+build.4_linux.sha256", "test-tool_1x2x3+build.4_linux"},
+		},
+		"Star": {
+			template:   "{tool}_{version}_*_{platform}.tar.gz",
+			glob:       true,
+			matches:    []string{"test-tool_1.2.3+build.4_abc123_linux.tar.gz", "test-tool_1.2.3+build.4__linux.tar.gz"},
+			mismatches: []string{"test-tool_1.2.3+build.4_abc/123_linux.tar.gz", "test-tool_1.2.3+build.44_abc_linux.tar.gz"},
+		},
+		"DoubleStar": {
+			template:   "**/bin/{tool}",
+			glob:       true,
+			matches:    []string{"bin/test-tool", "a/bin/test-tool", "a/b/c/bin/test-tool"},
+			mismatches: []string{"abin/test-tool", "a/bin/test-tool.exe"},
+		},
+		"QuestionMark": {
+			template:   "{tool}-v?",
+			glob:       true,
+			matches:    []string{"test-tool-v1", "test-tool-v2"},
+			mismatches: []string{"test-tool-v10", "test-tool-v/"},
+		},
+		"Class": {
+			template:   "{tool}-[0-9][!a]",
+			glob:       true,
+			matches:    []string{"test-tool-1b"},
+			mismatches: []string{"test-tool-1a", "test-tool-xb"},
+		},
+		"UnclosedClass": {
+			template:   "{tool}-[",
+			matches:    []string{"test-tool-["},
+			mismatches: []string{"test-tool-a"},
+		},
+	}
+
+	for name := range testcases {
+		testcase := testcases[name]
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := (&CommonConfig{}).namePattern(b, testcase.template)
+			require.NoError(t, err)
+			assert.Equal(t, testcase.glob, p.isGlob())
+			for _, m := range testcase.matches {
+				assert.True(t, p.match(m), m)
+			}
+			for _, m := range testcase.mismatches {
+				assert.False(t, p.match(m), m)
+			}
+		})
+	}
+}
+
+func TestNamePatternSelectMatch(t *testing.T) {
+	t.Parallel()
+
+	p, err := (&CommonConfig{}).namePattern(stdTestBinary, "{tool}_*")
+	require.NoError(t, err)
+	candidates := []string{"other-tool_linux", "test-tool_linux", "test-tool_darwin"}
+
+	_, err = p.selectMatch(candidates)
+	require.ErrorIs(t, err, ErrAmbiguousMatch)
+	assert.ErrorContains(t, err, "test-tool_linux, test-tool_darwin")
+
+	idx, err := p.selectMatch(candidates[:2])
+	require.NoError(t, err)
+	assert.Equal(t, 1, idx)
+
+	_, err = p.selectMatch(candidates[:1])
+	require.ErrorIs(t, err, ErrNoMatch)
+	assert.ErrorContains(t, err, "other-tool_linux")
+}
+
+func TestNamePatternInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := (&CommonConfig{}).namePattern(stdTestBinary, "{tool}-[z-a]")
+	require.ErrorIs(t, err, ErrInvalidTemplate)
+
+	// Invalid archive path templates are reported as errors when fetching rather than crashing.
+	conf := &CommonConfig{ArchivePathTemplate: "bin/[z-a]{tool}"}
+	_, err = conf.extractFromArchive(zap.NewNop(), stream(writeTestTGZ(t, []testArchiveEntry{{name: "bin/test-tool", content: "tool"}})), "archive.tar.gz", stdTestBinary)
+	require.ErrorIs(t, err, ErrInvalidTemplate)
+}
@@ -1,7 +1,6 @@
 package backend
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -30,9 +29,8 @@ func (c S3Config) String() string {
 }
 
 type S3 struct {
-	log     *zap.Logger
-	timeout time.Duration
-	client  *s3.Client
+	log    *zap.Logger
+	client *s3.Client
 
 	S3Config
 }
@@ -49,22 +47,18 @@ func NewS3(logBuilder logger.Builder, c *S3Config) *S3 {
 
 	return &S3{
 		log:      log,
-		timeout:  time.Minute,
 		client:   s3.NewFromConfig(cfg),
 		S3Config: *c,
 	}
 }
 
-func (s *S3) Fetch(b config.Binary) ([]byte, error) {
+func (s *S3) Fetch(ctx context.Context, b config.Binary) (io.ReadCloser, error) {
 	bucketPath := s.instantiateTemplate(b, s.S3PathTemplate)
 	log := s.log.With(
 		zap.Stringer("tool", b),
 		zap.String("artefact-path", bucketPath),
 	)
 
-	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
-	defer cancel()
-
 	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s.S3Bucket),
 		Key:    aws.String(bucketPath),
@@ -78,27 +72,21 @@ func (s *S3) Fetch(b config.Binary) ([]byte, error) {
 		}
 		return nil, err
 	}
-	defer out.Body.Close()
+	s.log.Debug("Opened object from S3 for download.")
+	return s.extractFromArchive(log, out.Body, bucketPath, b)
+}
 
-	raw, err := io.ReadAll(out.Body)
-	if err != nil {
-		log.Error("Failed to download object content from S3.", zap.Error(err))
-		return nil, err
-	}
-	s.log.Debug("Finished downloading object from S3.")
-	return s.extractFromArchive(log, raw, bucketPath, b)
+func (s *S3) Resolve(_ context.Context, b config.Binary) (string, error) {
+	return fmt.Sprintf("s3://%s/%s", s.S3Bucket, s.instantiateTemplate(b, s.S3PathTemplate)), nil
 }
 
-func (s *S3) Store(b config.Binary, content []byte) error {
+func (s *S3) Store(ctx context.Context, b config.Binary, content io.Reader) error {
 	bucketPath := s.instantiateTemplate(b, s.S3PathTemplate)
 	log := s.log.With(
 		zap.Stringer("tool", b),
 		zap.String("artefact-path", bucketPath),
 	)
 
-	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
-	defer cancel()
-
 	_, err := s.client.GetObjectAttributes(ctx, &s3.GetObjectAttributesInput{
 		Bucket:           aws.String(s.S3Bucket),
 		Key:              aws.String(bucketPath),
@@ -106,7 +94,7 @@ func (s *S3) Store(b config.Binary, content []byte) error {
 	})
 	if err == nil {
 		log.Error("Can not store a binary as one already exists.")
-		return errFailed
+		return ErrAlreadyExists
 	}
 	var s3err *types.NoSuchKey
 	if !errors.As(err, &s3err) {
@@ -114,10 +102,22 @@ func (s *S3) Store(b config.Binary, content []byte) error {
 		return err
 	}
 
+	// Payload signing requires a seekable body. Rather than buffering the binary in memory we spool it to disk.
+	body, ok := content.(io.ReadSeeker)
+	if !ok {
+		spool, _, spoolErr := spoolToTempFile(content)
+		if spoolErr != nil {
+			log.Error("Failed to spool binary to a temporary file before upload.", zap.Error(spoolErr))
+			return spoolErr
+		}
+		defer spool.Close()
+		body = spool
+	}
+
 	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket: aws.String(s.S3Bucket),
 		Key:    aws.String(bucketPath),
-		Body:   bytes.NewReader(content),
+		Body:   body,
 	})
 	if err != nil {
 		log.Error("Failed to store binary as object in S3.", zap.Error(err))
@@ -1,12 +1,12 @@
 package backend
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"net/http"
 	"net/http/httptest"
 	"testing"
-	"time"
 
 	aws_config "github.com/aws/aws-sdk-go-v2/config"
 	s3_lib "github.com/aws/aws-sdk-go-v2/service/s3"
@@ -42,8 +42,7 @@ func TestS3(t *testing.T) {
 	require.NoError(t, err)
 
 	s3 := &S3{
-		log:     zap.NewNop(),
-		timeout: 10 * time.Second,
+		log: zap.NewNop(),
 		client: s3_lib.NewFromConfig(s3Config, func(o *s3_lib.Options) {
 			o.BaseEndpoint = &serv.URL
 			o.Credentials = nil
@@ -56,17 +55,17 @@ func TestS3(t *testing.T) {
 		},
 	}
 
-	b, err := s3.Fetch(stdTestBinary)
+	b, err := s3.Fetch(context.Background(), stdTestBinary)
 	require.Error(t, err)
 	assert.Nil(t, b)
 
-	err = s3.Store(stdTestBinary, stdTestBinaryContent)
+	err = s3.Store(context.Background(), stdTestBinary, bytes.NewReader(stdTestBinaryContent))
 	require.NoError(t, err)
 
-	err = s3.Store(stdTestBinary, stdTestBinaryContent)
-	require.Error(t, err)
+	err = s3.Store(context.Background(), stdTestBinary, bytes.NewReader(stdTestBinaryContent))
+	require.ErrorIs(t, err, ErrAlreadyExists)
 
-	b, err = s3.Fetch(stdTestBinary)
+	b, err = s3.Fetch(context.Background(), stdTestBinary)
 	require.NoError(t, err)
-	assert.Equal(t, stdTestBinaryContent, b)
+	assert.Equal(t, stdTestBinaryContent, readContent(t, b))
 }
@@ -12,6 +12,8 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/goccy/go-yaml"
 	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/httpauth"
 )
 
 const (
@@ -21,22 +23,67 @@ const (
 )
 
 var (
-	ErrAmbiguousBackend = errors.New("ambiguous backend configuration")
-	ErrUnknownFields    = errors.New("unknown fields present in cache configuration")
+	ErrAmbiguousBackend  = errors.New("ambiguous backend configuration")
+	ErrUnknownFields     = errors.New("unknown fields present in cache configuration")
+	ErrInvalidDenyPolicy = errors.New("invalid deny policy")
 )
 
 type Global struct {
-	ForcePinned    bool `json:"force_pinned"`
-	DisableSources bool `json:"disable_sources"`
+	ForcePinned    bool          `json:"force_pinned"`
+	DisableSources bool          `json:"disable_sources"`
+	Timeout        time.Duration `json:"timeout"`
+	// ReleasesTTL is the duration for which the releases listed by a tool's source are cached to resolve version
+	// constraints such as 'latest'.
+	ReleasesTTL time.Duration `json:"releases_ttl"`
 
 	RemoteCache *Cache `json:"remote_cache"`
 	State       *State `json:"state"`
 }
 
 type State struct {
-	Type            string        `json:"type"`
+	Type            StateType     `json:"type"`
 	Local           string        `json:"local"`
+	URL             string        `json:"url"`
+	Bucket          string        `json:"bucket"`
+	Prefix          string        `json:"prefix"`
+	TokenEnv        string        `json:"token_env"`
 	RefreshInterval time.Duration `json:"refresh_interval"`
+	OnDenied        DenyPolicy    `json:"on_denied"`
+}
+
+// StateType determines the backend in which a state is stored.
+type StateType string
+
+const (
+	StateTypeFileSystem StateType = "filesystem"
+	StateTypeGCS        StateType = "gcs"
+	StateTypeGit        StateType = "git"
+	StateTypeHTTP       StateType = "http"
+	StateTypeS3         StateType = "s3"
+)
+
+// DenyPolicy determines how the use of a tool version that is denied by the state is handled.
+type DenyPolicy string
+
+const (
+	DenyPolicyFail DenyPolicy = "fail"
+	DenyPolicyWarn DenyPolicy = "warn"
+)
+
+// UnmarshalYAML rejects unknown policies when the configuration is loaded rather than only once a denied version is
+// encountered.
+func (p *DenyPolicy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var policy string
+	if err := unmarshal(&policy); err != nil {
+		return err
+	}
+	switch DenyPolicy(policy) {
+	case DenyPolicyFail, DenyPolicyWarn, "":
+	default:
+		return fmt.Errorf("%w %q", ErrInvalidDenyPolicy, policy)
+	}
+	*p = DenyPolicy(policy)
+	return nil
 }
 
 type Cache struct {
@@ -46,11 +93,25 @@ type Cache struct {
 type cacheContent struct {
 	PathPrefix string `json:"path_prefix"`
 
-	GCSBucket string `json:"gcs_bucket"`
-	HTTPSHost string `json:"https_host"`
-	S3Bucket  string `json:"s3_bucket"`
+	GCSBucket   string `json:"gcs_bucket"`
+	HTTPSHost   string `json:"https_host"`
+	OCIRegistry string `json:"oci_registry"`
+	S3Bucket    string `json:"s3_bucket"`
+
+	HTTPSAuth *httpauth.Config `json:"https_auth"`
+
+	WriteThrough   bool               `json:"write_through"`
+	OnWriteFailure WriteFailurePolicy `json:"on_write_failure"`
 }
 
+// WriteFailurePolicy determines how a failure to write a binary through to the remote cache is handled.
+type WriteFailurePolicy string
+
+const (
+	WriteFailurePolicyFail WriteFailurePolicy = "fail"
+	WriteFailurePolicyWarn WriteFailurePolicy = "warn"
+)
+
 func (c *Cache) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err := unmarshal(&c.cacheContent); err != nil {
 		return err
@@ -59,14 +120,14 @@ func (c *Cache) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err := unmarshal(&all); err != nil {
 		return err
 	}
-	for _, k := range []string{"path_prefix", "gcs_bucket", "https_host", "s3_bucket"} {
+	for _, k := range []string{"path_prefix", "gcs_bucket", "https_host", "oci_registry", "s3_bucket", "https_auth", "write_through", "on_write_failure"} {
 		delete(all, k)
 	}
 	if len(all) > 0 {
 		return ErrUnknownFields
 	}
 	var hostCount int
-	for _, h := range []*string{&c.GCSBucket, &c.HTTPSHost, &c.S3Bucket} {
+	for _, h := range []*string{&c.GCSBucket, &c.HTTPSHost, &c.OCIRegistry, &c.S3Bucket} {
 		if h != nil && *h != "" {
 			hostCount++
 		}
@@ -143,6 +204,14 @@ func StorageDir() string {
 	return filepath.Join(UserDir(), "cache")
 }
 
+func StateDir() string {
+	return filepath.Join(UserDir(), "state")
+}
+
+func ReleasesDir() string {
+	return filepath.Join(UserDir(), "releases")
+}
+
 func SubscriptionDir() string {
 	return filepath.Join(UserDir(), "subscriptions")
 }
@@ -18,13 +18,17 @@ func TestConfigUnmarshal(t *testing.T) {
 		testFile    string
 		expectedErr bool
 	}{
-		"ValidFilesystemCache":  {testFile: "valid_filesystem_cache.yaml", expectedErr: false},
-		"ValidGCSCache":         {testFile: "valid_gcs_cache.yaml", expectedErr: false},
-		"ValidHTTPSCache":       {testFile: "valid_https_cache.yaml", expectedErr: false},
-		"ValidS3Cache":          {testFile: "valid_s3_cache.yaml", expectedErr: false},
-		"ValidLockedDownConfig": {testFile: "valid_locked_down_config.yaml", expectedErr: false},
-		"InvalidMixedCache":     {testFile: "invalid_mixed_cache.yaml", expectedErr: true},
-		"InvalidErroneousCache": {testFile: "invalid_unknown_cache.yaml", expectedErr: true},
+		"ValidFilesystemCache":   {testFile: "valid_filesystem_cache.yaml", expectedErr: false},
+		"ValidGCSCache":          {testFile: "valid_gcs_cache.yaml", expectedErr: false},
+		"ValidHTTPSCache":        {testFile: "valid_https_cache.yaml", expectedErr: false},
+		"ValidOCICache":          {testFile: "valid_oci_cache.yaml", expectedErr: false},
+		"ValidS3Cache":           {testFile: "valid_s3_cache.yaml", expectedErr: false},
+		"ValidWriteThroughCache": {testFile: "valid_write_through_cache.yaml", expectedErr: false},
+		"ValidLockedDownConfig":  {testFile: "valid_locked_down_config.yaml", expectedErr: false},
+		"ValidDenyPolicy":        {testFile: "valid_deny_policy.yaml", expectedErr: false},
+		"InvalidMixedCache":      {testFile: "invalid_mixed_cache.yaml", expectedErr: true},
+		"InvalidErroneousCache":  {testFile: "invalid_unknown_cache.yaml", expectedErr: true},
+		"InvalidDenyPolicy":      {testFile: "invalid_deny_policy.yaml", expectedErr: true},
 	}
 
 	for name := range unmarshalTestCases {
@@ -0,0 +1,131 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/backend"
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/environment"
+)
+
+func Add(cOpts *CommonOpts) *cobra.Command {
+	opts := &addOptions{
+		CommonOpts: cOpts,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "add <tool> --github=<owner/repo>",
+		Short: "Add a tool and its source to the nearest environment file.",
+		Long: fmt.Sprintf(`Add a source for the tool to the innermost environment file of the current directory or any of its
+parents, or to a new one in the current directory. Unless the tool is already pinned it is also
+pinned to the latest release of the source.
+
+The release asset template, the archive path template and the template mappings of the source are
+inferred from the names of the latest release's assets. The inferred source is validated by fetching
+the tool for the current platform and architecture before anything is written. Mappings for other
+platforms and architectures are only inferred when their assets follow the same naming scheme, so
+review the written source before running '%s lock' for other platforms.`, config.DriverName),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.tool = args[0]
+			ctx, cancel := opts.withDeadline(cmd.Context())
+			defer cancel()
+			return opts.add(ctx)
+		},
+	}
+
+	registerAddFlags(cmd, opts)
+
+	return cmd
+}
+
+func registerAddFlags(cmd *cobra.Command, opts *addOptions) {
+	cmd.Flags().StringVar(&opts.gitHubSlug, "github", "", "The GitHub repository, as '<owner>/<repo>', whose releases provide the tool.")
+	cmd.Flags().StringVar(&opts.gitHubBaseURL, "github-base-url", "", "The base URL of the GitHub Enterprise instance hosting the repository, if any.")
+	_ = cmd.MarkFlagRequired("github")
+}
+
+type addOptions struct {
+	*CommonOpts
+
+	tool          string
+	gitHubSlug    string
+	gitHubBaseURL string
+}
+
+func (o *addOptions) add(ctx context.Context) error {
+	log := o.Log.With(zap.String("tool-name", o.tool), zap.String("github-slug", o.gitHubSlug))
+
+	gh := backend.NewGitHub(o.LogBuilder, &backend.GitHubConfig{GitHubSlug: o.gitHubSlug, GitHubBaseURL: o.gitHubBaseURL})
+	latest, assets, err := gh.LatestRelease(ctx)
+	if err != nil {
+		return err
+	}
+	log = log.With(zap.String("version", latest))
+
+	binary := config.Binary{
+		Tool:     o.tool,
+		Version:  latest,
+		Platform: config.CurrentPlatform(),
+		Arch:     config.CurrentArch(),
+	}
+	template, common, err := backend.InferReleaseAssetTemplate(binary, assets)
+	if err != nil {
+		log.Error("Unable to infer a release asset template from the latest release.", zap.Error(err))
+		return err
+	}
+	source := &environment.Source{GitHubConfig: &backend.GitHubConfig{
+		CommonConfig:               common,
+		GitHubSlug:                 o.gitHubSlug,
+		GitHubReleaseAssetTemplate: template,
+		GitHubBaseURL:              o.gitHubBaseURL,
+	}}
+	log = log.With(zap.Stringer("source", source))
+
+	if err = o.validateSource(ctx, log, source, binary); err != nil {
+		return err
+	}
+
+	reg := o.Env[o.tool]
+	pin := (reg.Version == "" && reg.Constraint == nil) || reg.Recommended
+	envFile, err := editNearestFile(log, func(content []byte) ([]byte, error) {
+		edited, editErr := environment.AddSource(content, o.tool, source)
+		if editErr != nil || !pin {
+			return edited, editErr
+		}
+		return environment.AddPin(edited, o.tool, latest)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !pin {
+		log.Info("Tool is already pinned. Leaving its pin untouched.", zap.String("pinned-version", reg.Version), zap.Stringer("constraint", reg.Constraint))
+	}
+	log.Info("Successfully added tool.", zap.String("env-file", envFile))
+	return nil
+}
+
+// validateSource fetches the binary from the source to verify that the inferred templates select a single release asset
+// and, if needed, a single file within it.
+func (o *addOptions) validateSource(ctx context.Context, log *zap.Logger, source *environment.Source, binary config.Binary) error {
+	rc, err := backend.NewGitHub(o.LogBuilder, source.GitHubConfig).Fetch(ctx, binary)
+	if err != nil {
+		log.Error("Failed to fetch the tool with the inferred source.", zap.Error(err))
+		return err
+	}
+	defer rc.Close()
+
+	size, err := io.Copy(io.Discard, rc)
+	if err != nil {
+		log.Error("Failed to fetch the tool with the inferred source.", zap.Error(err))
+		return err
+	}
+	log.Debug("Validated the inferred source.", zap.Stringer("tool", binary), zap.Int64("size", size))
+	return nil
+}
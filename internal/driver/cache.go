@@ -0,0 +1,403 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryanuber/columnize"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/backend"
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/environment"
+	"github.com/Helcaraxan/toolshare/internal/flock"
+	"github.com/Helcaraxan/toolshare/internal/version"
+)
+
+// lastUsedSuffix is appended to the path of a cached binary to obtain that of the marker file whose modification time
+// records when the binary was last invoked.
+const lastUsedSuffix = ".last-used"
+
+func Cache(cOpts *CommonOpts) *cobra.Command {
+	opts := &cacheOptions{
+		CommonOpts: cOpts,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the local cache of tool binaries.",
+		Args:  cobra.NoArgs,
+	}
+
+	prune := &cobra.Command{
+		Use:   "prune [--max-age=<duration>] [--max-size=<size>] [--unreferenced=<dir,...>] [--dry-run]",
+		Short: "Evict tool binaries from the local cache.",
+		Long: fmt.Sprintf(`Evict tool binaries from the local cache that match any of the given criteria:
+
+- They have not been used for longer than the maximum age. A binary is used whenever it is invoked,
+  including via shims, or otherwise when it was downloaded.
+- Their version is not referenced by any environment file in the given directory trees, nor by the
+  user and system-level environments or the versions recommended by the state. Version constraints
+  reference the newest cached version that satisfies them.
+- They exceed the size budget of the cache, in which case the least recently used binaries are
+  evicted first. Sizes accept units such as 'MB' or 'GiB', e.g. '10GiB'.
+
+Evicted binaries are fetched again when they are next needed. Binaries that are being downloaded by
+a concurrent invocation of '%s' are not evicted.`, config.DriverName),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return opts.prune(cmd.Context())
+		},
+	}
+	registerPruneFlags(prune, opts)
+
+	cmd.AddCommand(prune)
+
+	return cmd
+}
+
+func registerPruneFlags(cmd *cobra.Command, opts *cacheOptions) {
+	cmd.Flags().DurationVar(&opts.maxAge, "max-age", 0, "Evict binaries that have not been used for longer than this duration, e.g. '720h'.")
+	cmd.Flags().StringVar(&opts.maxSize, "max-size", "", "Evict the least recently used binaries until the cache fits in this size, e.g. '10GiB'.")
+	cmd.Flags().StringSliceVar(&opts.roots, "unreferenced", nil, "Evict binaries that are not referenced by any environment in these directory trees.")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "List the binaries that would be evicted without evicting them.")
+}
+
+type cacheOptions struct {
+	*CommonOpts
+
+	maxAge  time.Duration
+	maxSize string
+	roots   []string
+	dryRun  bool
+}
+
+type cacheEntry struct {
+	binary   config.Binary
+	path     string
+	size     int64
+	lastUsed time.Time
+	reason   string
+}
+
+func (o *cacheOptions) prune(ctx context.Context) error {
+	if o.maxAge <= 0 && o.maxSize == "" && len(o.roots) == 0 {
+		o.Log.Error("No eviction criteria were specified. Use at least one of '--max-age', '--max-size' or '--unreferenced'.")
+		return ErrNoPruneCriteria
+	}
+
+	var maxSize int64
+	if o.maxSize != "" {
+		var err error
+		if maxSize, err = parseSize(o.maxSize); err != nil {
+			o.Log.Error("Invalid size budget.", zap.String("max-size", o.maxSize), zap.Error(err))
+			return err
+		}
+	}
+
+	entries, err := o.cachedBinaries()
+	if err != nil {
+		o.Log.Error("Failed to list the binaries in the local cache.", zap.Error(err))
+		return err
+	}
+
+	var referenced map[string]map[string]bool
+	if len(o.roots) > 0 {
+		if referenced, err = o.referencedVersions(entries); err != nil {
+			return err
+		}
+	}
+
+	evictions := o.selectEvictions(entries, referenced, maxSize)
+	if len(evictions) == 0 {
+		o.Log.Info("No binaries need to be evicted from the local cache.")
+		return nil
+	}
+
+	if !o.dryRun {
+		var errs []error
+		evicted := evictions[:0]
+		for _, e := range evictions {
+			ok, evictErr := o.evict(ctx, e)
+			if evictErr != nil {
+				errs = append(errs, evictErr)
+			} else if ok {
+				evicted = append(evicted, e)
+			}
+		}
+		evictions = evicted
+		if len(errs) > 0 {
+			defer o.Log.Error("Failed to evict some binaries from the local cache.")
+			err = fmt.Errorf("failed to evict some binaries: %w", errors.Join(errs...))
+		}
+	}
+
+	rows := []string{
+		"Tool | Version | Platform | Arch | Size | Last used | Reason",
+		"---- | ------- | -------- | ---- | ---- | --------- | ------",
+	}
+	var freed int64
+	for _, e := range evictions {
+		rows = append(rows, fmt.Sprintf("%s | %s | %s | %s | %s | %s | %s", e.binary.Tool, e.binary.Version, e.binary.Platform, e.binary.Arch, formatSize(e.size), e.lastUsed.Format(time.DateOnly), e.reason))
+		freed += e.size
+	}
+	fmt.Println(columnize.SimpleFormat(rows))
+
+	if o.dryRun {
+		o.Log.Info("Dry-run: no binaries were evicted from the local cache.", zap.Int("binaries", len(evictions)), zap.String("size", formatSize(freed)))
+	} else {
+		o.Log.Info("Evicted binaries from the local cache.", zap.Int("binaries", len(evictions)), zap.String("size", formatSize(freed)))
+	}
+	return err
+}
+
+// selectEvictions returns the entries to evict, each with the reason for its eviction. Binaries that are too old or not
+// referenced are evicted first. Only if the remaining ones exceed the size budget are the least recently used amongst
+// them evicted as well.
+func (o *cacheOptions) selectEvictions(entries []*cacheEntry, referenced map[string]map[string]bool, maxSize int64) []*cacheEntry {
+	var evictions, kept []*cacheEntry
+	for _, e := range entries {
+		switch {
+		case o.maxAge > 0 && time.Since(e.lastUsed) > o.maxAge:
+			e.reason = "unused for longer than " + o.maxAge.String()
+		case referenced != nil && !referenced[e.binary.Tool][e.binary.Version]:
+			e.reason = "not referenced by any environment"
+		default:
+			kept = append(kept, e)
+			continue
+		}
+		evictions = append(evictions, e)
+	}
+	if maxSize > 0 {
+		// The least recently used binaries are evicted first.
+		sort.SliceStable(kept, func(i, j int) bool { return kept[i].lastUsed.Before(kept[j].lastUsed) })
+		var total int64
+		for _, e := range kept {
+			total += e.size
+		}
+		for _, e := range kept {
+			if total <= maxSize {
+				break
+			}
+			e.reason = "exceeds the size budget of " + o.maxSize
+			evictions = append(evictions, e)
+			total -= e.size
+		}
+	}
+	return evictions
+}
+
+// cachedBinaries lists the binaries in the local cache together with their size and the time at which they were last
+// used.
+func (o *cacheOptions) cachedBinaries() ([]*cacheEntry, error) {
+	local := backend.NewFileSystem(o.LogBuilder, &backend.FileSystemConfig{FilePathTemplate: localCacheTemplate()})
+
+	// The directories of cached binaries are at the depth of the '{arch}' element of the cache's layout.
+	pattern := []string{config.StorageDir(), cacheURLTemplate[0]}
+	for range cacheURLTemplate[1 : len(cacheURLTemplate)-1] {
+		pattern = append(pattern, "*")
+	}
+	dirs, err := filepath.Glob(filepath.Join(pattern...))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*cacheEntry
+	for _, dir := range dirs {
+		elems := strings.Split(dir, string(filepath.Separator))
+		elems = elems[len(elems)-4:]
+		b := config.Binary{Tool: elems[0], Version: elems[1], Platform: config.Platform(elems[2]), Arch: config.Arch(elems[3])}
+
+		e := &cacheEntry{binary: b, path: local.StorePath(b)}
+		info, err := os.Stat(e.path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		e.lastUsed = info.ModTime()
+		if marker, err := os.Stat(e.path + lastUsedSuffix); err == nil {
+			e.lastUsed = marker.ModTime()
+		}
+		if e.size, err = diskUsage(e.path); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// referencedVersions returns the versions of each tool that are referenced by the environments in the directory trees
+// to consider, by the user and system-level environments and by the state. Version constraints reference the newest
+// cached version that satisfies them as that is what they resolve to without access to the tool's source.
+func (o *cacheOptions) referencedVersions(entries []*cacheEntry) (map[string]map[string]bool, error) {
+	envFiles, err := environment.FilesUnder(o.roots)
+	if err != nil {
+		o.Log.Error("Failed to search for environment files.", zap.Strings("roots", o.roots), zap.Error(err))
+		return nil, err
+	}
+	o.Log.Debug("Found environment files.", zap.Strings("env-files", envFiles))
+
+	pins, err := environment.ReadPins(envFiles)
+	if err != nil {
+		o.Log.Error("Failed to read the pins of environment files.", zap.Error(err))
+		return nil, err
+	}
+	if o.State != nil {
+		tools, stateErr := o.State.AvailableTools()
+		if stateErr != nil {
+			o.Log.Error("Unable to list the tools available in the state.", zap.Error(stateErr))
+			return nil, stateErr
+		}
+		for _, tool := range tools {
+			if recommended, _ := o.State.RecommendedVersion(tool); recommended != "" {
+				pins[tool] = append(pins[tool], recommended)
+			}
+		}
+	}
+
+	cached := map[string][]string{}
+	for _, e := range entries {
+		cached[e.binary.Tool] = append(cached[e.binary.Tool], e.binary.Version)
+	}
+
+	referenced := map[string]map[string]bool{}
+	for tool, toolPins := range pins {
+		referenced[tool] = map[string]bool{}
+		for _, pin := range toolPins {
+			if !version.IsConstraint(pin) {
+				referenced[tool][pin] = true
+				continue
+			}
+			c, err := version.ParseConstraint(pin)
+			if err != nil {
+				o.Log.Warn("Ignoring invalid version constraint.", zap.String("tool-name", tool), zap.String("constraint", pin), zap.Error(err))
+				continue
+			}
+			if v := c.Highest(cached[tool]); v != "" {
+				referenced[tool][v] = true
+			}
+		}
+	}
+	return referenced, nil
+}
+
+// evict removes a binary from the local cache while holding its download lock. It reports whether the binary was
+// evicted, which is not the case if it was being downloaded concurrently. Directories of the cache's layout are left in
+// place as concurrent downloads may be about to create their lock file in them.
+func (o *cacheOptions) evict(ctx context.Context, e *cacheEntry) (bool, error) {
+	log := o.Log.With(zap.Stringer("tool", e.binary), zap.String("cache-path", e.path))
+
+	ok, err := flock.AcquireFileLock(ctx, log, e.path)
+	if err != nil {
+		log.Error("Failed to acquire download lock.", zap.Error(err))
+		return false, err
+	} else if !ok {
+		log.Info("Binary was downloaded concurrently. Skipping its eviction.")
+		return false, nil
+	}
+	defer func() {
+		if err := flock.ReleaseFileLock(log, e.path); err != nil {
+			log.Warn("Failed to release download lock correctly.", zap.Error(err))
+		}
+	}()
+
+	if err = os.RemoveAll(e.path); err != nil {
+		log.Error("Failed to remove binary from local cache.", zap.Error(err))
+		return false, err
+	}
+	if err = os.Remove(e.path + lastUsedSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn("Failed to remove the last-use marker of the binary.", zap.Error(err))
+	}
+	log.Debug("Evicted binary from local cache.")
+	return true, nil
+}
+
+// markUsed records that a cached binary is being used by updating the modification time of its last-use marker.
+// Failing to do so is not fatal as it only affects the eviction of the binary from the cache.
+func markUsed(log *zap.Logger, path string) {
+	marker := path + lastUsedSuffix
+	now := time.Now()
+
+	err := os.Chtimes(marker, now, now)
+	if errors.Is(err, os.ErrNotExist) {
+		var fd *os.File
+		if fd, err = os.Create(marker); err == nil {
+			err = fd.Close()
+		}
+	}
+	if err != nil {
+		log.Debug("Failed to record the use of the binary.", zap.Error(err))
+	}
+}
+
+// diskUsage returns the total size of the file, or of the files in the directory, at the given path.
+func diskUsage(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// parseSize parses a size such as '500MB' or '10GiB' into a number of bytes.
+func parseSize(s string) (int64, error) {
+	trimmed := strings.TrimSpace(s)
+	idx := strings.IndexFunc(trimmed, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if idx < 0 {
+		idx = len(trimmed)
+	}
+
+	value, err := strconv.ParseFloat(trimmed[:idx], 64)
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(trimmed[idx:]))]
+	if err != nil || !ok || value < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidSize, s)
+	}
+	return int64(value * float64(unit)), nil
+}
+
+func formatSize(size int64) string {
+	const unit = 1 << 10
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,167 @@
+package driver
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/Helcaraxan/toolshare/internal/backend"
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/logger"
+)
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		size     string
+		expected int64
+		invalid  bool
+	}{
+		"Bytes":         {size: "512", expected: 512},
+		"ExplicitBytes": {size: "512B", expected: 512},
+		"Decimal":       {size: "1.5MB", expected: 1_500_000},
+		"Binary":        {size: "10GiB", expected: 10 << 30},
+		"CaseAndSpaces": {size: " 2 kib ", expected: 2 << 10},
+		"Negative":      {size: "-1GB", invalid: true},
+		"UnknownUnit":   {size: "10XB", invalid: true},
+		"NoValue":       {size: "GiB", invalid: true},
+		"Empty":         {size: "", invalid: true},
+	}
+
+	for name, testcase := range testcases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			size, err := parseSize(testcase.size)
+			if testcase.invalid {
+				require.ErrorIs(t, err, ErrInvalidSize)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testcase.expected, size)
+		})
+	}
+}
+
+func TestSelectEvictions(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	entry := func(tool string, version string, size int64, age time.Duration) *cacheEntry {
+		return &cacheEntry{binary: config.Binary{Tool: tool, Version: version}, size: size, lastUsed: now.Add(-age)}
+	}
+
+	testcases := map[string]struct {
+		opts       cacheOptions
+		referenced map[string]map[string]bool
+		maxSize    int64
+		expected   map[string]string // Evicted version to the start of the reason for its eviction.
+	}{
+		"MaxAge": {
+			opts:     cacheOptions{maxAge: 24 * time.Hour},
+			expected: map[string]string{"1.0.0": "unused for longer than"},
+		},
+		"Unreferenced": {
+			opts:       cacheOptions{},
+			referenced: map[string]map[string]bool{"foo": {"2.0.0": true, "3.0.0": true}},
+			expected:   map[string]string{"1.0.0": "not referenced", "4.0.0": "not referenced"},
+		},
+		"SizeBudgetEvictsLeastRecentlyUsed": {
+			opts:     cacheOptions{maxSize: "25"},
+			maxSize:  25,
+			expected: map[string]string{"1.0.0": "exceeds the size budget", "2.0.0": "exceeds the size budget"},
+		},
+		"AgeTakesPrecedenceOverReferences": {
+			opts:       cacheOptions{maxAge: 24 * time.Hour},
+			referenced: map[string]map[string]bool{"foo": {"1.0.0": true, "2.0.0": true, "3.0.0": true}},
+			expected:   map[string]string{"1.0.0": "unused for longer than", "4.0.0": "not referenced"},
+		},
+		"SizeBudgetOnlyAppliesToKeptBinaries": {
+			opts:     cacheOptions{maxAge: 24 * time.Hour, maxSize: "20"},
+			maxSize:  20,
+			expected: map[string]string{"1.0.0": "unused for longer than", "2.0.0": "exceeds the size budget"},
+		},
+	}
+
+	for name, testcase := range testcases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			entries := []*cacheEntry{
+				entry("foo", "3.0.0", 10, time.Hour),
+				entry("foo", "1.0.0", 10, 48*time.Hour),
+				entry("foo", "4.0.0", 10, time.Minute),
+				entry("foo", "2.0.0", 10, 2*time.Hour),
+			}
+
+			evicted := map[string]string{}
+			for _, e := range testcase.opts.selectEvictions(entries, testcase.referenced, testcase.maxSize) {
+				evicted[e.binary.Version] = e.reason
+			}
+			require.Len(t, evicted, len(testcase.expected))
+			for v, reason := range testcase.expected {
+				assert.Contains(t, evicted, v)
+				assert.Regexp(t, "^"+reason, evicted[v])
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	local := backend.NewFileSystem(logger.NewTestBuilder(), &backend.FileSystemConfig{FilePathTemplate: localCacheTemplate()})
+	cache := func(v string, age time.Duration) string {
+		path := local.StorePath(config.Binary{Tool: "foo", Version: v, Platform: config.CurrentPlatform(), Arch: config.CurrentArch()})
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("foo-"+v), 0o755))
+		require.NoError(t, os.WriteFile(path+lastUsedSuffix, nil, 0o644))
+		lastUsed := time.Now().Add(-age)
+		require.NoError(t, os.Chtimes(path+lastUsedSuffix, lastUsed, lastUsed))
+		return path
+	}
+	unused := cache("1.0.0", 48*time.Hour)
+	used := cache("2.0.0", time.Hour)
+	locked := cache("3.0.0", 72*time.Hour)
+
+	// A binary whose download lock is held is skipped once the lock is released rather than removed from under the
+	// process holding it.
+	require.NoError(t, os.WriteFile(locked+".pid", []byte(strconv.Itoa(os.Getpid())), 0o600))
+	release := time.AfterFunc(200*time.Millisecond, func() { _ = os.Remove(locked + ".pid") })
+	t.Cleanup(func() { release.Stop() })
+
+	opts := &cacheOptions{
+		CommonOpts: &CommonOpts{
+			LogBuilder: logger.NewBuilder(zapcore.AddSync(io.Discard)),
+			Log:        zap.NewNop(),
+			Config:     &config.Global{},
+		},
+		maxAge: 24 * time.Hour,
+	}
+	require.NoError(t, opts.prune(context.Background()))
+
+	assert.NoFileExists(t, unused)
+	assert.NoFileExists(t, unused+lastUsedSuffix)
+	assert.FileExists(t, used)
+	assert.FileExists(t, locked)
+
+	// Constraints reference the newest cached version that satisfies them.
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".toolshare.yaml"), []byte("pins:\n  foo: '>=2'\n"), 0o644))
+	opts.maxAge = 0
+	opts.roots = []string{root}
+	require.NoError(t, opts.prune(context.Background()))
+
+	assert.NoFileExists(t, used)
+	assert.FileExists(t, locked)
+}
@@ -1,11 +1,16 @@
 package driver
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
-	"runtime"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -28,8 +33,10 @@ func Download(cOpts *CommonOpts) *cobra.Command {
 specify one or more platforms for which to fetch the binaries as well as an architecture. This can
 for example be used when mounting a binary into a docker container for an OS different from the one
 the host is running.`,
-		RunE: func(_ *cobra.Command, _ []string) error {
-			return opts.download()
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx, cancel := opts.withDeadline(cmd.Context())
+			defer cancel()
+			return opts.download(ctx)
 		},
 	}
 
@@ -39,8 +46,8 @@ the host is running.`,
 }
 
 func registerDownloadFlags(cmd *cobra.Command, opts *downloadOptions) {
-	cmd.Flags().StringSliceVar(&opts.archs, "archs", []string{runtime.GOARCH}, "The architecture(s) for which to download binaries.")
-	cmd.Flags().StringSliceVar(&opts.platforms, "platforms", []string{runtime.GOOS}, "The platform(s) for which to download binaries.")
+	cmd.Flags().StringSliceVar(&opts.archs, "archs", []string{string(config.CurrentArch())}, "The architecture(s) for which to download binaries.")
+	cmd.Flags().StringSliceVar(&opts.platforms, "platforms", []string{string(config.CurrentPlatform())}, "The platform(s) for which to download binaries.")
 	cmd.Flags().StringVar(&opts.tool, "tool", "", "The tool for which to download binaries.")
 	cmd.Flags().StringVar(&opts.version, "version", "", "The version of the tool for which to download binaries.")
 
@@ -56,7 +63,7 @@ type downloadOptions struct {
 	archs     []string
 }
 
-func (o downloadOptions) download() error {
+func (o downloadOptions) download(ctx context.Context) error {
 	if o.tool == "" {
 		o.Log.Error("No tool was specified.")
 		return ErrNoToolSet
@@ -73,16 +80,11 @@ func (o downloadOptions) download() error {
 	}
 
 	if o.version == "" {
-		tool, ok := o.Env[o.tool]
-		if !ok {
-			log.Error("Tool could not be found in the current toolshare environment. Use 'toolshare env' go get an overview of currently registered tools.")
-			return ErrUnknownTool
-		}
-		o.version = tool.Version
-		if o.version == "" {
-			log.Error("Tool was not found or could not be resolved to a version to use.")
-			os.Exit(invokeExitCode)
+		version, err := o.toolVersion(ctx, log, o.tool)
+		if err != nil {
+			return err
 		}
+		o.version = version
 	}
 
 	backends, err := o.setupBackends()
@@ -99,7 +101,7 @@ func (o downloadOptions) download() error {
 				Platform: platform,
 				Arch:     arch,
 			}
-			p, err := o.getToolBinary(backends, b)
+			p, err := o.getToolBinary(ctx, backends, b)
 			if err != nil {
 				errs = append(errs, err)
 			} else {
@@ -119,55 +121,157 @@ type storages struct {
 	source backend.Storage
 }
 
+// goToolName is the name of the tool providing the toolchain with which Go module sources are built.
+const goToolName = "go"
+
+// cacheURLTemplate is the layout under which binaries are stored in both the local and remote caches.
+var cacheURLTemplate = []string{"v1", "{tool}", "{version}", "{platform}", "{arch}", "{tool}{exe}"}
+
+// localCacheTemplate returns the path template under which binaries are stored in the local cache.
+func localCacheTemplate() string {
+	return filepath.Join(append([]string{config.StorageDir()}, cacheURLTemplate...)...)
+}
+
 func (o downloadOptions) setupBackends() (*storages, error) {
-	cacheURLTemplate := []string{"v1", "{tool}", "{version}", "{platform}", "{arch}", "{tool}{exe}"}
-
-	backends := &storages{
-		local: backend.NewFileSystem(o.LogBuilder, &backend.FileSystemConfig{
-			FilePathTemplate: filepath.Join(append([]string{config.StorageDir()}, cacheURLTemplate...)...),
-		}),
-		source: o.Env.Source(o.LogBuilder, o.tool),
-	}
-
-	if o.Config.RemoteCache != nil {
-		switch {
-		case o.Config.RemoteCache.GCSBucket != "":
-			backends.remote = backend.NewGCS(o.LogBuilder, &backend.GCSConfig{
-				GCSBucket:       o.Config.RemoteCache.GCSBucket,
-				GCSPathTemplate: strings.Join(append([]string{o.Config.RemoteCache.PathPrefix}, cacheURLTemplate...), "/"),
-			})
-		case o.Config.RemoteCache.HTTPSHost != "":
-			backends.remote = backend.NewHTTPS(o.LogBuilder, &backend.HTTPSConfig{
-				HTTPSURLTemplate: strings.Join(append([]string{o.Config.RemoteCache.HTTPSHost, o.Config.RemoteCache.PathPrefix}, cacheURLTemplate...), "/"),
-			})
-		case o.Config.RemoteCache.S3Bucket != "":
-			backends.remote = backend.NewS3(o.LogBuilder, &backend.S3Config{
-				S3Bucket:       o.Config.RemoteCache.S3Bucket,
-				S3PathTemplate: strings.Join(append([]string{o.Config.RemoteCache.PathPrefix}, cacheURLTemplate...), "/"),
-			})
-		case o.Config.RemoteCache.PathPrefix != "":
-			backends.remote = backend.NewFileSystem(o.LogBuilder, &backend.FileSystemConfig{
-				FilePathTemplate: strings.Join(append([]string{o.Config.RemoteCache.PathPrefix}, cacheURLTemplate...), "/"),
-			})
-		default:
-			return nil, ErrInvalidCacheConfig
-		}
-		o.Log.Debug("Configured remote cache backend.", zap.Stringer("remote-cache", backends.remote))
+	remote, err := o.remoteCache()
+	if err != nil {
+		return nil, err
+	}
+
+	local := &backend.FileSystemConfig{
+		FilePathTemplate: localCacheTemplate(),
+	}
+	if src := o.Env[o.tool].Source; src != nil {
+		local.BundleEntrypointTemplate = src.Common().EntrypointTemplate
+	}
+
+	return &storages{
+		local:  backend.NewFileSystem(o.LogBuilder, local),
+		remote: remote,
+		source: o.toolSource(o.tool),
+	}, nil
+}
+
+// remoteCache sets up the storage backend for the configured remote cache. It returns nil if no remote cache is
+// configured.
+func (c *CommonOpts) remoteCache() (backend.Storage, error) {
+	if c.Config.RemoteCache == nil {
+		return nil, nil
 	}
 
-	return backends, nil
+	var remote backend.Storage
+	switch {
+	case c.Config.RemoteCache.GCSBucket != "":
+		remote = backend.NewGCS(c.LogBuilder, &backend.GCSConfig{
+			GCSBucket:       c.Config.RemoteCache.GCSBucket,
+			GCSPathTemplate: strings.Join(append([]string{c.Config.RemoteCache.PathPrefix}, cacheURLTemplate...), "/"),
+		})
+	case c.Config.RemoteCache.HTTPSHost != "":
+		remote = backend.NewHTTPS(c.LogBuilder, &backend.HTTPSConfig{
+			HTTPSURLTemplate: strings.Join(append([]string{c.Config.RemoteCache.HTTPSHost, c.Config.RemoteCache.PathPrefix}, cacheURLTemplate...), "/"),
+			HTTPSAuth:        c.Config.RemoteCache.HTTPSAuth,
+		})
+	case c.Config.RemoteCache.OCIRegistry != "":
+		// Binaries are stored as one repository per tool with a tag per version, platform and architecture rather than
+		// following the cache's path layout.
+		remote = backend.NewOCI(c.LogBuilder, &backend.OCIConfig{
+			OCIRegistry:           c.Config.RemoteCache.OCIRegistry,
+			OCIRepositoryTemplate: path.Join(c.Config.RemoteCache.PathPrefix, backend.DefaultOCIRepositoryTemplate),
+		})
+	case c.Config.RemoteCache.S3Bucket != "":
+		remote = backend.NewS3(c.LogBuilder, &backend.S3Config{
+			S3Bucket:       c.Config.RemoteCache.S3Bucket,
+			S3PathTemplate: strings.Join(append([]string{c.Config.RemoteCache.PathPrefix}, cacheURLTemplate...), "/"),
+		})
+	case c.Config.RemoteCache.PathPrefix != "":
+		remote = backend.NewFileSystem(c.LogBuilder, &backend.FileSystemConfig{
+			FilePathTemplate: strings.Join(append([]string{c.Config.RemoteCache.PathPrefix}, cacheURLTemplate...), "/"),
+		})
+	default:
+		return nil, ErrInvalidCacheConfig
+	}
+	c.Log.Debug("Configured remote cache backend.", zap.Stringer("remote-cache", remote))
+	return remote, nil
 }
 
-func (o downloadOptions) getToolBinary(backends *storages, binary config.Binary) (string, error) {
+// binarySource returns the storage from which a binary should be fetched when it is not cached, together with the
+// checksum it is expected to match. Binaries recorded in a lock file are fetched from their recorded location.
+func (c *CommonOpts) binarySource(log *zap.Logger, source backend.Storage, binary config.Binary) (backend.Storage, string) {
+	reg := c.Env[binary.Tool]
+	if locked := reg.Locked(binary); locked != nil {
+		log.Debug("Using the source and checksum recorded in the lock file.", zap.String("lock-file", reg.LockFile))
+		return c.withGoToolchain(locked.Storage(c.LogBuilder, reg.Source)), locked.SHA256
+	}
+	return source, reg.Checksum(binary)
+}
+
+// toolSource returns the storage for the source configured for a tool in the current environment, if any.
+func (c *CommonOpts) toolSource(tool string) backend.Storage {
+	return c.withGoToolchain(c.Env.Source(c.LogBuilder, tool))
+}
+
+// withGoToolchain provides storages that build binaries from Go modules with the 'go' toolchain of the current
+// environment.
+func (c *CommonOpts) withGoToolchain(s backend.Storage) backend.Storage {
+	if gm, ok := s.(*backend.GoModule); ok {
+		gm.SetToolchain(c.goToolchain)
+	}
+	return s
+}
+
+// goToolchain returns the path to the 'go' binary at the version used in the current environment. It is obtained like
+// any other tool, i.e. from the local cache, the remote cache or its source.
+func (c *CommonOpts) goToolchain(ctx context.Context) (string, error) {
+	log := c.Log.With(zap.String("tool-name", goToolName))
+
+	version, err := c.toolVersion(ctx, log, goToolName)
+	if err != nil {
+		return "", err
+	}
+	if src := c.Env[goToolName].Source; src != nil && src.GoModuleConfig != nil {
+		log.Error("The go toolchain can not itself be built from a Go module.")
+		return "", ErrInvalidGoToolchain
+	}
+
+	dl := &downloadOptions{
+		CommonOpts: c,
+		tool:       goToolName,
+		version:    version,
+	}
+	backends, err := dl.setupBackends()
+	if err != nil {
+		return "", err
+	}
+	return dl.getToolBinary(ctx, backends, config.Binary{
+		Tool:     goToolName,
+		Version:  version,
+		Platform: config.CurrentPlatform(),
+		Arch:     config.CurrentArch(),
+	})
+}
+
+func (o downloadOptions) getToolBinary(ctx context.Context, backends *storages, binary config.Binary) (string, error) {
 	path := backends.local.Path(binary)
 	log := o.Log.With(zap.Stringer("tool", binary), zap.String("cache-path", path), zap.Int("pid", os.Getpid()))
 
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+	if err := o.checkDenied(log, binary); err != nil {
+		return "", err
+	}
+
+	// The download lock is held on the path at which the binary is stored rather than on the binary itself, as the
+	// latter lives inside of the directory that is replaced when storing a bundle.
+	lockPath := backends.local.StorePath(binary)
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
 		log.Error("Failed to prepare target folder for tool download.")
 		return "", err
 	}
 
 	for {
+		if err := ctx.Err(); err != nil {
+			log.Debug("Tool download was cancelled.", zap.Error(err))
+			return "", err
+		}
+
 		if _, err := os.Stat(path); err == nil {
 			log.Debug("Found binary in local storage.")
 			return path, nil
@@ -177,7 +281,7 @@ func (o downloadOptions) getToolBinary(backends *storages, binary config.Binary)
 		}
 		log.Debug("Binary not present in local storage.")
 
-		ok, err := flock.AcquireFileLock(log, path)
+		ok, err := flock.AcquireFileLock(ctx, log, lockPath)
 		if err != nil {
 			log.Error("Failed to acquire download lock.", zap.Error(err))
 		} else if ok {
@@ -186,13 +290,16 @@ func (o downloadOptions) getToolBinary(backends *storages, binary config.Binary)
 	}
 
 	defer func() {
-		if err := flock.ReleaseFileLock(log, path); err != nil {
+		if err := flock.ReleaseFileLock(log, lockPath); err != nil {
 			log.Warn("Failed to release download lock correctly.", zap.Error(err))
 		}
 	}()
 
+	source, expectedChecksum := o.binarySource(log, backends.source, binary)
+
+	var fetchedFrom backend.Storage
 	fetchErr := ErrNoBackends
-	for _, s := range []backend.Storage{backends.remote, backends.source} {
+	for _, s := range []backend.Storage{backends.remote, source} {
 		if s == nil {
 			continue
 		}
@@ -200,20 +307,117 @@ func (o downloadOptions) getToolBinary(backends *storages, binary config.Binary)
 		sLog := log.With(zap.Stringer("storage", s))
 		sLog.Debug("Attempting to fetch binary.")
 
-		var raw []byte
-		raw, fetchErr = s.Fetch(binary)
-		sLog.Debug("Fetched binary from storage.")
+		var rc io.ReadCloser
+		rc, fetchErr = s.Fetch(ctx, binary)
+		if fetchErr != nil {
+			continue
+		}
+		sLog.Debug("Streaming binary from storage to local cache.")
+
+		fetchErr = backends.local.Store(ctx, binary, newChecksumReader(sLog, rc, expectedChecksum))
+		if err := rc.Close(); err != nil {
+			sLog.Debug("Failed to close binary stream.", zap.Error(err))
+		}
 		if fetchErr == nil {
-			if err := backends.local.Store(binary, raw); err != nil {
-				log.Debug("Failed to store binary in local cache.", zap.Error(err))
-				return "", err
-			}
 			log.Debug("Successfully stored binary in local cache.")
+			fetchedFrom = s
 			break
 		}
+		sLog.Debug("Failed to store binary in local cache.", zap.Error(fetchErr))
 	}
 	if fetchErr != nil {
 		return "", fetchErr
 	}
+
+	if fetchedFrom != backends.remote && backends.remote != nil && o.Config.RemoteCache.WriteThrough {
+		if err := o.writeThrough(ctx, log, backends, binary); err != nil {
+			return "", err
+		}
+	}
 	return path, nil
 }
+
+// writeThrough stores a binary that was fetched from a source, and is hence now present in the local cache, in the
+// remote cache as well. Depending on the configured policy a failure to do so either fails or only results in a
+// warning.
+func (o downloadOptions) writeThrough(ctx context.Context, log *zap.Logger, backends *storages, binary config.Binary) error {
+	log = log.With(zap.Stringer("remote-cache", backends.remote))
+
+	err := func() error {
+		rc, err := backends.local.Fetch(ctx, binary)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return backends.remote.Store(ctx, binary, rc)
+	}()
+	switch {
+	case err == nil:
+		log.Debug("Stored binary in the remote cache.")
+		return nil
+	case errors.Is(err, backend.ErrAlreadyExists):
+		log.Debug("Binary was stored in the remote cache concurrently.")
+		return nil
+	}
+
+	switch o.Config.RemoteCache.OnWriteFailure {
+	case config.WriteFailurePolicyWarn, "":
+		log.Warn("Failed to store binary in the remote cache.", zap.Error(err))
+		return nil
+	case config.WriteFailurePolicyFail:
+		log.Error("Failed to store binary in the remote cache.", zap.Error(err))
+		// Remove the locally cached binary so that the write-through is attempted again on the next use of the tool.
+		if rmErr := os.RemoveAll(backends.local.StorePath(binary)); rmErr != nil {
+			log.Warn("Failed to remove binary from local cache.", zap.Error(rmErr))
+		}
+		return err
+	default:
+		log.Error("Unknown policy for handling remote cache write failures.", zap.String("policy", string(o.Config.RemoteCache.OnWriteFailure)))
+		return ErrInvalidWritePolicy
+	}
+}
+
+// checksumReader computes the SHA-256 digest of a binary while it is being streamed and compares it with the one
+// recorded in the environment, if any. On a mismatch the final read returns an error instead of io.EOF which ensures
+// that a binary that does not match the expected digest never makes it into the local cache.
+type checksumReader struct {
+	log      *zap.Logger
+	rd       io.Reader
+	hash     hash.Hash
+	expected string
+}
+
+func newChecksumReader(log *zap.Logger, rd io.Reader, expected string) *checksumReader {
+	return &checksumReader{
+		log:      log,
+		rd:       rd,
+		hash:     sha256.New(),
+		expected: expected,
+	}
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.rd.Read(p)
+	_, _ = r.hash.Write(p[:n])
+	if errors.Is(err, io.EOF) {
+		if verifyErr := verifyChecksum(r.log, hex.EncodeToString(r.hash.Sum(nil)), r.expected); verifyErr != nil {
+			return n, verifyErr
+		}
+	}
+	return n, err
+}
+
+func verifyChecksum(log *zap.Logger, actual, expected string) error {
+	log = log.With(zap.String("checksum", actual))
+
+	if expected == "" {
+		log.Debug("No checksum recorded for binary. Skipping verification.")
+		return nil
+	}
+	if actual != expected {
+		log.Error("Checksum of fetched binary does not match the expected one. Refusing to use it.", zap.String("expected-checksum", expected))
+		return fmt.Errorf("%w: expected %s but got %s", ErrChecksumMismatch, expected, actual)
+	}
+	log.Debug("Checksum of fetched binary matches the expected one.")
+	return nil
+}
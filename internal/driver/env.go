@@ -1,11 +1,13 @@
 package driver
 
 import (
+	"context"
 	"fmt"
 	"sort"
 
 	"github.com/ryanuber/columnize"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 
 	"github.com/Helcaraxan/toolshare/internal/config"
 )
@@ -26,7 +28,9 @@ follows:
   to the '%s subscribe' command.
 - For each tool we find the first version provided by the following steps:
   - Recursively walking up the filesystem up to the root looking for '%s.yaml' files containing
-    a pinned version for the config.
+    a pinned version for the config. Version constraints such as '^1.64' or '>=1.11,<2' that are
+    found before an exact version are combined and resolved to the newest satisfying version known
+    to the state or released by the tool's source.
   - Looking at the user's configuration directory for a potential 'global.yaml' file pinning a
 	version for the config. The configuration directory is '$HOME/.config/%s' on Linux and MacOS
 	and '%%LOCALAPPDATA%%/%s' on Windows.
@@ -36,8 +40,10 @@ follows:
   - If, and only if, running unpinned versions is not prohibited by the local configuration we check
     the global state for the default version, if one is available.`, config.DriverName, config.DriverName, config.DriverName, config.DriverName, config.DriverName, config.DriverName),
 		Args: cobra.NoArgs,
-		RunE: func(_ *cobra.Command, _ []string) error {
-			return opts.environment()
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx, cancel := opts.withDeadline(cmd.Context())
+			defer cancel()
+			return opts.environment(ctx)
 		},
 	}
 
@@ -56,7 +62,7 @@ func registerEnvFlags(cmd *cobra.Command, opts *envOptions) {
 	cmd.Flags().BoolVar(&opts.full, "full", false, "Print extra information.")
 }
 
-func (o *envOptions) environment() error {
+func (o *envOptions) environment(ctx context.Context) error {
 	defaultSource := "local"
 	if o.Config.RemoteCache != nil {
 		defaultSource += " or remote"
@@ -74,9 +80,21 @@ func (o *envOptions) environment() error {
 		if reg.Source != nil {
 			s = reg.Source.String()
 		}
-		info := fmt.Sprintf("%s | %s | %s", tool, reg.Version, s)
+		pin, pinFile := reg.Version, reg.VersionFile
+		switch {
+		case reg.Recommended:
+			pin += " (state)"
+			pinFile = "state"
+		case reg.Constraint != nil:
+			resolved, err := o.toolVersion(ctx, o.Log.With(zap.String("tool-name", tool)), tool)
+			if err != nil {
+				resolved = "?"
+			}
+			pin = fmt.Sprintf("%s -> %s", reg.Constraint, resolved)
+		}
+		info := fmt.Sprintf("%s | %s | %s", tool, pin, s)
 		if o.full {
-			info += fmt.Sprintf(" | %s | %s", reg.VersionFile, reg.SourceFile)
+			info += fmt.Sprintf(" | %s | %s", pinFile, reg.SourceFile)
 		}
 		sortedTools = append(sortedTools, info)
 	}
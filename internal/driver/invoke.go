@@ -1,6 +1,7 @@
 package driver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -25,9 +26,15 @@ func Invoke(cOpts *CommonOpts) *cobra.Command {
 		Short: "Run a tool with the given arguments.",
 		Long: fmt.Sprintf(`Run a tool at a version determined by the current environment with the given arguments. For details
 about how the current environment is determined please see '%s env --help'.`, config.DriverName),
-		RunE: func(_ *cobra.Command, args []string) error {
+		// Invocations, e.g. via shims, are on the hot path so the environment is only resolved against the state for the
+		// invoked tool. This replaces the root command's hook.
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			opts.Tools = []string{opts.tool}
+			return opts.Parse()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
 			opts.args = args
-			return opts.invoke()
+			return opts.invoke(cmd.Context())
 		},
 	}
 
@@ -53,7 +60,7 @@ func registerInvokeFlags(cmd *cobra.Command, opts *invokeOptions) {
 
 const invokeExitCode = 128 // Used to differentiate from exit codes from an invoked process.
 
-func (o *invokeOptions) invoke() error {
+func (o *invokeOptions) invoke(ctx context.Context) error {
 	if o.tool == "" {
 		o.Log.Error("No tool was specified.")
 		return ErrNoToolSet
@@ -62,15 +69,14 @@ func (o *invokeOptions) invoke() error {
 
 	version := o.version
 	if version == "" {
-		version = o.Env[o.tool].Version
-	}
-	if version == "" {
-		log.Error("Tool is not present in current toolshare environment or could not be resolved to a version to use")
-		os.Exit(invokeExitCode)
+		var err error
+		if version, err = o.toolVersion(ctx, log, o.tool); err != nil {
+			os.Exit(invokeExitCode)
+		}
 	}
 	log = log.With(zap.String("tool-version", version))
 
-	path, err := o.ensureTool(log, version)
+	path, err := o.ensureTool(ctx, log, version)
 	if err != nil {
 		return err
 	}
@@ -108,9 +114,13 @@ func (o *invokeOptions) invoke() error {
 	return nil
 }
 
-func (o *invokeOptions) ensureTool(log *zap.Logger, version string) (string, error) {
+func (o *invokeOptions) ensureTool(ctx context.Context, log *zap.Logger, version string) (string, error) {
 	log.Debug("Ensuring presence of tool binary.")
 
+	// The deadline only applies to obtaining the tool binary, not to the invocation itself.
+	ctx, cancel := o.withDeadline(ctx)
+	defer cancel()
+
 	dl := &downloadOptions{
 		CommonOpts: o.CommonOpts,
 		tool:       o.tool,
@@ -123,16 +133,18 @@ func (o *invokeOptions) ensureTool(log *zap.Logger, version string) (string, err
 		log.Error("Failed to prepare storage backends.", zap.Error(err))
 		os.Exit(invokeExitCode)
 	}
-	path, err := dl.getToolBinary(backends, config.Binary{
+	binary := config.Binary{
 		Tool:     o.tool,
 		Version:  version,
 		Platform: config.CurrentPlatform(),
 		Arch:     config.CurrentArch(),
-	})
+	}
+	path, err := dl.getToolBinary(ctx, backends, binary)
 	if err != nil {
 		log.Error("Failed to fetch tool.", zap.Error(err))
 		return "", err
 	}
+	markUsed(log, backends.local.StorePath(binary))
 	return path, nil
 }
 
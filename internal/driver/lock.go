@@ -0,0 +1,185 @@
+package driver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/backend"
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/environment"
+)
+
+func Lock(cOpts *CommonOpts) *cobra.Command {
+	opts := &lockOptions{
+		CommonOpts: cOpts,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "lock [--tools=<name,...>] [--platforms=<darwin,...>] [--archs=<x86_64,...>]",
+		Short: "Record the resolved sources and digests of tool binaries in a lock file.",
+		Long: fmt.Sprintf(`Resolve the binaries of pinned tools to the concrete location from which they are fetched and record
+these together with the digest of each binary in a lock file next to the innermost environment file.
+Subsequent downloads of the same binaries, including via '%s invoke', fetch them from the recorded
+location and verify them against the recorded digest.
+
+Existing lock entries for platforms and architectures that are not requested are kept as long as the
+tool's pinned version did not change.`, config.DriverName),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx, cancel := opts.withDeadline(cmd.Context())
+			defer cancel()
+			return opts.lock(ctx)
+		},
+	}
+
+	registerLockFlags(cmd, opts)
+
+	return cmd
+}
+
+func registerLockFlags(cmd *cobra.Command, opts *lockOptions) {
+	cmd.Flags().StringSliceVar(&opts.archs, "archs", []string{string(config.CurrentArch())}, "The architecture(s) for which to lock binaries.")
+	cmd.Flags().StringSliceVar(&opts.platforms, "platforms", []string{string(config.CurrentPlatform())}, "The platform(s) for which to lock binaries.")
+	cmd.Flags().StringSliceVar(&opts.tools, "tools", nil, "List of tools to lock. If left empty all pinned tools with a source in the current environment are locked.")
+}
+
+type lockOptions struct {
+	*CommonOpts
+
+	tools     []string
+	platforms []string
+	archs     []string
+}
+
+func (o *lockOptions) lock(ctx context.Context) error {
+	envFile, err := environment.LocalFile()
+	if err != nil {
+		o.Log.Error("Unable to find an environment file next to which to write a lock file.", zap.Error(err))
+		return err
+	}
+	lockFile := environment.LockFilePath(envFile)
+	log := o.Log.With(zap.String("lock-file", lockFile))
+
+	lock, err := environment.ReadLock(lockFile)
+	if err != nil {
+		log.Error("Failed to read existing lock file.", zap.Error(err))
+		return err
+	}
+
+	if len(o.tools) == 0 {
+		for name, reg := range o.Env {
+			if (reg.Version != "" || reg.Constraint != nil) && !reg.Recommended && reg.Source != nil {
+				o.tools = append(o.tools, name)
+			}
+		}
+		for name := range lock.Tools {
+			if reg := o.Env[name]; (reg.Version == "" && reg.Constraint == nil) || reg.Recommended || reg.Source == nil {
+				log.Debug("Dropping lock entry for tool that is no longer pinned.", zap.String("tool-name", name))
+				delete(lock.Tools, name)
+			}
+		}
+	}
+	sort.Strings(o.tools)
+
+	var errs []error
+	for _, name := range o.tools {
+		if err = o.lockTool(ctx, log.With(zap.String("tool-name", name)), lock, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		log.Error("Failed to lock some tools. Leaving the lock file untouched.")
+		return fmt.Errorf("failed to lock some tools: %w", errors.Join(errs...))
+	}
+
+	if err = environment.WriteLock(lockFile, lock); err != nil {
+		log.Error("Failed to write lock file.", zap.Error(err))
+		return err
+	}
+	log.Info("Successfully updated lock file.", zap.Strings("tools", o.tools))
+	return nil
+}
+
+func (o *lockOptions) lockTool(ctx context.Context, log *zap.Logger, lock *environment.Lock, name string) error {
+	reg, ok := o.Env[name]
+	if !ok || (reg.Version == "" && reg.Constraint == nil) || reg.Recommended {
+		log.Error("Tool is not pinned in the current environment.")
+		return fmt.Errorf("%w: %s", ErrUnknownTool, name)
+	}
+	version, err := o.toolVersion(ctx, log, name)
+	if err != nil {
+		return err
+	}
+	source := o.toolSource(name)
+	if source == nil {
+		log.Error("Tool has no source configured in the current environment.")
+		return fmt.Errorf("%w: %s", ErrNoBackends, name)
+	}
+
+	for _, platform := range o.platforms {
+		for _, arch := range o.archs {
+			b := config.Binary{
+				Tool:     name,
+				Version:  version,
+				Platform: config.Platform(platform),
+				Arch:     config.Arch(arch),
+			}
+			bLog := log.With(zap.Stringer("tool", b))
+
+			location, err := source.Resolve(ctx, b)
+			if err != nil {
+				bLog.Error("Failed to resolve the location of the binary.", zap.Error(err))
+				return err
+			}
+			digest, archivePath, err := fetchChecksum(ctx, bLog, source, b)
+			if err != nil {
+				return err
+			}
+			if err = verifyChecksum(bLog, digest, reg.Checksum(b)); err != nil {
+				return err
+			}
+
+			lock.Set(b, &environment.LockedBinary{
+				Source:      location,
+				ArchivePath: archivePath,
+				ArchiveDir:  reg.Source.Common().ArchiveDir(b),
+				Entrypoint:  reg.Source.Common().Entrypoint(b),
+				SHA256:      digest,
+			})
+			bLog.Debug("Locked binary.", zap.String("location", location))
+		}
+	}
+	return nil
+}
+
+// fetchChecksum streams a binary from the given storage and returns its hex-encoded SHA-256 digest. For binaries that are
+// extracted from an archive it also returns the path of the archive entry that matched the source's archive path
+// template, so that locking a template with glob patterns does not allow a different entry to match later on.
+func fetchChecksum(ctx context.Context, log *zap.Logger, source backend.Storage, b config.Binary) (string, string, error) {
+	rc, err := source.Fetch(ctx, b)
+	if err != nil {
+		log.Error("Failed to fetch binary.", zap.Error(err))
+		return "", "", err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, rc); err != nil {
+		log.Error("Failed to read binary.", zap.Error(err))
+		return "", "", err
+	}
+
+	var archiveEntry string
+	if ar, ok := rc.(backend.ArchiveEntryReader); ok {
+		archiveEntry = ar.ArchiveEntry()
+	}
+	return hex.EncodeToString(h.Sum(nil)), archiveEntry, nil
+}
@@ -0,0 +1,187 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/backend"
+	"github.com/Helcaraxan/toolshare/internal/config"
+)
+
+func Mirror(cOpts *CommonOpts) *cobra.Command {
+	opts := &mirrorOptions{
+		CommonOpts: cOpts,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "mirror [--tools=<name[@version],...>] [--platforms=<darwin,...>] [--archs=<x86_64,...>]",
+		Aliases: []string{"publish"},
+		Short:   "Populate the remote cache with tool binaries fetched from their sources.",
+		Long: `Fetch the binaries of tools from their configured sources and store them in the remote cache for each
+of the requested platforms and architectures. Binaries that are already present in the remote cache
+are skipped. This allows to seed a remote cache that is used in environments without access to the
+tools' sources, such as air-gapped networks.
+
+Tools are mirrored at the version used in the current environment unless a version is explicitly
+specified as '<name>@<version>'. Binaries are verified against any checksums or lock file entries
+recorded in the current environment before being stored.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx, cancel := opts.withDeadline(cmd.Context())
+			defer cancel()
+			return opts.mirror(ctx)
+		},
+	}
+
+	registerMirrorFlags(cmd, opts)
+
+	return cmd
+}
+
+func registerMirrorFlags(cmd *cobra.Command, opts *mirrorOptions) {
+	cmd.Flags().StringSliceVar(&opts.archs, "archs", []string{string(config.CurrentArch())}, "The architecture(s) for which to mirror binaries.")
+	cmd.Flags().StringSliceVar(&opts.platforms, "platforms", []string{string(config.CurrentPlatform())}, "The platform(s) for which to mirror binaries.")
+	cmd.Flags().StringSliceVar(&opts.tools, "tools", nil, "List of tools to mirror, optionally as '<name>@<version>'. If left empty all tools with a source in the current environment are mirrored.")
+}
+
+type mirrorOptions struct {
+	*CommonOpts
+
+	tools     []string
+	platforms []string
+	archs     []string
+}
+
+func (o *mirrorOptions) mirror(ctx context.Context) error {
+	remote, err := o.remoteCache()
+	if err != nil {
+		o.Log.Error("Invalid remote cache configuration.", zap.Error(err))
+		return err
+	} else if remote == nil {
+		o.Log.Error("No remote cache is configured. There is nowhere to mirror binaries to.")
+		return ErrNoRemoteCache
+	}
+	log := o.Log.With(zap.Stringer("remote-cache", remote))
+
+	if len(o.tools) == 0 {
+		for name, reg := range o.Env {
+			if (reg.Version != "" || reg.Constraint != nil) && reg.Source != nil {
+				o.tools = append(o.tools, name)
+			}
+		}
+	}
+	sort.Strings(o.tools)
+
+	var errs []error
+	for _, tool := range o.tools {
+		name, version, _ := strings.Cut(tool, "@")
+		tLog := log.With(zap.String("tool-name", name))
+
+		if version == "" {
+			if version, err = o.toolVersion(ctx, tLog, name); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+
+		source := o.toolSource(name)
+		for _, platform := range o.platforms {
+			for _, arch := range o.archs {
+				b := config.Binary{
+					Tool:     name,
+					Version:  version,
+					Platform: config.Platform(platform),
+					Arch:     config.Arch(arch),
+				}
+				if err = o.mirrorBinary(ctx, tLog.With(zap.Stringer("tool", b)), remote, source, b); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to mirror some binaries: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (o *mirrorOptions) mirrorBinary(ctx context.Context, log *zap.Logger, remote, source backend.Storage, b config.Binary) error {
+	if err := o.checkDenied(log, b); err != nil {
+		return err
+	}
+
+	if rc, err := remote.Fetch(ctx, b); err == nil {
+		_ = rc.Close()
+		log.Info("Binary is already present in the remote cache.")
+		return nil
+	}
+
+	source, expectedChecksum := o.binarySource(log, source, b)
+	if source == nil {
+		log.Error("Tool has no source configured in the current environment.")
+		return fmt.Errorf("%w: %s", ErrNoBackends, b.Tool)
+	}
+	log = log.With(zap.Stringer("storage", source))
+
+	verified, err := o.fetchVerified(ctx, log, source, b, expectedChecksum)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = verified.Close()
+		if err := os.Remove(verified.Name()); err != nil {
+			log.Debug("Failed to remove temporary copy of binary.", zap.Error(err))
+		}
+	}()
+
+	if err = remote.Store(ctx, b, verified); errors.Is(err, backend.ErrAlreadyExists) {
+		log.Info("Binary was stored in the remote cache concurrently.")
+		return nil
+	} else if err != nil {
+		log.Error("Failed to store binary in the remote cache.", zap.Error(err))
+		return err
+	}
+	log.Info("Mirrored binary to the remote cache.")
+	return nil
+}
+
+// fetchVerified fetches the binary into a temporary file and verifies its checksum before returning the file, rewound to
+// its start. Verifying the binary before storing it guarantees that remote caches whose uploads can not be aborted once
+// started never receive a binary that does not match the expected digest. The caller must remove the file.
+func (o *mirrorOptions) fetchVerified(ctx context.Context, log *zap.Logger, source backend.Storage, b config.Binary, expectedChecksum string) (*os.File, error) {
+	rc, err := source.Fetch(ctx, b)
+	if err != nil {
+		log.Error("Failed to fetch binary from source.", zap.Error(err))
+		return nil, err
+	}
+	defer rc.Close()
+
+	dir, err := backend.SpoolDir()
+	if err != nil {
+		log.Error("Failed to create directory for temporary files.", zap.Error(err))
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(dir, fmt.Sprintf("%s-mirror-*", config.DriverName))
+	if err != nil {
+		log.Error("Failed to create temporary file for binary.", zap.Error(err))
+		return nil, err
+	}
+	if _, err = io.Copy(tmp, newChecksumReader(log, rc, expectedChecksum)); err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		log.Error("Failed to fetch binary from source.", zap.Error(err))
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return nil, err
+	}
+	return tmp, nil
+}
@@ -0,0 +1,90 @@
+package driver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/environment"
+	"github.com/Helcaraxan/toolshare/internal/logger"
+)
+
+// memoryStorage keeps binaries in memory. Like some remote caches it commits whatever content it received, even when
+// reading that content fails.
+type memoryStorage struct {
+	binaries map[config.Binary][]byte
+}
+
+func (s *memoryStorage) String() string { return "memory" }
+
+func (s *memoryStorage) Fetch(_ context.Context, b config.Binary) (io.ReadCloser, error) {
+	content, ok := s.binaries[b]
+	if !ok {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *memoryStorage) Store(_ context.Context, b config.Binary, content io.Reader) error {
+	raw, err := io.ReadAll(content)
+	s.binaries[b] = raw
+	return err
+}
+
+func (s *memoryStorage) Resolve(_ context.Context, b config.Binary) (string, error) {
+	return b.String(), nil
+}
+
+func TestMirrorBinary(t *testing.T) {
+	t.Parallel()
+
+	b := config.Binary{Tool: "foo", Version: "1.0.0", Platform: config.PlatformLinux, Arch: config.ArchX64}
+	content := []byte("foo-binary")
+	digest := sha256.Sum256(content)
+
+	testcases := map[string]struct {
+		checksum    string
+		expectedErr error
+	}{
+		"NoChecksum":       {},
+		"MatchingChecksum": {checksum: hex.EncodeToString(digest[:])},
+		"ChecksumMismatch": {checksum: hex.EncodeToString(make([]byte, sha256.Size)), expectedErr: ErrChecksumMismatch},
+	}
+
+	for name, testcase := range testcases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			reg := environment.ToolRegistration{Version: b.Version}
+			if testcase.checksum != "" {
+				reg.Checksums = map[string]map[string]string{b.Version: {"linux/x86_64": testcase.checksum}}
+			}
+			opts := &mirrorOptions{CommonOpts: &CommonOpts{
+				LogBuilder: logger.NewBuilder(zapcore.AddSync(io.Discard)),
+				Log:        zap.NewNop(),
+				Config:     &config.Global{},
+				Env:        environment.Environment{b.Tool: reg},
+			}}
+			source := &memoryStorage{binaries: map[config.Binary][]byte{b: content}}
+			remote := &memoryStorage{binaries: map[config.Binary][]byte{}}
+
+			err := opts.mirrorBinary(context.Background(), zap.NewNop(), remote, source, b)
+			if testcase.expectedErr != nil {
+				require.ErrorIs(t, err, testcase.expectedErr)
+				assert.Empty(t, remote.binaries)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, content, remote.binaries[b])
+		})
+	}
+}
@@ -1,28 +1,43 @@
 package driver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/Helcaraxan/toolshare/internal/backend"
 	"github.com/Helcaraxan/toolshare/internal/config"
 	"github.com/Helcaraxan/toolshare/internal/environment"
 	"github.com/Helcaraxan/toolshare/internal/logger"
+	"github.com/Helcaraxan/toolshare/internal/state"
 )
 
 var (
+	ErrChecksumMismatch     = errors.New("checksum mismatch")
+	ErrDeniedVersion        = errors.New("tool version is denied by the state")
 	ErrFailedShimCreation   = errors.New("failed to create tool shim")
 	ErrInvalidCacheConfig   = errors.New("invalid cache configuration")
+	ErrInvalidGoToolchain   = errors.New("invalid go toolchain")
+	ErrInvalidPin           = errors.New("invalid pin")
+	ErrInvalidSize          = errors.New("invalid size")
+	ErrInvalidWritePolicy   = errors.New("invalid remote cache write failure policy")
 	ErrInvalidToolshareShim = fmt.Errorf("can not create shim for tool with the same name as the driver %q", config.DriverName)
 	ErrNoBackends           = errors.New("no backend found")
+	ErrNoPruneCriteria      = errors.New("no eviction criteria specified")
+	ErrNoRemoteCache        = errors.New("no remote cache configured")
+	ErrNoState              = errors.New("no state configured")
 	ErrNoToolSet            = errors.New("no tool set")
 	ErrUnknownSyncMode      = errors.New("unknown sync mode")
+	ErrUnknownNewestVersion = errors.New("unable to determine the newest version of tool")
 	ErrUnknownTool          = errors.New("tool unknown in current environment")
-
-	ErrUnimplemented = errors.New("unimplemented")
+	ErrUnpinnedTool         = errors.New("tool is not pinned in current environment")
+	ErrUnsatisfiedPin       = errors.New("no version satisfies the pinned version constraint")
 )
 
 type CommonOpts struct {
@@ -30,7 +45,13 @@ type CommonOpts struct {
 	Log        *zap.Logger
 	Config     *config.Global
 	Env        environment.Environment
+	State      state.Cache
 	Verbose    []string
+	Timeout    time.Duration
+
+	// Tools restricts the resolution of the environment against the state to the given tools. This spares commands that
+	// only use a single tool from consulting the state about all others. All tools are resolved if it is empty.
+	Tools []string
 }
 
 func NewCommonOpts() *CommonOpts {
@@ -51,8 +72,129 @@ func (c *CommonOpts) Parse() error {
 		return err
 	}
 
-	if err := environment.GetEnvironment(c.Config, c.Env); err != nil {
+	if c.Config.State != nil {
+		c.State = state.NewCache(c.LogBuilder.Domain(logger.StateDomain), config.StateDir(), c.Config.State)
+	}
+
+	if err := environment.GetEnvironment(c.LogBuilder.Domain(logger.StateDomain), c.Config, c.State, c.Env, c.Tools...); err != nil {
 		return err
 	}
+
+	if c.Timeout == 0 {
+		c.Timeout = c.Config.Timeout
+	}
+	return nil
+}
+
+// toolVersion determines the version at which a tool should be used in the current environment. Tools that are not
+// pinned but have a version recommended by the state may only be used if the configuration does not force pinning.
+// Version constraints that could not be resolved from the lock or the state are resolved against the releases listed by
+// the tool's source.
+func (c *CommonOpts) toolVersion(ctx context.Context, log *zap.Logger, tool string) (string, error) {
+	if len(c.Tools) > 0 && !slices.Contains(c.Tools, tool) {
+		// The environment was only resolved against the state for the tools that the command was expected to use.
+		environment.ResolveState(c.LogBuilder.Domain(logger.StateDomain), c.State, c.Env, tool)
+		c.Tools = append(c.Tools, tool)
+	}
+
+	reg, ok := c.Env[tool]
+	var fromSource bool
+	if ok && reg.Version == "" && reg.Constraint != nil {
+		resolved, err := c.resolveConstraint(ctx, log, tool)
+		if err != nil {
+			return "", err
+		}
+		reg.Version = resolved
+		c.Env[tool] = reg
+		fromSource = true
+	}
+
+	if !ok || reg.Version == "" {
+		log.Sugar().Errorf("Tool is not present in current toolshare environment or could not be resolved to a version to use. Use '%s env' to get an overview of currently registered tools.", config.DriverName)
+		return "", ErrUnknownTool
+	}
+	// Versions resolved from the source's releases satisfy the constraint by construction. Checking them again would
+	// infer their pre-release status from their version rather than from the source's metadata.
+	if reg.Constraint != nil && !fromSource && !reg.Constraint.Check(reg.Version) {
+		log.Error("The version pinned by an outer environment file does not satisfy the constraint of an inner one.", zap.String("version", reg.Version), zap.Stringer("constraint", reg.Constraint))
+		return "", fmt.Errorf("%w: %s does not satisfy %s", ErrUnsatisfiedPin, reg.Version, reg.Constraint)
+	}
+	if reg.Recommended && c.Config.ForcePinned {
+		log.Error("Tool is not pinned in the current environment and the configuration forbids the use of versions recommended by the state.")
+		return "", ErrUnpinnedTool
+	}
+	return reg.Version, nil
+}
+
+// resolveConstraint returns the newest version that satisfies the tool's version constraint amongst the releases listed
+// by its source. Releases that the source marks as pre-releases are only considered if the constraint allows for them.
+func (c *CommonOpts) resolveConstraint(ctx context.Context, log *zap.Logger, tool string) (string, error) {
+	constraint := c.Env[tool].Constraint
+	log = log.With(zap.Stringer("constraint", constraint))
+
+	source := c.toolSource(tool)
+	lister, ok := source.(backend.ReleaseLister)
+	if !ok {
+		log.Error("The constraint can not be resolved from the lock or the state and the tool's source does not list releases.")
+		return "", fmt.Errorf("%w: %s", ErrUnsatisfiedPin, constraint)
+	}
+	releases, err := c.toolReleases(ctx, log, tool, source, lister)
+	if err != nil {
+		return "", err
+	}
+
+	versions, isPrerelease := releaseVersions(releases)
+	resolved := environment.ResolveConstraint(log, c.State, tool, constraint, versions, isPrerelease)
+	if resolved == "" {
+		log.Error("No release of the tool satisfies the constraint.")
+		return "", fmt.Errorf("%w: %s", ErrUnsatisfiedPin, constraint)
+	}
+	return resolved, nil
+}
+
+// checkDenied verifies that the binary's version is not denied by the state. Depending on the configured policy the use
+// of a denied version either fails or only results in a warning. This applies even to explicitly pinned versions. A
+// version that can not be checked against the deny-list is handled as if it were denied.
+func (c *CommonOpts) checkDenied(log *zap.Logger, binary config.Binary) error {
+	if c.State == nil {
+		return nil
+	}
+
+	denied, err := c.State.DeniedVersions(binary.Tool)
+	if err != nil {
+		if c.Config.State.OnDenied == config.DenyPolicyWarn {
+			log.Warn("Unable to determine whether the tool version is denied by the state.", zap.Error(err))
+			return nil
+		}
+		log.Error("Unable to determine whether the tool version is denied by the state. Refusing to use it.", zap.Error(err))
+		return fmt.Errorf("%w: %s: unable to read the deny-list: %w", ErrDeniedVersion, binary.Version, err)
+	}
+
+	for _, d := range denied {
+		if d.Version != binary.Version {
+			continue
+		}
+		log = log.With(zap.String("deny-reason", d.Reason))
+
+		switch c.Config.State.OnDenied {
+		case config.DenyPolicyWarn:
+			log.Warn("Tool version is denied by the state. Please switch to a different version.")
+			return nil
+		case config.DenyPolicyFail, "":
+			log.Error("Tool version is denied by the state. Refusing to use it.")
+			return fmt.Errorf("%w: %s: %s", ErrDeniedVersion, binary.Version, d.Reason)
+		default:
+			log.Error("Unknown policy for handling denied tool versions.", zap.String("policy", string(c.Config.State.OnDenied)))
+			return config.ErrInvalidDenyPolicy
+		}
+	}
 	return nil
 }
+
+// withDeadline derives a context that enforces the configured overall deadline, if any, on fetching tools.
+func (c *CommonOpts) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.Timeout)
+}
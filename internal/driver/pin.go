@@ -0,0 +1,101 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/environment"
+	"github.com/Helcaraxan/toolshare/internal/version"
+)
+
+func Pin(cOpts *CommonOpts) *cobra.Command {
+	opts := &pinOptions{
+		CommonOpts: cOpts,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "pin <tool>@<version>",
+		Short: "Pin a tool to a version in the nearest environment file.",
+		Long: fmt.Sprintf(`Pin the tool to the given version, or version constraint, in the innermost environment file of the
+current directory or any of its parents. An existing pin of the tool in that file is replaced. If
+there is no such environment file, one is created in the current directory.
+
+Pinning a tool does not add a source for it. Use '%s add' for tools that are neither available from
+another environment file nor from the state.`, config.DriverName),
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			tool, v, ok := strings.Cut(args[0], "@")
+			if !ok || tool == "" || v == "" {
+				return fmt.Errorf("%w: %q should be of the form <tool>@<version>", ErrInvalidPin, args[0])
+			}
+			opts.tool, opts.version = tool, v
+			return opts.pin()
+		},
+	}
+
+	return cmd
+}
+
+type pinOptions struct {
+	*CommonOpts
+
+	tool    string
+	version string
+}
+
+func (o *pinOptions) pin() error {
+	log := o.Log.With(zap.String("tool-name", o.tool), zap.String("version", o.version))
+
+	if version.IsConstraint(o.version) {
+		if _, err := version.ParseConstraint(o.version); err != nil {
+			log.Error("Invalid version constraint.", zap.Error(err))
+			return err
+		}
+	}
+
+	envFile, err := editNearestFile(log, func(content []byte) ([]byte, error) {
+		return environment.AddPin(content, o.tool, o.version)
+	})
+	if err != nil {
+		return err
+	}
+	log.Info("Successfully pinned tool.", zap.String("env-file", envFile))
+
+	if o.Env[o.tool].Source == nil && o.State == nil {
+		log.Sugar().Warnf("The tool has no source in the current environment. Use '%s add' to add one.", config.DriverName)
+	}
+	return nil
+}
+
+// editNearestFile applies the edit to the content of the nearest environment file, which is created if it does not
+// exist yet, and returns the file's path.
+func editNearestFile(log *zap.Logger, edit func(content []byte) ([]byte, error)) (string, error) {
+	envFile, err := environment.NearestFile()
+	if err != nil {
+		log.Error("Unable to determine the nearest environment file.", zap.Error(err))
+		return "", err
+	}
+	log = log.With(zap.String("env-file", envFile))
+
+	content, err := os.ReadFile(envFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Error("Failed to read environment file.", zap.Error(err))
+		return "", err
+	}
+	edited, err := edit(content)
+	if err != nil {
+		log.Error("Failed to edit environment file.", zap.Error(err))
+		return "", err
+	}
+	if err = environment.WriteFile(envFile, edited); err != nil {
+		log.Error("Failed to write environment file.", zap.Error(err))
+		return "", err
+	}
+	return envFile, nil
+}
@@ -0,0 +1,115 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/backend"
+	"github.com/Helcaraxan/toolshare/internal/config"
+)
+
+const defaultReleasesTTL = time.Hour
+
+// releaseListing records the releases listed by a tool's source. Caching it allows for version constraints, and
+// 'latest' pins in particular, to be resolved without hitting the network on every invocation of the tool.
+type releaseListing struct {
+	Source   string            `json:"source"`
+	ListedAt time.Time         `json:"listed_at"`
+	Releases []backend.Release `json:"releases"`
+}
+
+// toolReleases returns the releases listed by the tool's source. Listings are cached on disk for the configured TTL.
+// When the source can not be reached a previous listing is used regardless of its age.
+func (c *CommonOpts) toolReleases(ctx context.Context, log *zap.Logger, tool string, source backend.Storage, lister backend.ReleaseLister) ([]backend.Release, error) {
+	path := filepath.Join(config.ReleasesDir(), tool+".yaml")
+	log = log.With(zap.String("releases-file", path))
+
+	ttl := c.Config.ReleasesTTL
+	if ttl == 0 {
+		ttl = defaultReleasesTTL
+	}
+
+	cached, err := readReleaseListing(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn("Ignoring unreadable cached releases.", zap.Error(err))
+	}
+	if cached != nil && cached.Source != source.String() {
+		log.Debug("Ignoring cached releases of a different source.", zap.String("cached-source", cached.Source))
+		cached = nil
+	}
+	if cached != nil && time.Since(cached.ListedAt) < ttl {
+		log.Debug("Using cached releases.", zap.Time("listed-at", cached.ListedAt))
+		return cached.Releases, nil
+	}
+
+	releases, err := lister.Releases(ctx)
+	if err != nil {
+		if cached != nil {
+			log.Warn("Failed to list the releases of the tool's source. Using previously listed releases.", zap.Time("listed-at", cached.ListedAt), zap.Error(err))
+			return cached.Releases, nil
+		}
+		log.Error("Failed to list the releases of the tool's source.", zap.Error(err))
+		return nil, err
+	}
+
+	listing := &releaseListing{Source: source.String(), ListedAt: time.Now().UTC(), Releases: releases}
+	if err = writeReleaseListing(path, listing); err != nil {
+		log.Warn("Failed to cache the releases of the tool's source.", zap.Error(err))
+	}
+	return releases, nil
+}
+
+// releaseVersions returns the versions of the releases together with a function that reports whether a version is a
+// pre-release according to the source that listed it.
+func releaseVersions(releases []backend.Release) ([]string, func(string) bool) {
+	versions := make([]string, 0, len(releases))
+	prereleases := map[string]bool{}
+	for _, r := range releases {
+		versions = append(versions, r.Version)
+		prereleases[r.Version] = r.Prerelease
+	}
+	return versions, func(v string) bool { return prereleases[v] }
+}
+
+func readReleaseListing(path string) (*releaseListing, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var listing releaseListing
+	if err = yaml.Unmarshal(raw, &listing); err != nil {
+		return nil, err
+	}
+	return &listing, nil
+}
+
+// writeReleaseListing atomically replaces the cached listing so that concurrent invocations never read a partial one.
+func writeReleaseListing(path string, listing *releaseListing) error {
+	raw, err := yaml.Marshal(listing)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(raw); err != nil {
+		_ = tmp.Close()
+		return err
+	} else if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
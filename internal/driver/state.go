@@ -0,0 +1,206 @@
+package driver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/logger"
+	"github.com/Helcaraxan/toolshare/internal/state"
+)
+
+func State(cOpts *CommonOpts) *cobra.Command {
+	opts := &stateOptions{
+		CommonOpts: cOpts,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Manage the tool versions recorded in the configured state.",
+		Long: `Inspect and modify the versions of tools that are made available and recommended by the state
+designated in the configuration. Modifications are applied directly to the remote state and are
+subsequently picked up by all users of that state when their local state cache is refreshed.`,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "add <tool> <version> [<version>...]",
+			Short: "Make one or more versions of a tool available via the state.",
+			Args:  cobra.MinimumNArgs(2),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return opts.add(args[0], args[1:])
+			},
+		},
+		&cobra.Command{
+			Use:   "remove <tool> <version> [<version>...]",
+			Short: "Remove one or more versions of a tool from the state.",
+			Args:  cobra.MinimumNArgs(2),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return opts.remove(args[0], args[1:])
+			},
+		},
+		&cobra.Command{
+			Use:   "recommend <tool> <version>",
+			Short: "Recommend a version of a tool that is available via the state.",
+			Long: `Recommend a version of a tool that is available via the state. The recommended version is used in
+environments that do not pin the tool, unless the configuration forces pinning.`,
+			Args: cobra.ExactArgs(2),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return opts.recommend(args[0], args[1])
+			},
+		},
+		&cobra.Command{
+			Use:   "show [<tool>...]",
+			Short: "Show the current content of the state for all or only the specified tools.",
+			RunE: func(_ *cobra.Command, args []string) error {
+				return opts.show(args)
+			},
+		},
+	)
+
+	return cmd
+}
+
+type stateOptions struct {
+	*CommonOpts
+}
+
+func (o *stateOptions) add(tool string, versions []string) error {
+	log := o.Log.With(zap.String("tool-name", tool), zap.Strings("versions", versions))
+
+	remote, err := o.remoteState()
+	if err != nil {
+		return err
+	}
+
+	if err = remote.AddVersions(toBinaries(tool, versions)...); err != nil {
+		log.Error("Failed to add versions to the state.", zap.Error(err))
+		return err
+	}
+	log.Info("Added versions to the state.")
+	o.refreshCache()
+	return nil
+}
+
+func (o *stateOptions) remove(tool string, versions []string) error {
+	log := o.Log.With(zap.String("tool-name", tool), zap.Strings("versions", versions))
+
+	remote, err := o.remoteState()
+	if err != nil {
+		return err
+	}
+
+	if err = remote.DeleteVersions(toBinaries(tool, versions)...); err != nil {
+		log.Error("Failed to remove versions from the state.", zap.Error(err))
+		return err
+	}
+	log.Info("Removed versions from the state.")
+	o.refreshCache()
+	return nil
+}
+
+func (o *stateOptions) recommend(tool string, version string) error {
+	log := o.Log.With(zap.String("tool-name", tool), zap.String("version", version))
+
+	remote, err := o.remoteState()
+	if err != nil {
+		return err
+	}
+
+	if err = remote.RecommendVersion(config.Binary{Tool: tool, Version: version}); err != nil {
+		log.Error("Failed to recommend version in the state.", zap.Error(err))
+		return err
+	}
+	log.Info("Recommended version in the state.")
+	o.refreshCache()
+	return nil
+}
+
+func (o *stateOptions) show(tools []string) error {
+	remote, err := o.remoteState()
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := state.Snapshot(o.Log, remote)
+	if err != nil {
+		o.Log.Error("Failed to retrieve the content of the state.", zap.Error(err))
+		return err
+	}
+
+	if len(tools) == 0 {
+		if tools, err = snapshot.AvailableTools(); err != nil {
+			o.Log.Error("Failed to list the tools in the state.", zap.Error(err))
+			return err
+		}
+		if len(tools) == 0 {
+			fmt.Println("The state does not contain any tools.")
+			return nil
+		}
+	}
+	sort.Strings(tools)
+
+	for _, tool := range tools {
+		log := o.Log.With(zap.String("tool-name", tool))
+
+		versions, err := snapshot.AvailableVersions(tool)
+		if err != nil {
+			log.Error("Failed to retrieve available versions from the state.", zap.Error(err))
+			return err
+		}
+		recommended, err := snapshot.RecommendedVersion(tool)
+		if err != nil {
+			log.Error("Failed to retrieve the recommended version from the state.", zap.Error(err))
+			return err
+		}
+		denied, err := snapshot.DeniedVersions(tool)
+		if err != nil {
+			log.Error("Failed to retrieve the denied versions from the state.", zap.Error(err))
+			return err
+		}
+
+		newestFirst := make([]string, 0, len(versions))
+		for idx := len(versions) - 1; idx >= 0; idx-- {
+			newestFirst = append(newestFirst, versions[idx])
+		}
+		if recommended == "" {
+			recommended = "<none>"
+		}
+
+		fmt.Println(tool)
+		fmt.Printf("  recommended: %s\n", recommended)
+		fmt.Printf("  versions:    %s\n", strings.Join(newestFirst, ", "))
+		for _, d := range denied {
+			fmt.Printf("  denied:      %s (%s)\n", d.Version, d.Reason)
+		}
+	}
+	return nil
+}
+
+func (o *stateOptions) remoteState() (state.State, error) {
+	if o.Config.State == nil {
+		o.Log.Error("No state is configured.")
+		return nil, ErrNoState
+	}
+	return state.New(o.LogBuilder.Domain(logger.StateDomain), o.Config.State)
+}
+
+// refreshCache ensures that modifications of the remote state are immediately reflected in the local state cache.
+func (o *stateOptions) refreshCache() {
+	if err := o.State.Refresh(true); err != nil {
+		o.Log.Warn("Failed to refresh the local state cache. It will be updated on the next refresh.", zap.Error(err))
+	}
+}
+
+func toBinaries(tool string, versions []string) []config.Binary {
+	binaries := make([]config.Binary, 0, len(versions))
+	for _, version := range versions {
+		binaries = append(binaries, config.Binary{Tool: tool, Version: version})
+	}
+	return binaries
+}
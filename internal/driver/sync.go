@@ -1,6 +1,7 @@
 package driver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -31,8 +32,10 @@ func Sync(cOpts *CommonOpts) *cobra.Command {
 versions according to the current environment. If the subscription folder is appropriately placed at
 the start of your $PATH environment variable this ensures that tools can be directly invoked at the
 configured version as if they were installed directly in your $PATH.`,
-		RunE: func(_ *cobra.Command, _ []string) error {
-			return opts.sync()
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx, cancel := opts.withDeadline(cmd.Context())
+			defer cancel()
+			return opts.sync(ctx)
 		},
 	}
 
@@ -63,7 +66,7 @@ type syncOptions struct {
 	tools []string
 }
 
-func (o *syncOptions) sync() error {
+func (o *syncOptions) sync(ctx context.Context) error {
 	log := o.Log.With(zap.String("mode", o.mode))
 
 	switch o.mode {
@@ -75,8 +78,10 @@ func (o *syncOptions) sync() error {
 	}
 
 	if len(o.tools) == 0 {
-		for name := range o.Env {
-			o.tools = append(o.tools, name)
+		for name, reg := range o.Env {
+			if !reg.Recommended {
+				o.tools = append(o.tools, name)
+			}
 		}
 		log.Info("No tools were specified. Syncing all tools registered in the current environment.")
 	}
@@ -101,14 +106,18 @@ func (o *syncOptions) sync() error {
 
 	log.Debug("Downloading binaries for tools to sync.")
 	for _, name := range o.tools {
+		version, err := o.toolVersion(ctx, log.With(zap.String("tool-name", name)), name)
+		if err != nil {
+			return err
+		}
 		dl := &downloadOptions{
 			CommonOpts: o.CommonOpts,
 			tool:       name,
-			version:    o.Env[name].Version,
+			version:    version,
 			platforms:  []string{string(config.CurrentPlatform())},
 			archs:      []string{string(config.CurrentArch())},
 		}
-		if err := dl.download(); err != nil {
+		if err := dl.download(ctx); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,194 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/backend"
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/environment"
+	"github.com/Helcaraxan/toolshare/internal/version"
+)
+
+func Upgrade(cOpts *CommonOpts) *cobra.Command {
+	opts := &upgradeOptions{
+		CommonOpts: cOpts,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "upgrade [tool...] [--dry-run]",
+		Short: "Upgrade the versions at which tools are pinned in environment files.",
+		Long: fmt.Sprintf(`Upgrade the pins of the given tools, or of all tools pinned to an exact version in the current
+environment, to the version recommended by the state or, if there is none, to the newest release of
+the tool's source that is neither a pre-release nor denied by the state. Each pin is rewritten in the
+environment file that defines it. Everything else in the file, including comments, is left untouched.
+
+Tools pinned with a version constraint, such as '^1.64' or 'latest', are not upgraded as they are
+resolved to newer versions automatically. Re-run '%s lock' after upgrading tools that are locked.`, config.DriverName),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.tools = args
+			ctx, cancel := opts.withDeadline(cmd.Context())
+			defer cancel()
+			return opts.upgrade(ctx)
+		},
+	}
+
+	registerUpgradeFlags(cmd, opts)
+
+	return cmd
+}
+
+func registerUpgradeFlags(cmd *cobra.Command, opts *upgradeOptions) {
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Print the changes to environment files as a unified diff instead of applying them.")
+}
+
+type upgradeOptions struct {
+	*CommonOpts
+
+	tools  []string
+	dryRun bool
+}
+
+func (o *upgradeOptions) upgrade(ctx context.Context) error {
+	if len(o.tools) == 0 {
+		for name, reg := range o.Env {
+			if reg.Version != "" && reg.Constraint == nil && !reg.Recommended {
+				o.tools = append(o.tools, name)
+			}
+		}
+	}
+	sort.Strings(o.tools)
+
+	// Upgraded versions are indexed by the environment file that pins the tool.
+	upgrades := map[string]map[string]string{}
+	var errs []error
+	for _, name := range o.tools {
+		log := o.Log.With(zap.String("tool-name", name))
+
+		reg, ok := o.Env[name]
+		switch {
+		case reg.Constraint != nil:
+			log.Info("Tool is pinned with a version constraint. Skipping it.", zap.Stringer("constraint", reg.Constraint))
+			continue
+		case !ok || reg.Version == "" || reg.Recommended:
+			log.Error("Tool is not pinned in the current environment.")
+			errs = append(errs, fmt.Errorf("%w: %s", ErrUnknownTool, name))
+			continue
+		}
+
+		newest, err := o.newestVersion(ctx, log, name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if version.Compare(newest, reg.Version) <= 0 {
+			log.Info("Tool is up-to-date.", zap.String("version", reg.Version))
+			continue
+		}
+		// Retain the style of the existing pin as versions listed by sources never have a 'v' prefix.
+		if strings.HasPrefix(reg.Version, "v") && !strings.HasPrefix(newest, "v") {
+			newest = "v" + newest
+		}
+
+		log.Info("Upgrading tool.", zap.String("from", reg.Version), zap.String("to", newest), zap.String("env-file", reg.VersionFile))
+		if upgrades[reg.VersionFile] == nil {
+			upgrades[reg.VersionFile] = map[string]string{}
+		}
+		upgrades[reg.VersionFile][name] = newest
+	}
+	if len(errs) > 0 {
+		o.Log.Error("Failed to upgrade some tools. Leaving the environment files untouched.")
+		return fmt.Errorf("failed to upgrade some tools: %w", errors.Join(errs...))
+	}
+
+	envFiles := make([]string, 0, len(upgrades))
+	for envFile := range upgrades {
+		envFiles = append(envFiles, envFile)
+	}
+	sort.Strings(envFiles)
+
+	for _, envFile := range envFiles {
+		if err := o.rewritePins(o.Log.With(zap.String("env-file", envFile)), envFile, upgrades[envFile]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newestVersion returns the version recommended by the state, if any, or otherwise the newest release of the tool's
+// source that is neither a pre-release nor denied by the state.
+func (o *upgradeOptions) newestVersion(ctx context.Context, log *zap.Logger, tool string) (string, error) {
+	if o.State != nil {
+		recommended, err := o.State.RecommendedVersion(tool)
+		if err == nil && recommended != "" {
+			return recommended, nil
+		}
+		log.Debug("No recommended version available in the state.", zap.Error(err))
+	}
+
+	lister, ok := o.toolSource(tool).(backend.ReleaseLister)
+	if !ok {
+		log.Error("The state does not recommend a version and the tool's source does not list releases.")
+		return "", fmt.Errorf("%w: %s", ErrUnknownNewestVersion, tool)
+	}
+	releases, err := lister.Releases(ctx)
+	if err != nil {
+		log.Error("Failed to list the releases of the tool's source.", zap.Error(err))
+		return "", err
+	}
+
+	latest, err := version.ParseConstraint(version.Latest)
+	if err != nil {
+		return "", err
+	}
+	versions, isPrerelease := releaseVersions(releases)
+	newest := environment.ResolveConstraint(log, o.State, tool, latest, versions, isPrerelease)
+	if newest == "" {
+		log.Error("The tool's source does not list any suitable release.")
+		return "", fmt.Errorf("%w: %s", ErrUnknownNewestVersion, tool)
+	}
+	return newest, nil
+}
+
+func (o *upgradeOptions) rewritePins(log *zap.Logger, envFile string, pins map[string]string) error {
+	content, err := os.ReadFile(envFile)
+	if err != nil {
+		log.Error("Failed to read environment file.", zap.Error(err))
+		return err
+	}
+	edited, err := environment.SetPins(content, pins)
+	if err != nil {
+		log.Error("Failed to rewrite pins in environment file.", zap.Error(err))
+		return err
+	}
+
+	if o.dryRun {
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(content)),
+			B:        difflib.SplitLines(string(edited)),
+			FromFile: envFile,
+			ToFile:   envFile,
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Print(diff)
+		return nil
+	}
+
+	if err = environment.WriteFile(envFile, edited); err != nil {
+		log.Error("Failed to write environment file.", zap.Error(err))
+		return err
+	}
+	log.Info("Successfully upgraded pins in environment file.")
+	return nil
+}
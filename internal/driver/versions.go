@@ -1,7 +1,10 @@
 package driver
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 )
 
 func Versions(cOpts *CommonOpts) *cobra.Command {
@@ -10,10 +13,13 @@ func Versions(cOpts *CommonOpts) *cobra.Command {
 	}
 
 	cmd := &cobra.Command{
-		Use:     "versions <tool> [--count=<n>]",
+		Use:     "versions <tool> [--count=<n>] [--refresh]",
 		Aliases: []string{"list-versions"},
 		Short:   "List the versions available for a config.",
-		Args:    cobra.ExactArgs(1),
+		Long: `List the versions of a tool that are available according to the configured state, starting with the
+most recent one. The version recommended by the state is marked as such and is always listed, even
+if it falls outside of the requested number of versions.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(_ *cobra.Command, args []string) error {
 			opts.tool = args[0]
 			return opts.versions()
@@ -27,16 +33,70 @@ func Versions(cOpts *CommonOpts) *cobra.Command {
 
 func registerVersionFlags(cmd *cobra.Command, opts *versionOpts) {
 	cmd.Flags().IntVar(&opts.count, "count", 10, "Number of versions to list. The default version will always be printed.")
+	cmd.Flags().BoolVar(&opts.refresh, "refresh", false, "Refresh the local state cache before listing versions, regardless of when it was last refreshed.")
 }
 
 type versionOpts struct {
 	*CommonOpts
 
-	tool  string
-	count int
+	tool    string
+	count   int
+	refresh bool
 }
 
 func (o *versionOpts) versions() error {
-	// TODO - requires the use of state.
-	return ErrUnimplemented
+	log := o.Log.With(zap.String("tool-name", o.tool))
+
+	if o.State == nil {
+		log.Error("No state is configured. Unable to determine available versions.")
+		return ErrNoState
+	}
+
+	if err := o.State.Refresh(o.refresh); err != nil {
+		log.Warn("Failed to refresh the state cache. Available versions may be outdated.", zap.Error(err))
+	}
+
+	versions, err := o.State.AvailableVersions(o.tool)
+	if err != nil {
+		log.Error("Failed to retrieve available versions from the state.", zap.Error(err))
+		return err
+	}
+	recommended, err := o.State.RecommendedVersion(o.tool)
+	if err != nil {
+		log.Error("Failed to retrieve the recommended version from the state.", zap.Error(err))
+		return err
+	}
+	denied, err := o.State.DeniedVersions(o.tool)
+	if err != nil {
+		log.Error("Failed to retrieve the denied versions from the state.", zap.Error(err))
+		return err
+	}
+	denyReasons := map[string]string{}
+	for _, d := range denied {
+		denyReasons[d.Version] = d.Reason
+	}
+
+	if len(versions) == 0 {
+		fmt.Printf("No versions of %q are available.\n", o.tool)
+		return nil
+	}
+
+	var printedRecommended bool
+	for idx := len(versions) - 1; idx >= 0; idx-- {
+		v := versions[idx]
+		listed := o.count <= 0 || len(versions)-idx <= o.count
+		switch reason, isDenied := denyReasons[v]; {
+		case v == recommended:
+			fmt.Printf("%s (recommended)\n", v)
+			printedRecommended = true
+		case listed && isDenied:
+			fmt.Printf("%s (denied: %s)\n", v, reason)
+		case listed:
+			fmt.Println(v)
+		}
+	}
+	if recommended != "" && !printedRecommended {
+		fmt.Printf("%s (recommended)\n", recommended)
+	}
+	return nil
 }
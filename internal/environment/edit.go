@@ -0,0 +1,256 @@
+package environment
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+var ErrUneditableEnvironment = errors.New("environment file can not be edited")
+
+// plainScalar matches versions that can be written without quotes without changing their meaning.
+var plainScalar = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+
+// SetPins returns the content of an environment file in which the given tools are pinned to the given versions. The
+// file's syntax tree is only used to locate the existing pins, which are then replaced in the original content so that
+// everything else, including comments and the quoting of each pin, is preserved byte-for-byte.
+func SetPins(content []byte, pins map[string]string) ([]byte, error) {
+	f, err := parser.ParseBytes(content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	entries := pinEntries(f)
+
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	for tool, v := range pins {
+		entry, ok := entries[tool]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not pinned", ErrUneditableEnvironment, tool)
+		}
+		if err = replaceScalar(lines, entry.GetToken(), v); err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrUneditableEnvironment, tool, err)
+		}
+	}
+	return bytes.Join(lines, nil), nil
+}
+
+// AddPin returns the content of an environment file in which the tool is pinned to the given version. An existing pin is
+// replaced in place whereas a new one is appended to the 'pins' mapping, which is created if necessary.
+func AddPin(content []byte, tool string, v string) ([]byte, error) {
+	f, err := parser.ParseBytes(content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := pinEntries(f)[tool]; ok {
+		return SetPins(content, map[string]string{tool: v})
+	}
+	return insertEntry(f, content, "pins", yaml.MapItem{Key: tool, Value: v})
+}
+
+// AddSource returns the content of an environment file to which the source of the tool has been added. The file may not
+// yet define a source for the tool.
+func AddSource(content []byte, tool string, source *Source) ([]byte, error) {
+	f, err := parser.ParseBytes(content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if mv := topLevelEntry(f, "sources"); mv != nil {
+		for _, entry := range mappingValues(mv.Value) {
+			if entry.Key.String() == tool {
+				return nil, fmt.Errorf("%w: a source for %q already exists", ErrUneditableEnvironment, tool)
+			}
+		}
+	}
+	return insertEntry(f, content, "sources", yaml.MapItem{Key: tool, Value: source})
+}
+
+// insertEntry appends an entry to the top-level mapping with the given key, directly after the last line of its existing
+// entries and at the same indentation. The mapping is appended to the content if it does not exist yet.
+func insertEntry(f *ast.File, content []byte, key string, entry yaml.MapItem) ([]byte, error) {
+	raw, err := yaml.Marshal(yaml.MapSlice{entry})
+	if err != nil {
+		return nil, err
+	}
+
+	var edited []byte
+	if mv := topLevelEntry(f, key); mv == nil {
+		edited = append(edited, content...)
+		if len(edited) > 0 && !bytes.HasSuffix(edited, []byte("\n")) {
+			edited = append(edited, '\n')
+		}
+		edited = append(edited, key+":\n"...)
+		edited = append(edited, indentLines(raw, "  ")...)
+	} else {
+		keyIndent := mv.Key.GetToken().Position.Column - 1
+		entryIndent := keyIndent + 2
+		switch m := mv.Value.(type) {
+		case *ast.NullNode:
+		case *ast.MappingNode:
+			if m.IsFlowStyle {
+				return nil, fmt.Errorf("%w: %q is a flow mapping", ErrUneditableEnvironment, key)
+			}
+			entryIndent = m.Values[0].Key.GetToken().Position.Column - 1
+		case *ast.MappingValueNode:
+			entryIndent = m.Key.GetToken().Position.Column - 1
+		default:
+			return nil, fmt.Errorf("%w: %q is not a mapping", ErrUneditableEnvironment, key)
+		}
+
+		// The existing entries span all subsequent lines that are indented further than the key. Blank lines and
+		// comments are skipped so that the new entry is not separated from the existing ones.
+		lines := bytes.SplitAfter(content, []byte("\n"))
+		last := mv.Key.GetToken().Position.Line - 1
+		for idx := last + 1; idx < len(lines); idx++ {
+			trimmed := bytes.TrimLeft(lines[idx], " ")
+			if len(bytes.TrimSpace(trimmed)) == 0 || trimmed[0] == '#' {
+				continue
+			}
+			if len(lines[idx])-len(trimmed) <= keyIndent {
+				break
+			}
+			last = idx
+		}
+
+		for idx, line := range lines {
+			edited = append(edited, line...)
+			if idx == last {
+				if !bytes.HasSuffix(line, []byte("\n")) {
+					edited = append(edited, '\n')
+				}
+				edited = append(edited, indentLines(raw, strings.Repeat(" ", entryIndent))...)
+			}
+		}
+	}
+
+	// Guard against content whose layout resulted in the entry being inserted at an invalid position.
+	if _, err = parser.ParseBytes(edited, 0); err != nil {
+		return nil, fmt.Errorf("%w: adding %q results in invalid content: %w", ErrUneditableEnvironment, key, err)
+	}
+	return edited, nil
+}
+
+func indentLines(raw []byte, prefix string) []byte {
+	var indented []byte
+	for _, line := range bytes.SplitAfter(raw, []byte("\n")) {
+		if len(line) > 0 {
+			indented = append(indented, prefix...)
+			indented = append(indented, line...)
+		}
+	}
+	return indented
+}
+
+// pinEntries returns the value nodes of the 'pins' mapping of the first document that has one.
+func pinEntries(f *ast.File) map[string]ast.Node {
+	mv := topLevelEntry(f, "pins")
+	if mv == nil {
+		return nil
+	}
+	entries := map[string]ast.Node{}
+	for _, pin := range mappingValues(mv.Value) {
+		entries[pin.Key.String()] = pin.Value
+	}
+	return entries
+}
+
+// topLevelEntry returns the top-level entry with the given key of the first document that has one.
+func topLevelEntry(f *ast.File, key string) *ast.MappingValueNode {
+	for _, doc := range f.Docs {
+		for _, mv := range mappingValues(doc.Body) {
+			if mv.Key.String() == key {
+				return mv
+			}
+		}
+	}
+	return nil
+}
+
+func mappingValues(n ast.Node) []*ast.MappingValueNode {
+	switch m := n.(type) {
+	case *ast.MappingNode:
+		return m.Values
+	case *ast.MappingValueNode:
+		return []*ast.MappingValueNode{m}
+	default:
+		return nil
+	}
+}
+
+// replaceScalar replaces the scalar of the given token with the version, retaining the scalar's quoting style.
+func replaceScalar(lines [][]byte, tk *token.Token, v string) error {
+	switch tk.Type {
+	case token.StringType, token.DoubleQuoteType, token.SingleQuoteType, token.IntegerType, token.FloatType:
+	default:
+		return fmt.Errorf("pin at line %d is not a scalar", tk.Position.Line)
+	}
+	if tk.Position.Line < 1 || tk.Position.Line > len(lines) {
+		return fmt.Errorf("pin at line %d is out of range", tk.Position.Line)
+	}
+	line := lines[tk.Position.Line-1]
+
+	// Token columns count characters rather than bytes.
+	offset := 0
+	for col := 1; col < tk.Position.Column && offset < len(line); col++ {
+		_, size := utf8.DecodeRune(line[offset:])
+		offset += size
+	}
+	origin := strings.TrimSpace(tk.Origin)
+	if !bytes.HasPrefix(line[offset:], []byte(origin)) {
+		return fmt.Errorf("pin at line %d spans multiple lines", tk.Position.Line)
+	}
+
+	var replacement string
+	switch {
+	case tk.Type == token.SingleQuoteType:
+		replacement = "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case tk.Type == token.DoubleQuoteType || !plainScalar.MatchString(v):
+		replacement = strconv.Quote(v)
+	default:
+		replacement = v
+	}
+
+	edited := append([]byte{}, line[:offset]...)
+	edited = append(edited, replacement...)
+	lines[tk.Position.Line-1] = append(edited, line[offset+len(origin):]...)
+	return nil
+}
+
+// WriteFile atomically replaces the content of a file while retaining its permissions. Files that do not exist yet are
+// created.
+func WriteFile(path string, content []byte) error {
+	mode := fs.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	} else if err = tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		return err
+	} else if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
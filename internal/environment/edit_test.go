@@ -0,0 +1,152 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Helcaraxan/toolshare/internal/backend"
+	"github.com/Helcaraxan/toolshare/internal/config"
+)
+
+func TestSetPins(t *testing.T) {
+	t.Parallel()
+
+	content := []byte(`# yaml-language-server: $schema=../environment.schema.json
+---
+# Tools used throughout the repository.
+pins:
+  kubectl: "1.20.1" # Keep in sync with the cluster.
+  gofumpt: 0.7.0
+
+  helm: 'v3.16.2'
+  terraform: 1.9
+  golangci-lint: ^1.64
+
+sources:
+  kubectl:
+    github_slug: kubernetes/kubectl
+`)
+
+	edited, err := SetPins(content, map[string]string{
+		"kubectl":   "1.31.0",
+		"gofumpt":   "0.8.0",
+		"helm":      "v3.17.0",
+		"terraform": "1.10.2",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `# yaml-language-server: $schema=../environment.schema.json
+---
+# Tools used throughout the repository.
+pins:
+  kubectl: "1.31.0" # Keep in sync with the cluster.
+  gofumpt: 0.8.0
+
+  helm: 'v3.17.0'
+  terraform: 1.10.2
+  golangci-lint: ^1.64
+
+sources:
+  kubectl:
+    github_slug: kubernetes/kubectl
+`, string(edited))
+
+	edited, err = SetPins([]byte("pins: {a: 1.0.0, b: 2.0.0}\n"), map[string]string{"b": "2.1.0+build"})
+	require.NoError(t, err)
+	assert.Equal(t, "pins: {a: 1.0.0, b: 2.1.0+build}\n", string(edited))
+
+	_, err = SetPins(content, map[string]string{"missing": "1.0.0"})
+	require.ErrorIs(t, err, ErrUneditableEnvironment)
+
+	_, err = SetPins([]byte("pins:\n  a:\n    nested: 1.0.0\n"), map[string]string{"a": "1.0.0"})
+	require.ErrorIs(t, err, ErrUneditableEnvironment)
+}
+
+func TestAddPin(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		content  string
+		expected string
+	}{
+		"Empty": {
+			content:  "",
+			expected: "pins:\n  gh: 2.63.0\n",
+		},
+		"NoPins": {
+			content:  "# yaml-language-server: $schema=environment.schema.json\nsources:\n  gh:\n    github_slug: cli/cli\n",
+			expected: "# yaml-language-server: $schema=environment.schema.json\nsources:\n  gh:\n    github_slug: cli/cli\npins:\n  gh: 2.63.0\n",
+		},
+		"EmptyPins": {
+			content:  "pins:\nsources: {}\n",
+			expected: "pins:\n  gh: 2.63.0\nsources: {}\n",
+		},
+		"Existing": {
+			content:  "pins:\n  gh: \"2.62.0\" # Latest.\n",
+			expected: "pins:\n  gh: \"2.63.0\" # Latest.\n",
+		},
+		"Append": {
+			content:  "pins:\n    kubectl: 1.31.0\n    # Linters.\n    golangci-lint: ^1.64\n\n# Sources of tools.\nsources: {}\n",
+			expected: "pins:\n    kubectl: 1.31.0\n    # Linters.\n    golangci-lint: ^1.64\n    gh: 2.63.0\n\n# Sources of tools.\nsources: {}\n",
+		},
+		"NoTrailingNewline": {
+			content:  "pins:\n  kubectl: 1.31.0",
+			expected: "pins:\n  kubectl: 1.31.0\n  gh: 2.63.0\n",
+		},
+	}
+
+	for name, testcase := range testcases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			edited, err := AddPin([]byte(testcase.content), "gh", "2.63.0")
+			require.NoError(t, err)
+			assert.Equal(t, testcase.expected, string(edited))
+		})
+	}
+
+	edited, err := AddPin([]byte("pins:\n  a: 1.0.0\n"), "terraform", "1.10")
+	require.NoError(t, err)
+	assert.Equal(t, "pins:\n  a: 1.0.0\n  terraform: \"1.10\"\n", string(edited))
+
+	_, err = AddPin([]byte("pins: {a: 1.0.0}\n"), "gh", "2.63.0")
+	require.ErrorIs(t, err, ErrUneditableEnvironment)
+}
+
+func TestAddSource(t *testing.T) {
+	t.Parallel()
+
+	linux := "Linux"
+	source := &Source{GitHubConfig: &backend.GitHubConfig{
+		CommonConfig: backend.CommonConfig{
+			ArchivePathTemplate: "**/{tool}{exe}",
+			Mappings:            backend.TemplateMappings{Linux: &linux},
+		},
+		GitHubSlug:                 "cli/cli",
+		GitHubReleaseAssetTemplate: "gh_{version}_{platform}_{arch}.tar.gz",
+	}}
+
+	content := []byte("pins:\n  gh: 2.63.0\nsources:\n  kubectl:\n    https_url_template: https://dl.k8s.io/{version}/kubectl\n")
+	edited, err := AddSource(content, "gh", source)
+	require.NoError(t, err)
+	assert.Equal(t, `pins:
+  gh: 2.63.0
+sources:
+  kubectl:
+    https_url_template: https://dl.k8s.io/{version}/kubectl
+  gh:
+    github_slug: cli/cli
+    github_release_asset_template: gh_{version}_{platform}_{arch}.tar.gz
+    archive_path_template: "**/{tool}{exe}"
+    template_mappings:
+      linux: Linux
+`, string(edited))
+
+	env := Environment{}
+	require.NoError(t, mergeEnvironment(&config.Global{}, env, "test", edited))
+	assert.Equal(t, source, env["gh"].Source)
+
+	_, err = AddSource(edited, "gh", source)
+	require.ErrorIs(t, err, ErrUneditableEnvironment)
+}
@@ -4,19 +4,26 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/goccy/go-yaml"
+	"go.uber.org/zap"
 
 	"github.com/Helcaraxan/toolshare/internal/backend"
 	"github.com/Helcaraxan/toolshare/internal/config"
 	"github.com/Helcaraxan/toolshare/internal/logger"
+	"github.com/Helcaraxan/toolshare/internal/state"
+	"github.com/Helcaraxan/toolshare/internal/version"
 )
 
 type environmentSpec struct {
-	Pins    map[string]string  `json:"pins"`
-	Sources map[string]*Source `json:"sources"`
+	Pins      map[string]string                       `json:"pins"`
+	Sources   map[string]*Source                      `json:"sources"`
+	Checksums map[string]map[string]map[string]string `json:"checksums"`
 }
 
 type Environment map[string]ToolRegistration
@@ -26,28 +33,42 @@ type ToolRegistration struct {
 	SourceFile  string
 	Version     string
 	VersionFile string
+	Lock        *LockedTool
+	LockFile    string
+
+	// Constraint combines the version constraints with which the tool is pinned by environment files. Version is then
+	// the newest version that satisfies it, once resolved.
+	Constraint *version.Constraint
+
+	// Recommended is set when the tool is not pinned and its version is instead the one recommended by the state.
+	Recommended bool
+
+	// Expected SHA-256 digests of the tool's binaries indexed by version and then by '<platform>/<arch>'.
+	Checksums map[string]map[string]string
+}
+
+// Checksum returns the expected hex-encoded SHA-256 digest of the given binary if one has been recorded in the
+// environment, or an empty string otherwise.
+func (r ToolRegistration) Checksum(b config.Binary) string {
+	return r.Checksums[b.Version][checksumKey(b)]
+}
+
+func checksumKey(b config.Binary) string {
+	return fmt.Sprintf("%s/%s", b.Platform, b.Arch)
 }
 
-func GetEnvironment(conf *config.Global, env Environment) error {
-	envFileName := fmt.Sprintf("%s.yaml", config.DriverName)
+var ErrNoEnvironmentFile = errors.New("no environment file found")
 
-	cwd, err := os.Getwd()
+// GetEnvironment merges the environment files, and their lock files, that apply to the current working directory into
+// the environment. Tools that are not pinned, or pinned with a version constraint, are then resolved against the state,
+// if any. When tools are specified only these are resolved against the state, which avoids consulting the state about
+// tools that are not going to be used.
+func GetEnvironment(log *zap.Logger, conf *config.Global, cache state.Cache, env Environment, tools ...string) error {
+	candidatePaths, err := candidateFiles()
 	if err != nil {
 		return err
 	}
 
-	var candidatePaths []string
-	for {
-		candidatePaths = append(candidatePaths, filepath.Join(cwd, "."+envFileName))
-		if cwd == filepath.Dir(cwd) {
-			break
-		}
-		cwd = filepath.Dir(cwd)
-	}
-	for _, p := range config.AllDirs() {
-		candidatePaths = append(candidatePaths, filepath.Join(p, envFileName))
-	}
-
 	for _, p := range candidatePaths {
 		var raw []byte
 		raw, err = os.ReadFile(p)
@@ -60,14 +81,249 @@ func GetEnvironment(conf *config.Global, env Environment) error {
 		if err = mergeEnvironment(conf, env, p, raw); err != nil {
 			return err
 		}
-	}
 
-	if !conf.ForcePinned && conf.State != nil { //nolint: staticcheck // Requires further implementation
-		// TODO.
+		var lock *Lock
+		lock, err = ReadLock(LockFilePath(p))
+		if err != nil {
+			return err
+		}
+		mergeLock(env, LockFilePath(p), lock)
 	}
+
+	ResolveState(log, cache, env, tools...)
 	return nil
 }
 
+// ResolveState resolves tools that are not pinned to the version recommended by the state, if any, and tools that are
+// pinned with a version constraint to the newest satisfying version. Only the given tools are resolved, or all tools if
+// none are given.
+func ResolveState(log *zap.Logger, cache state.Cache, env Environment, tools ...string) {
+	if cache != nil {
+		mergeState(log, env, cache, tools)
+	}
+	resolveConstraints(log, env, cache, tools)
+}
+
+// resolveConstraints resolves tools that are pinned with a version constraint to the locked version if it satisfies the
+// constraint, or otherwise to the newest satisfying version known to the state. Tools that remain unresolved need to
+// be resolved against the releases listed by their source, as do those pinned to 'latest'. Only the given tools are
+// resolved, or all of them if none are given.
+func resolveConstraints(log *zap.Logger, env Environment, cache state.Cache, tools []string) {
+	for tool, r := range env {
+		if len(tools) > 0 && !slices.Contains(tools, tool) {
+			continue
+		}
+		if r.Constraint == nil || r.Constraint.Latest() || r.Version != "" {
+			continue
+		}
+		tLog := log.With(zap.String("tool-name", tool), zap.Stringer("constraint", r.Constraint))
+
+		if r.Lock != nil && r.Constraint.Check(r.Lock.Version) {
+			tLog.Debug("Resolved version constraint to the locked version.", zap.String("version", r.Lock.Version))
+			r.Version = r.Lock.Version
+		} else if cache != nil {
+			versions, err := cache.AvailableVersions(tool)
+			if err != nil {
+				tLog.Debug("No versions available in the state.", zap.Error(err))
+				continue
+			}
+			r.Version = ResolveConstraint(tLog, cache, tool, r.Constraint, versions, version.IsPrerelease)
+		}
+		env[tool] = r
+	}
+}
+
+// ResolveConstraint returns the newest of the candidate versions that satisfies the constraint and that is not denied
+// by the state, if any. It returns an empty string if there is no such version. Whether a candidate is a pre-release is
+// determined by isPrerelease.
+func ResolveConstraint(log *zap.Logger, cache state.Cache, tool string, constraint *version.Constraint, candidates []string, isPrerelease func(string) bool) string {
+	if cache != nil {
+		denied, err := cache.DeniedVersions(tool)
+		if err != nil {
+			log.Warn("Unable to determine which versions are denied by the state.", zap.Error(err))
+		}
+		allowed := make([]string, 0, len(candidates))
+		for _, v := range candidates {
+			if !slices.ContainsFunc(denied, func(d state.DeniedVersion) bool { return d.Version == v }) {
+				allowed = append(allowed, v)
+			}
+		}
+		candidates = allowed
+	}
+
+	resolved := constraint.HighestRelease(candidates, isPrerelease)
+	if resolved != "" {
+		log.Debug("Resolved version constraint.", zap.String("version", resolved))
+	}
+	return resolved
+}
+
+// mergeState resolves tools that are not pinned to the version recommended by the state. Whether such tools may be
+// used is up to the caller as it depends on the 'force_pinned' setting. Failures to consult the state are not fatal as
+// they should not prevent the use of pinned tools. Only the given tools are resolved, or all tools of the environment and
+// the state if none are given.
+func mergeState(log *zap.Logger, env Environment, cache state.Cache, tools []string) {
+	if len(tools) == 0 {
+		var err error
+		if tools, err = cache.AvailableTools(); err != nil {
+			log.Warn("Unable to list the tools available in the state.", zap.Error(err))
+		}
+		for tool := range env {
+			tools = append(tools, tool)
+		}
+	}
+
+	for _, tool := range tools {
+		r := env[tool]
+		if r.Version != "" || r.Constraint != nil {
+			continue
+		}
+
+		recommended, err := cache.RecommendedVersion(tool)
+		if err != nil || recommended == "" {
+			log.Debug("No recommended version available in the state.", zap.String("tool-name", tool), zap.Error(err))
+			continue
+		}
+		r.Version = recommended
+		r.Recommended = true
+		env[tool] = r
+	}
+}
+
+// LocalFile returns the path of the innermost existing environment file as seen from the current working directory. If
+// no environment file exists in the current directory or any of its parents the user and system-level ones are
+// considered as well.
+func LocalFile() (string, error) {
+	candidatePaths, err := candidateFiles()
+	if err != nil {
+		return "", err
+	}
+	for _, p := range candidatePaths {
+		if _, err = os.Stat(p); err == nil {
+			return p, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", ErrNoEnvironmentFile
+}
+
+// NearestFile returns the path of the innermost existing environment file in the current working directory or any of
+// its parents. Unlike LocalFile it does not consider the user and system-level ones and instead returns the path of a
+// new environment file in the current working directory if none exists.
+func NearestFile() (string, error) {
+	candidatePaths, err := directoryFiles()
+	if err != nil {
+		return "", err
+	}
+	for _, p := range candidatePaths {
+		if _, err = os.Stat(p); err == nil {
+			return p, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return candidatePaths[0], nil
+}
+
+// FilesUnder returns the paths of the environment files found in the given directory trees, followed by those of the
+// user and system-level environments as these apply to all of the trees. Hidden directories, such as '.git', are not
+// searched.
+func FilesUnder(roots []string) ([]string, error) {
+	envFileName := fmt.Sprintf(".%s.yaml", config.DriverName)
+
+	var paths []string
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case d.IsDir() && p != root && strings.HasPrefix(d.Name(), "."):
+				return filepath.SkipDir
+			case !d.IsDir() && d.Name() == envFileName:
+				paths = append(paths, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dir := range config.AllDirs() {
+		p := filepath.Join(dir, fmt.Sprintf("%s.yaml", config.DriverName))
+		if _, err := os.Stat(p); err == nil {
+			paths = append(paths, p)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// ReadPins returns for each tool the versions and version constraints at which it is pinned by the given environment
+// files, including the versions recorded in their lock files.
+func ReadPins(paths []string) (map[string][]string, error) {
+	pins := map[string][]string{}
+	for _, p := range paths {
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		// Only the pins are decoded so that files with sources that are invalid, or unknown to this version of the
+		// driver, do not prevent the use of their pins.
+		var spec struct {
+			Pins map[string]string `json:"pins"`
+		}
+		if err = yaml.Unmarshal(raw, &spec); err != nil {
+			return nil, fmt.Errorf("failed to parse environment file %q: %w", p, err)
+		}
+		for tool, pin := range spec.Pins {
+			pins[tool] = append(pins[tool], pin)
+		}
+
+		lock, err := ReadLock(LockFilePath(p))
+		if err != nil {
+			return nil, err
+		}
+		for tool, locked := range lock.Tools {
+			pins[tool] = append(pins[tool], locked.Version)
+		}
+	}
+	return pins, nil
+}
+
+// candidateFiles returns the paths of all potential environment files in order of decreasing priority.
+func candidateFiles() ([]string, error) {
+	candidatePaths, err := directoryFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range config.AllDirs() {
+		candidatePaths = append(candidatePaths, filepath.Join(p, fmt.Sprintf("%s.yaml", config.DriverName)))
+	}
+	return candidatePaths, nil
+}
+
+// directoryFiles returns the paths of the potential environment files in the current working directory and its parents,
+// from the innermost to the outermost.
+func directoryFiles() ([]string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidatePaths []string
+	for {
+		candidatePaths = append(candidatePaths, filepath.Join(cwd, fmt.Sprintf(".%s.yaml", config.DriverName)))
+		if cwd == filepath.Dir(cwd) {
+			break
+		}
+		cwd = filepath.Dir(cwd)
+	}
+	return candidatePaths, nil
+}
+
 func mergeEnvironment(conf *config.Global, env Environment, path string, content []byte) error {
 	// We should preferably set the yaml.Strict() option on the decoder. This is currently not possible due to the
 	// goccy/go-yaml library not supporting partial unmarshalling in combination with yaml.Strict(). Setting the option
@@ -80,14 +336,44 @@ func mergeEnvironment(conf *config.Global, env Environment, path string, content
 		return err
 	}
 
-	// For both pins and sources we only add tool settings if there are none available yet.
-	for tool, version := range newEnv.Pins {
+	// For both pins and sources we only add tool settings if there are none available yet. The exception are version
+	// constraints which accumulate until a file pins an exact version or 'latest', so that inner environments can
+	// narrow down the ranges allowed by outer ones.
+	for tool, pin := range newEnv.Pins {
 		r := env[tool]
-		if r.Version == "" {
-			r.Version = version
+		if r.Version != "" || r.Constraint.Latest() {
+			continue
+		}
+		if version.IsConstraint(pin) {
+			c, err := version.ParseConstraint(pin)
+			if err != nil {
+				return fmt.Errorf("invalid pin for tool %q in %q: %w", tool, path, err)
+			}
+			r.Constraint = r.Constraint.And(c)
+		} else {
+			r.Version = pin
+		}
+		if r.VersionFile == "" {
 			r.VersionFile = path
-			env[tool] = r
 		}
+		env[tool] = r
+	}
+	for tool, versions := range newEnv.Checksums {
+		r := env[tool]
+		if r.Checksums == nil {
+			r.Checksums = map[string]map[string]string{}
+		}
+		for version, digests := range versions {
+			if r.Checksums[version] == nil {
+				r.Checksums[version] = map[string]string{}
+			}
+			for target, digest := range digests {
+				if _, ok := r.Checksums[version][target]; !ok {
+					r.Checksums[version][target] = strings.ToLower(strings.TrimPrefix(digest, "sha256:"))
+				}
+			}
+		}
+		env[tool] = r
 	}
 	if conf.DisableSources {
 		return nil
@@ -117,8 +403,16 @@ func (e Environment) Source(logBuilder logger.Builder, tool string) backend.Stor
 		return backend.NewGCS(logBuilder, sc.GCSConfig)
 	case sc.GitHubConfig != nil:
 		return backend.NewGitHub(logBuilder, sc.GitHubConfig)
+	case sc.GitLabConfig != nil:
+		return backend.NewGitLab(logBuilder, sc.GitLabConfig)
+	case sc.GiteaConfig != nil:
+		return backend.NewGitea(logBuilder, sc.GiteaConfig)
+	case sc.GoModuleConfig != nil:
+		return backend.NewGoModule(logBuilder, sc.GoModuleConfig)
 	case sc.HTTPSConfig != nil:
 		return backend.NewHTTPS(logBuilder, sc.HTTPSConfig)
+	case sc.OCIConfig != nil:
+		return backend.NewOCI(logBuilder, sc.OCIConfig)
 	case sc.S3Config != nil:
 		return backend.NewS3(logBuilder, sc.S3Config)
 	default:
@@ -7,8 +7,11 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 
 	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/state"
+	"github.com/Helcaraxan/toolshare/internal/version"
 )
 
 func TestParseErroneousConfigSyntax(t *testing.T) {
@@ -32,13 +35,22 @@ func TestMergeEnvironment(t *testing.T) {
 		sourceCount int
 	}{
 		"InvalidEmpty":                     {testfile: "config_invalid_empty.yaml", errType: ErrInvalidSource},
+		"InvalidBundleArchivePath":         {testfile: "config_invalid_bundle_archive_path.yaml", errType: ErrInvalidSource},
+		"InvalidBundleMissingEntrypoint":   {testfile: "config_invalid_bundle_missing_entrypoint.yaml", errType: ErrInvalidSource},
 		"InvalidGitHubMissingSlug":         {testfile: "config_invalid_github_missing_slug.yaml", errType: ErrInvalidSource},
 		"InvalidGitHubMissingReleaseAsset": {testfile: "config_invalid_github_missing_asset.yaml", errType: ErrInvalidSource},
+		"InvalidGiteaMissingBaseURL":       {testfile: "config_invalid_gitea_missing_base_url.yaml", errType: ErrInvalidSource},
+		"InvalidGoModuleArchivePath":       {testfile: "config_invalid_go_module_archive.yaml", errType: ErrInvalidSource},
 		"InvalidMixedParameters":           {testfile: "config_invalid_mixed.yaml", errType: ErrInvalidSource},
+		"ValidBundleSource":                {testfile: "config_valid_bundle.yaml", sourceCount: 2},
 		"ValidFileSystemSource":            {testfile: "config_valid_filesystem.yaml", sourceCount: 1},
 		"ValidGCSSource":                   {testfile: "config_valid_gcs.yaml", sourceCount: 1},
 		"ValidGitHubSource":                {testfile: "config_valid_github.yaml", sourceCount: 3},
+		"ValidGitLabSource":                {testfile: "config_valid_gitlab.yaml", sourceCount: 2},
+		"ValidGiteaSource":                 {testfile: "config_valid_gitea.yaml", sourceCount: 1},
+		"ValidGoModuleSource":              {testfile: "config_valid_go_module.yaml", sourceCount: 2},
 		"ValidHTTPSSource":                 {testfile: "config_valid_https.yaml", sourceCount: 1},
+		"ValidOCISource":                   {testfile: "config_valid_oci.yaml", sourceCount: 2},
 		"ValidS3Source":                    {testfile: "config_valid_s3.yaml", sourceCount: 1},
 	}
 
@@ -76,6 +88,72 @@ func TestMergePins(t *testing.T) {
 	assert.Equal(t, "child", env["c"].Version)
 }
 
+func TestMergePinConstraints(t *testing.T) {
+	t.Parallel()
+
+	env := Environment{}
+	require.NoError(t, mergeEnvironment(&config.Global{}, env, "inner", []byte("pins:\n  a: ~1.20\n  b: ^2\n  c: 3.1.0\n")))
+	require.NoError(t, mergeEnvironment(&config.Global{}, env, "middle", []byte("pins:\n  a: '>=1.11,<2'\n  b: 2.4.0\n  c: ^3\n")))
+	require.NoError(t, mergeEnvironment(&config.Global{}, env, "outer", []byte("pins:\n  a: 1.20.1\n  b: 2.5.0\n  c: ^4\n")))
+
+	// Constraints accumulate up to and including the first exact version.
+	assert.Equal(t, "~1.20, >=1.11, <2", env["a"].Constraint.String())
+	assert.Equal(t, "1.20.1", env["a"].Version)
+	assert.Equal(t, "inner", env["a"].VersionFile)
+	assert.Equal(t, "^2", env["b"].Constraint.String())
+	assert.Equal(t, "2.4.0", env["b"].Version)
+	assert.Nil(t, env["c"].Constraint)
+	assert.Equal(t, "3.1.0", env["c"].Version)
+
+	latest := Environment{}
+	require.NoError(t, mergeEnvironment(&config.Global{}, latest, "inner", []byte("pins:\n  a: ^1\n  b: latest\n")))
+	require.NoError(t, mergeEnvironment(&config.Global{}, latest, "outer", []byte("pins:\n  a: latest-prerelease\n  b: 1.0.0\n")))
+	assert.Equal(t, "^1, latest-prerelease", latest["a"].Constraint.String())
+	assert.Equal(t, "latest", latest["b"].Constraint.String())
+	assert.Empty(t, latest["b"].Version, "outer pins should not override an inner 'latest' pin")
+
+	err := mergeEnvironment(&config.Global{}, Environment{}, "invalid", []byte("pins:\n  a: ^one\n"))
+	require.ErrorIs(t, err, version.ErrInvalidConstraint)
+}
+
+func TestResolveConstraints(t *testing.T) {
+	t.Parallel()
+
+	env := Environment{}
+	require.NoError(t, mergeEnvironment(&config.Global{}, env, "", []byte(`---
+pins:
+  a: ^1.64
+  b: ~3.10.0
+  c: '>=1.11,<2'
+  d: ^5
+  e: latest
+`)))
+	env["e"] = ToolRegistration{Constraint: env["e"].Constraint, Lock: &LockedTool{Version: "1.0.0"}}
+	env["b"] = ToolRegistration{Constraint: env["b"].Constraint, Lock: &LockedTool{Version: "3.10.1"}}
+	env["c"] = ToolRegistration{Constraint: env["c"].Constraint, Lock: &LockedTool{Version: "2.0.0"}}
+
+	cache := &fakeStateCache{
+		recommended: map[string]string{"a": "1.64.0"},
+		versions: map[string][]string{
+			"a": {"1.63.0", "1.64.0", "1.65.2", "1.66.0", "2.0.0"},
+			"b": {"3.10.1", "3.10.4"},
+			"c": {"1.10.0", "1.12.0", "2.0.0"},
+			"d": {"4.0.0"},
+			"e": {"1.0.0", "1.1.0"},
+		},
+		denied: map[string][]state.DeniedVersion{"a": {{Version: "1.66.0", Reason: "broken"}}},
+	}
+	mergeState(zap.NewNop(), env, cache, nil)
+	resolveConstraints(zap.NewNop(), env, cache, nil)
+
+	assert.Equal(t, "1.65.2", env["a"].Version, "denied versions should be skipped")
+	assert.False(t, env["a"].Recommended)
+	assert.Equal(t, "3.10.1", env["b"].Version, "a satisfying locked version should be preferred")
+	assert.Equal(t, "1.12.0", env["c"].Version)
+	assert.Empty(t, env["d"].Version, "unsatisfied constraints should be left for resolution against the source")
+	assert.Empty(t, env["e"].Version, "'latest' pins should only be resolved against the source")
+}
+
 func TestMergeSources(t *testing.T) {
 	t.Parallel()
 
@@ -102,3 +180,138 @@ sources:
 	assert.Equal(t, "child", env["b"].Source.HTTPSURLTemplate)
 	assert.Equal(t, "child", env["c"].Source.HTTPSURLTemplate)
 }
+
+func TestMergeChecksums(t *testing.T) {
+	t.Parallel()
+
+	childContent := []byte(`---
+checksums:
+  a:
+    "1.0.0":
+      linux/x86_64: child
+`)
+	parentContent := []byte(`---
+checksums:
+  a:
+    "1.0.0":
+      linux/x86_64: parent
+      darwin/arm64: sha256:PARENT
+  b:
+    "2.0.0":
+      linux/x86_64: parent
+`)
+
+	env := Environment{}
+	require.NoError(t, mergeEnvironment(&config.Global{}, env, "", childContent))
+	require.NoError(t, mergeEnvironment(&config.Global{}, env, "", parentContent))
+
+	bin := func(tool, version string, p config.Platform, a config.Arch) config.Binary {
+		return config.Binary{Tool: tool, Version: version, Platform: p, Arch: a}
+	}
+	assert.Equal(t, "child", env["a"].Checksum(bin("a", "1.0.0", config.PlatformLinux, config.ArchX64)))
+	assert.Equal(t, "parent", env["a"].Checksum(bin("a", "1.0.0", config.PlatformDarwin, config.ArchARM64)))
+	assert.Equal(t, "parent", env["b"].Checksum(bin("b", "2.0.0", config.PlatformLinux, config.ArchX64)))
+	assert.Empty(t, env["b"].Checksum(bin("b", "1.0.0", config.PlatformLinux, config.ArchX64)))
+}
+
+type fakeStateCache struct {
+	recommended map[string]string
+	versions    map[string][]string
+	denied      map[string][]state.DeniedVersion
+	queried     []string
+}
+
+func (c *fakeStateCache) AvailableTools() ([]string, error) {
+	var tools []string
+	for tool := range c.recommended {
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+func (c *fakeStateCache) AvailableVersions(tool string) ([]string, error) {
+	if versions, ok := c.versions[tool]; ok {
+		return versions, nil
+	}
+	return []string{c.recommended[tool]}, nil
+}
+
+func (c *fakeStateCache) DeniedVersions(tool string) ([]state.DeniedVersion, error) {
+	return c.denied[tool], nil
+}
+
+func (c *fakeStateCache) RecommendedVersion(tool string) (string, error) {
+	c.queried = append(c.queried, tool)
+	v, ok := c.recommended[tool]
+	if !ok {
+		return "", os.ErrNotExist
+	}
+	return v, nil
+}
+
+func (c *fakeStateCache) Refresh(_ bool) error { return nil }
+
+func TestMergeState(t *testing.T) {
+	t.Parallel()
+
+	env := Environment{}
+	require.NoError(t, mergeEnvironment(&config.Global{}, env, "", []byte(`---
+pins:
+  a: pinned
+sources:
+  c:
+    https_url_template: https://example.com/c
+`)))
+
+	mergeState(zap.NewNop(), env, &fakeStateCache{recommended: map[string]string{"a": "state", "b": "state"}}, nil)
+
+	assert.Equal(t, "pinned", env["a"].Version)
+	assert.False(t, env["a"].Recommended)
+	assert.Equal(t, "state", env["b"].Version)
+	assert.True(t, env["b"].Recommended)
+	assert.Empty(t, env["c"].Version)
+	assert.False(t, env["c"].Recommended)
+
+	// When restricted to specific tools the state is not consulted about any others.
+	env = Environment{}
+	cache := &fakeStateCache{recommended: map[string]string{"a": "state", "b": "state"}}
+	mergeState(zap.NewNop(), env, cache, []string{"b"})
+
+	assert.Equal(t, []string{"b"}, cache.queried)
+	assert.Equal(t, "state", env["b"].Version)
+	assert.NotContains(t, env, "a")
+}
+
+func TestReadPins(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "config"))
+
+	files := map[string]string{
+		".toolshare.yaml":                  "pins:\n  gh: 2.63.0\n  golangci-lint: ^1.64\n",
+		".toolshare.lock":                  "tools:\n  golangci-lint:\n    version: 1.64.8\n",
+		"project/.toolshare.yaml":          "pins:\n  gh: 2.62.0\nsources:\n  gh:\n    unknown_setting: true\n",
+		".git/.toolshare.yaml":             "pins:\n  ignored: 1.0.0\n",
+		"config/toolshare/toolshare.yaml":  "pins:\n  jq: latest\n",
+		"project/nested/not-an-env-file.y": "pins:\n  ignored: 1.0.0\n",
+	}
+	for p, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, p)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, p), []byte(content), 0o600))
+	}
+
+	paths, err := FilesUnder([]string{root})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(root, ".toolshare.yaml"),
+		filepath.Join(root, "project", ".toolshare.yaml"),
+		filepath.Join(root, "config", "toolshare", "toolshare.yaml"),
+	}, paths)
+
+	pins, err := ReadPins(paths)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"gh":            {"2.63.0", "2.62.0"},
+		"golangci-lint": {"^1.64", "1.64.8"},
+		"jq":            {"latest"},
+	}, pins)
+}
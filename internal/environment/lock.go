@@ -0,0 +1,168 @@
+package environment
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/Helcaraxan/toolshare/internal/backend"
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/httpauth"
+	"github.com/Helcaraxan/toolshare/internal/logger"
+)
+
+const lockFileHeader = "# Generated by '" + config.DriverName + " lock'. Do not edit manually.\n"
+
+// Lock records for each tool of an environment the concrete location from which each of its binaries was resolved
+// together with the binary's digest. This allows for byte-for-byte reproducible tool installations across machines.
+type Lock struct {
+	Tools map[string]*LockedTool `json:"tools"`
+}
+
+type LockedTool struct {
+	Version  string                   `json:"version"`
+	Binaries map[string]*LockedBinary `json:"binaries"`
+}
+
+type LockedBinary struct {
+	Source      string `json:"source"`
+	ArchivePath string `json:"archive_path,omitempty"`
+	ArchiveDir  string `json:"archive_dir,omitempty"`
+	Entrypoint  string `json:"entrypoint,omitempty"`
+	SHA256      string `json:"sha256"`
+}
+
+// LockFilePath returns the path of the lock file that accompanies the given environment file.
+func LockFilePath(envFile string) string {
+	return strings.TrimSuffix(envFile, filepath.Ext(envFile)) + ".lock"
+}
+
+// ReadLock parses the lock file at the given path. A non-existent lock file results in an empty lock.
+func ReadLock(path string) (*Lock, error) {
+	lock := &Lock{Tools: map[string]*LockedTool{}}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return lock, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = yaml.Unmarshal(raw, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file %q: %w", path, err)
+	}
+	if lock.Tools == nil {
+		lock.Tools = map[string]*LockedTool{}
+	}
+	return lock, nil
+}
+
+// WriteLock atomically replaces the lock file at the given path with the provided content.
+func WriteLock(path string, lock *Lock) error {
+	raw, err := yaml.Marshal(lock)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	} else if _, err = tmp.WriteString(lockFileHeader + "---\n" + string(raw)); err != nil {
+		return err
+	} else if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Set records the given entry for the binary, discarding any entries recorded for a different version of the tool.
+func (l *Lock) Set(b config.Binary, entry *LockedBinary) {
+	t := l.Tools[b.Tool]
+	if t == nil || t.Version != b.Version {
+		t = &LockedTool{Version: b.Version, Binaries: map[string]*LockedBinary{}}
+		l.Tools[b.Tool] = t
+	}
+	t.Binaries[checksumKey(b)] = entry
+}
+
+// Locked returns the lock entry for the given binary, if one has been recorded for the binary's exact version.
+func (r ToolRegistration) Locked(b config.Binary) *LockedBinary {
+	if r.Lock == nil || r.Lock.Version != b.Version {
+		return nil
+	}
+	return r.Lock.Binaries[checksumKey(b)]
+}
+
+// Storage returns a storage backend that fetches the binary from the exact location recorded in the lock entry. The
+// tool's configured source, if any, provides the credentials with which to access that location. Release assets of
+// software forges are downloaded through the forge's backend so that they are authenticated like any other request to
+// the forge.
+func (l *LockedBinary) Storage(logBuilder logger.Builder, source *Source) backend.Storage {
+	common := backend.CommonConfig{
+		ArchivePathTemplate: l.ArchivePath,
+		ArchiveDirTemplate:  l.ArchiveDir,
+		EntrypointTemplate:  l.Entrypoint,
+	}
+
+	switch {
+	case strings.HasPrefix(l.Source, "https://"), strings.HasPrefix(l.Source, "http://"):
+		if fetcher := source.assetFetcher(logBuilder); fetcher != nil {
+			return backend.NewReleaseAsset(logBuilder, common, l.Source, fetcher)
+		}
+		var auth *httpauth.Config
+		if source != nil && source.HTTPSConfig != nil {
+			auth = source.HTTPSAuth
+		}
+		return backend.NewHTTPS(logBuilder, &backend.HTTPSConfig{CommonConfig: common, HTTPSURLTemplate: l.Source, HTTPSAuth: auth})
+	case strings.HasPrefix(l.Source, "gs://"):
+		bucket, key, _ := strings.Cut(strings.TrimPrefix(l.Source, "gs://"), "/")
+		return backend.NewGCS(logBuilder, &backend.GCSConfig{CommonConfig: common, GCSBucket: bucket, GCSPathTemplate: key})
+	case strings.HasPrefix(l.Source, "go://"):
+		var proxy string
+		if source != nil && source.GoModuleConfig != nil {
+			proxy = source.GoModuleProxy
+		}
+		module, _, pkg := backend.ParseGoModuleLocation(l.Source)
+		return backend.NewGoModule(logBuilder, &backend.GoModuleConfig{GoModulePath: module, GoModulePackage: pkg, GoModuleProxy: proxy})
+	case strings.HasPrefix(l.Source, "oci://"):
+		repo, digest, _ := strings.Cut(strings.TrimPrefix(l.Source, "oci://"), "@")
+		registry, repo, _ := strings.Cut(repo, "/")
+		return backend.NewOCI(logBuilder, &backend.OCIConfig{CommonConfig: common, OCIRegistry: registry, OCIRepositoryTemplate: repo, OCITagTemplate: digest})
+	case strings.HasPrefix(l.Source, "s3://"):
+		bucket, key, _ := strings.Cut(strings.TrimPrefix(l.Source, "s3://"), "/")
+		return backend.NewS3(logBuilder, &backend.S3Config{CommonConfig: common, S3Bucket: bucket, S3PathTemplate: key})
+	default:
+		return backend.NewFileSystem(logBuilder, &backend.FileSystemConfig{CommonConfig: common, FilePathTemplate: l.Source})
+	}
+}
+
+// assetFetcher returns the backend of the source if it downloads binaries from the release assets of a software forge.
+func (s *Source) assetFetcher(logBuilder logger.Builder) backend.AssetFetcher {
+	switch {
+	case s == nil:
+		return nil
+	case s.GitHubConfig != nil:
+		return backend.NewGitHub(logBuilder, s.GitHubConfig)
+	case s.GitLabConfig != nil:
+		return backend.NewGitLab(logBuilder, s.GitLabConfig)
+	case s.GiteaConfig != nil:
+		return backend.NewGitea(logBuilder, s.GiteaConfig)
+	default:
+		return nil
+	}
+}
+
+func mergeLock(env Environment, path string, lock *Lock) {
+	for tool, locked := range lock.Tools {
+		r := env[tool]
+		if r.Lock == nil {
+			r.Lock = locked
+			r.LockFile = path
+			env[tool] = r
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package environment
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Helcaraxan/toolshare/internal/backend"
+	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/httpauth"
+	"github.com/Helcaraxan/toolshare/internal/logger"
+)
+
+func TestLockFilePath(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, filepath.Join("foo", ".toolshare.lock"), LockFilePath(filepath.Join("foo", ".toolshare.yaml")))
+	assert.Equal(t, filepath.Join("foo", "toolshare.lock"), LockFilePath(filepath.Join("foo", "toolshare.yaml")))
+}
+
+func TestLockRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), ".toolshare.lock")
+
+	lock, err := ReadLock(path)
+	require.NoError(t, err)
+	assert.Empty(t, lock.Tools)
+
+	linux := config.Binary{Tool: "a", Version: "1.0.0", Platform: config.PlatformLinux, Arch: config.ArchX64}
+	darwin := config.Binary{Tool: "a", Version: "1.0.0", Platform: config.PlatformDarwin, Arch: config.ArchARM64}
+	lock.Set(linux, &LockedBinary{Source: "https://example.com/a_linux.tar.gz", ArchivePath: "a", SHA256: "linux"})
+	lock.Set(darwin, &LockedBinary{Source: "https://example.com/a_darwin.tar.gz", ArchivePath: "a", SHA256: "darwin"})
+	require.NoError(t, WriteLock(path, lock))
+
+	lock, err = ReadLock(path)
+	require.NoError(t, err)
+	require.Contains(t, lock.Tools, "a")
+	assert.Len(t, lock.Tools["a"].Binaries, 2)
+
+	reg := ToolRegistration{Lock: lock.Tools["a"]}
+	assert.Equal(t, "linux", reg.Locked(linux).SHA256)
+	assert.Equal(t, "darwin", reg.Locked(darwin).SHA256)
+
+	linux.Version = "2.0.0"
+	assert.Nil(t, reg.Locked(linux))
+
+	lock.Set(linux, &LockedBinary{Source: "https://example.com/a_linux.tar.gz", SHA256: "new"})
+	assert.Len(t, lock.Tools["a"].Binaries, 1)
+}
+
+func TestMergeLock(t *testing.T) {
+	t.Parallel()
+
+	env := Environment{}
+	mergeLock(env, "child", &Lock{Tools: map[string]*LockedTool{"a": {Version: "child"}}})
+	mergeLock(env, "parent", &Lock{Tools: map[string]*LockedTool{"a": {Version: "parent"}, "b": {Version: "parent"}}})
+
+	assert.Equal(t, "child", env["a"].Lock.Version)
+	assert.Equal(t, "child", env["a"].LockFile)
+	assert.Equal(t, "parent", env["b"].Lock.Version)
+}
+
+func TestLockedBinaryStorage(t *testing.T) {
+	t.Parallel()
+
+	auth := &httpauth.Config{BearerTokenEnv: "TOKEN"}
+	source := &Source{HTTPSConfig: &backend.HTTPSConfig{HTTPSURLTemplate: "https://example.com/{tool}.zip", HTTPSAuth: auth}}
+
+	s := (&LockedBinary{Source: "https://example.com/tool.zip", ArchivePath: "tool"}).Storage(logger.NewTestBuilder(), source)
+	require.IsType(t, &backend.HTTPS{}, s)
+	assert.Equal(t, "https://example.com/tool.zip", s.(*backend.HTTPS).HTTPSURLTemplate)
+	assert.Equal(t, "tool", s.(*backend.HTTPS).ArchivePathTemplate)
+	assert.Equal(t, auth, s.(*backend.HTTPS).HTTPSAuth)
+
+	// Release assets of forges are downloaded through the forge's backend to authenticate the download.
+	gitHubSource := &Source{GitHubConfig: &backend.GitHubConfig{GitHubSlug: "foo/bar", GitHubReleaseAssetTemplate: "{tool}.zip"}}
+	s = (&LockedBinary{Source: "https://github.com/foo/bar/releases/download/v1.2.3/tool.zip", ArchivePath: "tool"}).Storage(logger.NewTestBuilder(), gitHubSource)
+	require.IsType(t, &backend.ReleaseAsset{}, s)
+	assert.Equal(t, "https://github.com/foo/bar/releases/download/v1.2.3/tool.zip", s.String())
+	assert.Equal(t, "tool", s.(*backend.ReleaseAsset).ArchivePathTemplate)
+
+	s = (&LockedBinary{Source: "oci://registry.example.com/tools/tool@sha256:0123"}).Storage(logger.NewTestBuilder(), nil)
+	require.IsType(t, &backend.OCI{}, s)
+	assert.Equal(t, "registry.example.com", s.(*backend.OCI).OCIRegistry)
+	assert.Equal(t, "tools/tool", s.(*backend.OCI).OCIRepositoryTemplate)
+	assert.Equal(t, "sha256:0123", s.(*backend.OCI).OCITagTemplate)
+
+	goSource := &Source{GoModuleConfig: &backend.GoModuleConfig{GoModulePath: "example.com/tool", GoModuleProxy: "file:///proxy"}}
+	s = (&LockedBinary{Source: "go://example.com/tool@v1.2.3/cmd/tool"}).Storage(logger.NewTestBuilder(), goSource)
+	require.IsType(t, &backend.GoModule{}, s)
+	assert.Equal(t, "example.com/tool", s.(*backend.GoModule).GoModulePath)
+	assert.Equal(t, "cmd/tool", s.(*backend.GoModule).GoModulePackage)
+	assert.Equal(t, "file:///proxy", s.(*backend.GoModule).GoModuleProxy)
+
+	s = (&LockedBinary{Source: "/some/path/tool"}).Storage(logger.NewTestBuilder(), nil)
+	require.IsType(t, &backend.FileSystem{}, s)
+	assert.Equal(t, "/some/path/tool", s.(*backend.FileSystem).FilePathTemplate)
+}
@@ -3,9 +3,12 @@ package environment
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"reflect"
 	"strings"
 
+	"github.com/goccy/go-yaml"
+
 	"github.com/Helcaraxan/toolshare/internal/backend"
 )
 
@@ -15,7 +18,11 @@ type Source struct {
 	*backend.FileSystemConfig
 	*backend.GCSConfig
 	*backend.GitHubConfig
+	*backend.GitLabConfig
+	*backend.GiteaConfig
+	*backend.GoModuleConfig
 	*backend.HTTPSConfig
+	*backend.OCIConfig
 	*backend.S3Config
 }
 
@@ -31,8 +38,16 @@ func (s *Source) String() string {
 			b = "github.com"
 		}
 		return fmt.Sprintf("%s/%s:%s", b, s.GitHubSlug, s.GitHubReleaseAssetTemplate)
+	case s.GitLabConfig != nil:
+		return fmt.Sprintf("%s:%s", s.GitLabConfig, s.GitLabReleaseAssetTemplate)
+	case s.GiteaConfig != nil:
+		return fmt.Sprintf("%s:%s", s.GiteaConfig, s.GiteaReleaseAssetTemplate)
+	case s.GoModuleConfig != nil:
+		return s.GoModuleConfig.String()
 	case s.HTTPSConfig != nil:
 		return s.HTTPSURLTemplate
+	case s.OCIConfig != nil:
+		return s.OCIConfig.String()
 	case s.S3Config != nil:
 		return fmt.Sprintf("s3://%s/%s", s.S3Bucket, s.S3PathTemplate)
 	default:
@@ -40,6 +55,72 @@ func (s *Source) String() string {
 	}
 }
 
+// Common returns the configuration elements that are shared by all source types.
+func (s *Source) Common() *backend.CommonConfig {
+	switch {
+	case s.FileSystemConfig != nil:
+		return &s.FileSystemConfig.CommonConfig
+	case s.GCSConfig != nil:
+		return &s.GCSConfig.CommonConfig
+	case s.GitHubConfig != nil:
+		return &s.GitHubConfig.CommonConfig
+	case s.GitLabConfig != nil:
+		return &s.GitLabConfig.CommonConfig
+	case s.GiteaConfig != nil:
+		return &s.GiteaConfig.CommonConfig
+	case s.GoModuleConfig != nil:
+		return &s.GoModuleConfig.CommonConfig
+	case s.HTTPSConfig != nil:
+		return &s.HTTPSConfig.CommonConfig
+	case s.OCIConfig != nil:
+		return &s.OCIConfig.CommonConfig
+	case s.S3Config != nil:
+		return &s.S3Config.CommonConfig
+	default:
+		return &backend.CommonConfig{}
+	}
+}
+
+// MarshalYAML only emits the settings of the source that are set, in the order in which they are declared, so that
+// written sources are as concise as hand-written ones.
+func (s *Source) MarshalYAML() (interface{}, error) {
+	for _, c := range []interface{}{s.FileSystemConfig, s.GCSConfig, s.GitHubConfig, s.GitLabConfig, s.GiteaConfig, s.GoModuleConfig, s.HTTPSConfig, s.OCIConfig, s.S3Config} {
+		if v := reflect.ValueOf(c); !v.IsNil() {
+			return setFields(v.Elem()), nil
+		}
+	}
+	return nil, fmt.Errorf("backend has no configuration attached: %w", ErrInvalidSource)
+}
+
+// setFields returns the non-zero fields of a configuration struct keyed by their name in environment files. Fields of
+// embedded structs, i.e. the settings common to all sources, follow those of the struct itself.
+func setFields(v reflect.Value) yaml.MapSlice {
+	var fields, embedded yaml.MapSlice
+	for idx := 0; idx < v.NumField(); idx++ {
+		f, fv := v.Type().Field(idx), v.Field(idx)
+		if f.Anonymous {
+			embedded = append(embedded, setFields(fv)...)
+			continue
+		}
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" || fv.IsZero() {
+			continue
+		}
+		if fv.Kind() == reflect.Pointer {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct {
+			if nested := setFields(fv); len(nested) > 0 {
+				fields = append(fields, yaml.MapItem{Key: name, Value: nested})
+			}
+			continue
+		}
+		fields = append(fields, yaml.MapItem{Key: name, Value: fv.Interface()})
+	}
+	return append(fields, embedded...)
+}
+
 //nolint:cyclop // Exhaustive case-matching trivially increases cyclomatic complexity.
 func (s *Source) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	m := map[string]interface{}{}
@@ -47,7 +128,7 @@ func (s *Source) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return ErrInvalidSource
 	}
 
-	var isFile, isGCS, isGitHub, isHTTPS, isS3 bool
+	var isFile, isGCS, isGitHub, isGitLab, isGitea, isGoModule, isHTTPS, isOCI, isS3 bool
 	for fn := range m {
 		switch strings.Split(fn, "_")[0] {
 		case "file":
@@ -56,8 +137,16 @@ func (s *Source) UnmarshalYAML(unmarshal func(interface{}) error) error {
 			isGCS = true
 		case "github":
 			isGitHub = true
+		case "gitlab":
+			isGitLab = true
+		case "gitea":
+			isGitea = true
+		case "go":
+			isGoModule = true
 		case "https":
 			isHTTPS = true
+		case "oci":
+			isOCI = true
 		case "s3":
 			isS3 = true
 		}
@@ -86,12 +175,36 @@ func (s *Source) UnmarshalYAML(unmarshal func(interface{}) error) error {
 			return err
 		}
 	}
+	if isGitLab {
+		s.GitLabConfig = &backend.GitLabConfig{CommonConfig: c}
+		if err := unmarshal(s.GitLabConfig); err != nil {
+			return err
+		}
+	}
+	if isGitea {
+		s.GiteaConfig = &backend.GiteaConfig{CommonConfig: c}
+		if err := unmarshal(s.GiteaConfig); err != nil {
+			return err
+		}
+	}
+	if isGoModule {
+		s.GoModuleConfig = &backend.GoModuleConfig{CommonConfig: c}
+		if err := unmarshal(s.GoModuleConfig); err != nil {
+			return err
+		}
+	}
 	if isHTTPS {
 		s.HTTPSConfig = &backend.HTTPSConfig{CommonConfig: c}
 		if err := unmarshal(s.HTTPSConfig); err != nil {
 			return err
 		}
 	}
+	if isOCI {
+		s.OCIConfig = &backend.OCIConfig{CommonConfig: c}
+		if err := unmarshal(s.OCIConfig); err != nil {
+			return err
+		}
+	}
 	if isS3 {
 		s.S3Config = &backend.S3Config{CommonConfig: c}
 		if err := unmarshal(s.S3Config); err != nil {
@@ -104,7 +217,7 @@ func (s *Source) UnmarshalYAML(unmarshal func(interface{}) error) error {
 //nolint:cyclop // Exhaustive case-matching trivially increases cyclomatic complexity.
 func (s *Source) validate() error {
 	var sourceConfigCount int
-	for _, si := range []interface{}{s.FileSystemConfig, s.GCSConfig, s.GitHubConfig, s.HTTPSConfig, s.S3Config} {
+	for _, si := range []interface{}{s.FileSystemConfig, s.GCSConfig, s.GitHubConfig, s.GitLabConfig, s.GiteaConfig, s.GoModuleConfig, s.HTTPSConfig, s.OCIConfig, s.S3Config} {
 		if !reflect.ValueOf(si).IsNil() {
 			sourceConfigCount++
 		}
@@ -116,6 +229,12 @@ func (s *Source) validate() error {
 		return fmt.Errorf("backend has multiple configuration attached: %w", ErrInvalidSource)
 	}
 
+	if c := s.Common(); c.IsBundle() && c.ArchivePathTemplate != "" {
+		return fmt.Errorf("backend can not have both an archive path and an entrypoint set: %w", ErrInvalidSource)
+	} else if !c.IsBundle() && c.ArchiveDirTemplate != "" {
+		return fmt.Errorf("backend has an archive directory but no entrypoint set: %w", ErrInvalidSource)
+	}
+
 	switch {
 	case s.FileSystemConfig != nil:
 		if s.FilePathTemplate == "" {
@@ -132,11 +251,40 @@ func (s *Source) validate() error {
 			return fmt.Errorf("github backend has no slug and / or release asset template set: %w", ErrInvalidSource)
 		}
 
+	case s.GitLabConfig != nil:
+		if s.GitLabProject == "" || s.GitLabReleaseAssetTemplate == "" {
+			return fmt.Errorf("gitlab backend has no project and / or release asset template set: %w", ErrInvalidSource)
+		}
+		if _, err := url.Parse(s.GitLabBaseURL); err != nil {
+			return fmt.Errorf("gitlab backend has an invalid base url: %w", ErrInvalidSource)
+		}
+
+	case s.GiteaConfig != nil:
+		if s.GiteaRepo == "" || s.GiteaReleaseAssetTemplate == "" || s.GiteaBaseURL == "" {
+			return fmt.Errorf("gitea backend has no repo, release asset template and / or base url set: %w", ErrInvalidSource)
+		}
+		if _, err := url.Parse(s.GiteaBaseURL); err != nil {
+			return fmt.Errorf("gitea backend has an invalid base url: %w", ErrInvalidSource)
+		}
+
+	case s.GoModuleConfig != nil:
+		if s.GoModulePath == "" {
+			return fmt.Errorf("go module backend has no module path set: %w", ErrInvalidSource)
+		}
+		if s.GoModuleConfig.ArchivePathTemplate != "" || s.GoModuleConfig.IsBundle() {
+			return fmt.Errorf("go module backend builds binaries and does not support an archive path or entrypoint: %w", ErrInvalidSource)
+		}
+
 	case s.HTTPSConfig != nil:
 		if s.HTTPSURLTemplate == "" {
 			return fmt.Errorf("https backend has no url template set: %w", ErrInvalidSource)
 		}
 
+	case s.OCIConfig != nil:
+		if s.OCIRegistry == "" {
+			return fmt.Errorf("oci backend has no registry set: %w", ErrInvalidSource)
+		}
+
 	case s.S3Config != nil:
 		if s.S3Bucket == "" || s.S3PathTemplate == "" {
 			return fmt.Errorf("s3 backend has no bucket and / or path template set: %w", ErrInvalidSource)
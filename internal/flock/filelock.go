@@ -1,6 +1,7 @@
 package flock
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -19,13 +20,13 @@ const (
 	pidWriteGracePeriod = 1 * time.Second
 )
 
-func AcquireFileLock(log *zap.Logger, path string) (bool, error) {
+func AcquireFileLock(ctx context.Context, log *zap.Logger, path string) (bool, error) {
 	sem, err := os.OpenFile(path+".pid", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
 	if err != nil && !errors.Is(err, os.ErrExist) {
 		return false, err
 	} else if errors.Is(err, os.ErrExist) {
 		log.Debug("Lockfile already exists. Waiting for it to be released.")
-		return false, waitOnPID(log, path)
+		return false, waitOnPID(ctx, log, path)
 	}
 
 	log.Debug("Acquired lock. Writing PID to file.")
@@ -46,9 +47,12 @@ func ReleaseFileLock(log *zap.Logger, path string) error {
 	return nil
 }
 
-func waitOnPID(log *zap.Logger, path string) error {
+func waitOnPID(ctx context.Context, log *zap.Logger, path string) error {
 	iterations := 1
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if iterations%100 == 0 {
 			log.Info("Waiting for tool download lock to be released.")
 		}
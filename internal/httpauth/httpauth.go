@@ -0,0 +1,99 @@
+// Package httpauth provides the authentication of HTTP(S) requests to servers hosting tool binaries, such as
+// Artifactory or Nexus instances.
+package httpauth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+var ErrMissingCredentials = errors.New("missing credentials")
+
+// Config specifies how requests are authenticated. Secrets are never part of the configuration itself. Instead they
+// are read from the environment variables named in the configuration or from the user's '.netrc' file.
+//
+// When more than one mechanism is configured the 'Authorization' header is set by the first of: a bearer token, basic
+// auth credentials, a matching '.netrc' entry. Custom headers are always added.
+type Config struct {
+	BearerTokenEnv string            `json:"bearer_token_env"`
+	Username       string            `json:"username"`
+	PasswordEnv    string            `json:"password_env"`
+	Netrc          bool              `json:"netrc"`
+	Headers        map[string]string `json:"headers"` // Header name to the name of the environment variable with its value.
+}
+
+// Apply adds the configured credentials to the request. A nil configuration leaves the request untouched.
+func (c *Config) Apply(req *http.Request) error {
+	if c == nil {
+		return nil
+	}
+
+	for header, env := range c.Headers {
+		value, err := fromEnv(env)
+		if err != nil {
+			return fmt.Errorf("header %q: %w", header, err)
+		}
+		req.Header.Set(header, value)
+	}
+
+	switch {
+	case c.BearerTokenEnv != "":
+		token, err := fromEnv(c.BearerTokenEnv)
+		if err != nil {
+			return fmt.Errorf("bearer token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+	case c.Username != "":
+		password, err := fromEnv(c.PasswordEnv)
+		if err != nil {
+			return fmt.Errorf("password: %w", err)
+		}
+		req.SetBasicAuth(c.Username, password)
+
+	case c.Netrc:
+		entry, err := lookupNetrc(req.URL.Hostname())
+		if err != nil {
+			return fmt.Errorf("netrc: %w", err)
+		}
+		if entry != nil {
+			req.SetBasicAuth(entry.login, entry.password)
+		}
+	}
+	return nil
+}
+
+// Client returns an HTTP client with which to send requests authenticated with the configuration. When following a
+// redirect to another host the configured custom headers are removed from the request, as the standard client only
+// does so for the 'Authorization' and 'Cookie' headers.
+func (c *Config) Client() *http.Client {
+	return &http.Client{CheckRedirect: c.checkRedirect}
+}
+
+func (c *Config) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if c != nil && req.URL.Host != via[0].URL.Host {
+		for header := range c.Headers {
+			req.Header.Del(header)
+		}
+	}
+	return nil
+}
+
+// maxRedirects matches the number of redirects that the standard client follows.
+const maxRedirects = 10
+
+func fromEnv(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("%w: no environment variable specified", ErrMissingCredentials)
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return "", fmt.Errorf("%w: environment variable %q is not set", ErrMissingCredentials, name)
+	}
+	return value, nil
+}
@@ -0,0 +1,91 @@
+package httpauth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+type netrcEntry struct {
+	login    string
+	password string
+}
+
+// netrcPath returns the location of the user's '.netrc' file, honouring the 'NETRC' environment variable like curl and
+// the go toolchain do.
+func netrcPath() (string, error) {
+	if p := os.Getenv("NETRC"); p != "" {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	name := ".netrc"
+	if runtime.GOOS == "windows" {
+		name = "_netrc"
+	}
+	return filepath.Join(home, name), nil
+}
+
+// lookupNetrc returns the credentials for the given host from the user's '.netrc' file. It returns nil if there is no
+// '.netrc' file or if it has neither an entry for the host nor a default entry.
+func lookupNetrc(host string) (*netrcEntry, error) {
+	path, err := netrcPath()
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return parseNetrc(string(raw), host), nil
+}
+
+func parseNetrc(content string, host string) *netrcEntry {
+	var (
+		current, fallback *netrcEntry
+		matched           bool
+	)
+
+	tokens := strings.Fields(content)
+	for idx := 0; idx < len(tokens); idx++ {
+		switch tokens[idx] {
+		case "machine":
+			if matched {
+				return current
+			}
+			idx++
+			current = &netrcEntry{}
+			matched = idx < len(tokens) && tokens[idx] == host
+		case "default":
+			if matched {
+				return current
+			}
+			current = &netrcEntry{}
+			fallback = current
+		case "login":
+			idx++
+			if current != nil && idx < len(tokens) {
+				current.login = tokens[idx]
+			}
+		case "password":
+			idx++
+			if current != nil && idx < len(tokens) {
+				current.password = tokens[idx]
+			}
+		case "account", "macdef":
+			// Neither is relevant for HTTP authentication. Macro definitions are not supported.
+			idx++
+		}
+	}
+	if matched {
+		return current
+	}
+	return fallback
+}
@@ -18,8 +18,13 @@ const (
 	FileSystemDomain
 	GCSDomain
 	GitHubDomain
+	GitLabDomain
+	GiteaDomain
+	GoModuleDomain
 	HTTPSDomain
+	OCIDomain
 	S3Domain
+	StateDomain
 )
 
 var (
@@ -30,8 +35,13 @@ var (
 		"fs":     FileSystemDomain,
 		"gcs":    GCSDomain,
 		"github": GitHubDomain,
+		"gitlab": GitLabDomain,
+		"gitea":  GiteaDomain,
+		"gomod":  GoModuleDomain,
 		"https":  HTTPSDomain,
+		"oci":    OCIDomain,
 		"s3":     S3Domain,
+		"state":  StateDomain,
 	}
 
 	stringFromDomain = map[Domain]string{
@@ -41,8 +51,13 @@ var (
 		FileSystemDomain: "fs",
 		GCSDomain:        "gcs",
 		GitHubDomain:     "github",
+		GitLabDomain:     "gitlab",
+		GiteaDomain:      "gitea",
+		GoModuleDomain:   "gomod",
 		HTTPSDomain:      "https",
+		OCIDomain:        "oci",
 		S3Domain:         "s3",
+		StateDomain:      "state",
 		UnknownDomain:    "unknown",
 	}
 )
@@ -76,7 +91,7 @@ func (b *builder) SetDomainLevel(domain string, level zapcore.Level) {
 		b.log.Warn("Unrecognised logger domain.")
 	case AllDomain:
 		b.defaultLevel = level
-	case InitDomain, CLIDomain, FileSystemDomain, GCSDomain, GitHubDomain, HTTPSDomain, S3Domain:
+	case InitDomain, CLIDomain, FileSystemDomain, GCSDomain, GitHubDomain, GitLabDomain, GiteaDomain, GoModuleDomain, HTTPSDomain, OCIDomain, S3Domain, StateDomain:
 		b.domainLevels[d] = level
 	default:
 		panic(fmt.Sprintf("unexpected domain %q", d))
@@ -2,10 +2,11 @@ package state
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"sort"
+	"slices"
 	"strings"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/Helcaraxan/toolshare/internal/config"
+	"github.com/Helcaraxan/toolshare/internal/version"
 )
 
 const cacheStatusFile = "cache.status.yaml"
@@ -58,15 +60,37 @@ func (s *fileSystem) AvailableVersions(toolName string) ([]string, error) {
 		return nil, err
 	}
 
-	sort.Strings(state.Versions)
+	version.Sort(state.Versions)
 	return state.Versions, nil
 }
 
+func (s *fileSystem) DeniedVersions(toolName string) ([]DeniedVersion, error) {
+	if err := s.Refresh(false); err != nil {
+		s.log.Warn("Failed to refresh state cache.", zap.Error(err))
+	}
+
+	// Tools that are not managed via the state can not have any denied versions.
+	if _, err := s.storage.Stat(toolName + ".yaml"); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	state, err := s.readToolState(toolName)
+	if err != nil {
+		return nil, err
+	}
+	return state.Denied, nil
+}
+
 func (s *fileSystem) RecommendedVersion(toolName string) (string, error) {
 	if err := s.Refresh(false); err != nil {
 		s.log.Warn("Failed to refresh state cache.", zap.Error(err))
 	}
 
+	// Tools that are not managed via the state do not have a recommended version.
+	if _, err := s.storage.Stat(toolName + ".yaml"); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+
 	state, err := s.readToolState(toolName)
 	if err != nil {
 		return "", err
@@ -77,7 +101,7 @@ func (s *fileSystem) RecommendedVersion(toolName string) (string, error) {
 func (s *fileSystem) Refresh(force bool) error {
 	log := s.log.With(zap.String("status-file", filepath.Join(s.storage.Root(), cacheStatusFile)))
 
-	stateFile, err := s.storage.OpenFile(cacheStatusFile, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0o644)
+	stateFile, err := s.storage.OpenFile(cacheStatusFile, os.O_RDWR|os.O_CREATE, 0o644)
 	if err != nil {
 		log.Error("Failed to open state cache status file.", zap.Error(err))
 		return err
@@ -103,6 +127,11 @@ func (s *fileSystem) Refresh(force bool) error {
 		return nil
 	}
 
+	if s.remote == nil {
+		log.Debug("No remote state configured. Relying on the existing content of the state cache.")
+		return nil
+	}
+
 	if err = s.remote.Fetch(s.storage); err != nil {
 		return err
 	}
@@ -114,7 +143,13 @@ func (s *fileSystem) Refresh(force bool) error {
 		return err
 	}
 
-	if _, err = stateFile.Write(stateContent); err != nil {
+	if err = stateFile.Truncate(0); err != nil {
+		log.Error("Unable to reset state cache status file.", zap.Error(err))
+		return err
+	} else if _, err = stateFile.Seek(0, io.SeekStart); err != nil {
+		log.Error("Unable to reset state cache status file.", zap.Error(err))
+		return err
+	} else if _, err = stateFile.Write(stateContent); err != nil {
 		log.Error("Unable to update state cache status file.", zap.Error(err))
 		return err
 	}
@@ -130,6 +165,7 @@ func (s *fileSystem) Fetch(target billy.Filesystem) error {
 	}
 
 	var state *toolState
+	dst := &fileSystem{log: s.log, storage: target}
 	copiedFiles := map[string]bool{}
 	for _, info := range stateFiles {
 		if info.IsDir() || filepath.Ext(info.Name()) != ".yaml" || info.Name() == cacheStatusFile {
@@ -141,7 +177,7 @@ func (s *fileSystem) Fetch(target billy.Filesystem) error {
 		if err != nil {
 			return err
 		}
-		if err = s.writeToolState(toolName, state); err != nil {
+		if err = dst.writeToolState(toolName, state); err != nil {
 			return err
 		}
 
@@ -162,7 +198,7 @@ func (s *fileSystem) Fetch(target billy.Filesystem) error {
 		}
 
 		if err = target.Remove(info.Name()); err != nil {
-			log.Error("Failed to clean up stale state file.", zap.String("state-file", filepath.Join(s.storage.Root(), info.Name())), zap.Error(err))
+			log.Error("Failed to clean up stale state file.", zap.String("state-file", filepath.Join(target.Root(), info.Name())), zap.Error(err))
 			return err
 		}
 	}
@@ -175,6 +211,18 @@ func (s *fileSystem) RecommendVersion(binary config.Binary) error {
 		return err
 	}
 
+	var available bool
+	for _, version := range state.Versions {
+		if version == binary.Version {
+			available = true
+			break
+		}
+	}
+	if !available {
+		s.log.Error("Can not recommend a version that is not available in the state.", zap.String("tool-name", binary.Tool), zap.String("version", binary.Version))
+		return fmt.Errorf("%w: %s@%s", ErrUnknownVersion, binary.Tool, binary.Version)
+	}
+
 	state.RecommendedVersion = binary.Version
 
 	return s.writeToolState(binary.Tool, state)
@@ -182,14 +230,14 @@ func (s *fileSystem) RecommendVersion(binary config.Binary) error {
 
 func (s *fileSystem) AddVersions(binaries ...config.Binary) error {
 	for _, binary := range binaries {
-		state, err := s.readToolState(binary.Tool)
+		state, err := s.readOrInitToolState(binary.Tool)
 		if err != nil {
 			return err
 		}
 
 		var exists bool
-		for _, version := range state.Versions {
-			if version == binary.Version {
+		for _, v := range state.Versions {
+			if v == binary.Version {
 				exists = true
 				break
 			}
@@ -199,7 +247,7 @@ func (s *fileSystem) AddVersions(binaries ...config.Binary) error {
 		}
 
 		state.Versions = append(state.Versions, binary.Version)
-		sort.Strings(state.Versions)
+		version.Sort(state.Versions)
 
 		if err = s.writeToolState(binary.Tool, state); err != nil {
 			return err
@@ -215,12 +263,19 @@ func (s *fileSystem) DeleteVersions(binaries ...config.Binary) error {
 			return err
 		}
 
-		for idx, version := range state.Versions {
-			if version == binary.Version {
-				state.Versions = append(state.Versions[:idx], state.Versions[idx+1:]...)
-				break
-			}
+		// Removing the recommended version would leave unpinned invocations resolving to a version the state no longer
+		// offers. Another version needs to be recommended first.
+		if state.RecommendedVersion == binary.Version {
+			s.log.Error("Can not remove the version recommended by the state.", zap.String("tool-name", binary.Tool), zap.String("version", binary.Version))
+			return fmt.Errorf("%w: %s@%s", ErrRecommendedVersion, binary.Tool, binary.Version)
+		}
+
+		idx := slices.Index(state.Versions, binary.Version)
+		if idx < 0 {
+			s.log.Error("Can not remove a version that is not available in the state.", zap.String("tool-name", binary.Tool), zap.String("version", binary.Version))
+			return fmt.Errorf("%w: %s@%s", ErrUnknownVersion, binary.Tool, binary.Version)
 		}
+		state.Versions = slices.Delete(state.Versions, idx, idx+1)
 
 		if err = s.writeToolState(binary.Tool, state); err != nil {
 			return err
@@ -229,6 +284,14 @@ func (s *fileSystem) DeleteVersions(binaries ...config.Binary) error {
 	return nil
 }
 
+// readOrInitToolState behaves like readToolState but returns an empty state for tools that are not yet present.
+func (s *fileSystem) readOrInitToolState(toolName string) (*toolState, error) {
+	if _, err := s.storage.Stat(toolName + ".yaml"); errors.Is(err, os.ErrNotExist) {
+		return &toolState{Name: toolName}, nil
+	}
+	return s.readToolState(toolName)
+}
+
 func (s *fileSystem) readToolState(toolName string) (*toolState, error) {
 	log := s.log.With(zap.String("tool-state", filepath.Join(s.storage.Root(), toolName+".yaml")))
 
@@ -266,7 +329,7 @@ func (s *fileSystem) writeToolState(toolName string, state *toolState) error {
 		return err
 	}
 
-	stateFile, err := s.storage.OpenFile(toolName+".yaml.new", os.O_CREATE|os.O_TRUNC, 0o644)
+	stateFile, err := s.storage.OpenFile(toolName+".yaml.new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
 	if err != nil {
 		log.Error("Unable to open tool state file.", zap.Error(err))
 		return err
@@ -276,10 +339,14 @@ func (s *fileSystem) writeToolState(toolName string, state *toolState) error {
 	if _, err = stateFile.Write(stateContent); err != nil {
 		log.Error("Failed to write new tool state file content.", zap.Error(err))
 		return err
+	} else if err = stateFile.Close(); err != nil {
+		log.Error("Failed to close new tool state file.", zap.Error(err))
+		return err
 	}
 
-	if err = s.storage.Rename(stateFile.Name(), toolName+".yaml"); err != nil {
+	if err = s.storage.Rename(toolName+".yaml.new", toolName+".yaml"); err != nil {
 		log.Error("Unable to move temporary tool state file to permanent position.", zap.Error(err))
+		return err
 	}
 	return nil
 }
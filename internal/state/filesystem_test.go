@@ -0,0 +1,75 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+)
+
+func TestDeniedVersions(t *testing.T) {
+	t.Parallel()
+
+	storage := memfs.New()
+	require.NoError(t, util.WriteFile(storage, "foo.yaml", []byte(`---
+name: foo
+recommended_version: 1.2.0
+versions: [1.1.0, 1.2.0]
+denied:
+  - version: 1.1.0
+    reason: known vulnerability
+`), 0o644))
+
+	cache := &fileSystem{log: zap.NewNop(), refreshInterval: defaultRefreshInterval, storage: storage}
+
+	denied, err := cache.DeniedVersions("foo")
+	require.NoError(t, err)
+	assert.Equal(t, []DeniedVersion{{Version: "1.1.0", Reason: "known vulnerability"}}, denied)
+
+	denied, err = cache.DeniedVersions("bar")
+	require.NoError(t, err)
+	assert.Empty(t, denied)
+}
+
+func TestRecommendVersion(t *testing.T) {
+	t.Parallel()
+
+	storage := memfs.New()
+	require.NoError(t, util.WriteFile(storage, "foo.yaml", []byte("name: foo\nversions: [1.1.0, 1.2.0]\n"), 0o644))
+
+	s := &fileSystem{log: zap.NewNop(), refreshInterval: defaultRefreshInterval, storage: storage}
+
+	require.NoError(t, s.RecommendVersion(config.Binary{Tool: "foo", Version: "1.2.0"}))
+	assert.ErrorIs(t, s.RecommendVersion(config.Binary{Tool: "foo", Version: "2.0.0"}), ErrUnknownVersion)
+
+	recommended, err := s.RecommendedVersion("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", recommended)
+
+	recommended, err = s.RecommendedVersion("bar")
+	require.NoError(t, err)
+	assert.Empty(t, recommended)
+}
+
+func TestDeleteVersions(t *testing.T) {
+	t.Parallel()
+
+	storage := memfs.New()
+	require.NoError(t, util.WriteFile(storage, "foo.yaml", []byte("name: foo\nrecommended_version: 1.2.0\nversions: [1.1.0, 1.2.0]\n"), 0o644))
+
+	s := &fileSystem{log: zap.NewNop(), storage: storage}
+
+	assert.ErrorIs(t, s.DeleteVersions(config.Binary{Tool: "foo", Version: "1.2.0"}), ErrRecommendedVersion)
+	assert.ErrorIs(t, s.DeleteVersions(config.Binary{Tool: "foo", Version: "2.0.0"}), ErrUnknownVersion)
+	require.NoError(t, s.DeleteVersions(config.Binary{Tool: "foo", Version: "1.1.0"}))
+
+	state, err := s.readToolState("foo")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2.0"}, state.Versions)
+	assert.Equal(t, "1.2.0", state.RecommendedVersion)
+}
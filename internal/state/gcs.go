@@ -0,0 +1,107 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	net_http "net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"go.uber.org/zap"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+)
+
+// gcs is a state stored as objects in a GCS bucket. Writes are conditional on the generation of each object so that
+// concurrent modifications are detected rather than silently overwritten.
+type gcs struct {
+	objectState
+
+	client *storage.Client
+	bucket string
+	prefix string
+}
+
+func newGCS(log *zap.Logger, settings *config.State) *gcs {
+	log = log.With(zap.String("state-bucket", fmt.Sprintf("gs://%s/%s", settings.Bucket, settings.Prefix)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := storage.NewClient(ctx, option.WithScopes(storage.ScopeReadWrite))
+	if err != nil {
+		log.Fatal("Unable to set up a GCS storage client.", zap.Error(err))
+	}
+
+	s := &gcs{
+		client: client,
+		bucket: settings.Bucket,
+		prefix: settings.Prefix,
+	}
+	s.objectState = objectState{log: log, store: s}
+	return s
+}
+
+func (s *gcs) get(name string) ([]byte, string, error) {
+	log := s.log.With(zap.String("state-file", name))
+
+	ctx, cancel := context.WithTimeout(context.Background(), objectRequestTimeout)
+	defer cancel()
+
+	r, err := s.client.Bucket(s.bucket).Object(path.Join(s.prefix, name)).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, "", objectNotFound(name)
+	} else if err != nil {
+		log.Error("Unable to open reader on remote GCS object.", zap.Error(err))
+		return nil, "", err
+	}
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		log.Error("Failed to read state file from GCS.", zap.Error(err))
+		return nil, "", err
+	}
+	return content, strconv.FormatInt(r.Attrs.Generation, 10), nil
+}
+
+func (s *gcs) put(name string, content []byte, revision string, exists bool) error {
+	log := s.log.With(zap.String("state-file", name))
+
+	conds := storage.Conditions{DoesNotExist: true}
+	if exists {
+		generation, err := strconv.ParseInt(revision, 10, 64)
+		if err != nil {
+			log.Error("Invalid object generation.", zap.String("generation", revision), zap.Error(err))
+			return err
+		}
+		conds = storage.Conditions{GenerationMatch: generation}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), objectRequestTimeout)
+	defer cancel()
+
+	w := s.client.Bucket(s.bucket).Object(path.Join(s.prefix, name)).If(conds).NewWriter(ctx)
+	w.ContentType = "application/yaml"
+	if _, err := w.Write(content); err != nil {
+		_ = w.Close()
+		log.Error("Failed to write state file to GCS.", zap.Error(err))
+		return err
+	}
+	if err := w.Close(); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == net_http.StatusPreconditionFailed {
+			log.Debug("The state file was modified concurrently.")
+			return fmt.Errorf("%w: %s", ErrConflict, name)
+		}
+		log.Error("Failed to write state file to GCS.", zap.Error(err))
+		return err
+	}
+	return nil
+}
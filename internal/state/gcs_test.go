@@ -0,0 +1,36 @@
+package state
+
+import (
+	"path"
+	"testing"
+
+	"github.com/fsouza/fake-gcs-server/fakestorage"
+	"go.uber.org/zap"
+)
+
+func TestGCS(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bucketName = "test-bucket"
+		prefix     = "toolshare/state"
+	)
+
+	fakeGCS := fakestorage.NewServer([]fakestorage.Object{})
+	t.Cleanup(fakeGCS.Stop)
+	fakeGCS.CreateBucketWithOpts(fakestorage.CreateBucketOpts{Name: bucketName})
+
+	s := &gcs{
+		client: fakeGCS.Client(),
+		bucket: bucketName,
+		prefix: prefix,
+	}
+	s.objectState = objectState{log: zap.NewNop(), store: s}
+
+	testObjectState(t, &s.objectState, func(name string) {
+		fakeGCS.CreateObject(fakestorage.Object{
+			ObjectAttrs: fakestorage.ObjectAttrs{BucketName: bucketName, Name: path.Join(prefix, name)},
+			Content:     []byte("name: concurrent\n"),
+		})
+	})
+}
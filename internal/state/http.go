@@ -1,28 +1,125 @@
 package state
 
 import (
-	"github.com/go-git/go-billy/v5"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	net_http "net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
 
 	"github.com/Helcaraxan/toolshare/internal/config"
 )
 
+var ErrHTTPStatus = errors.New("unexpected HTTP status")
+
+// http is a state served as a set of files relative to a base URL. Modifications are written back by PUT-ing the full
+// content of each modified file. Writes are made conditional on the ETag returned when the file was read, if any, so
+// that servers supporting it can reject concurrent updates.
 type http struct {
-	Root string
-	URL  string
+	objectState
+
+	client *net_http.Client
+	url    string
+	token  string
 }
 
-func (s *http) Fetch(target billy.Filesystem) error {
-	return nil
+func newHTTP(log *zap.Logger, settings *config.State) *http {
+	s := &http{
+		client: &net_http.Client{Timeout: objectRequestTimeout},
+		url:    strings.TrimSuffix(settings.URL, "/"),
+	}
+	if settings.TokenEnv != "" {
+		s.token = os.Getenv(settings.TokenEnv)
+	}
+	s.objectState = objectState{log: log.With(zap.String("state-url", settings.URL)), store: s}
+	return s
 }
 
-func (s *http) RecommendVersion(binary config.Binary) error {
-	return nil
+func (s *http) get(file string) ([]byte, string, error) {
+	log := s.log.With(zap.String("state-file", file))
+
+	req, err := s.newRequest(net_http.MethodGet, file, nil)
+	if err != nil {
+		log.Error("Failed to create HTTP request.", zap.Error(err))
+		return nil, "", err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		log.Error("Failed to retrieve state file.", zap.Error(err))
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case net_http.StatusOK:
+	case net_http.StatusNotFound:
+		return nil, "", objectNotFound(file)
+	default:
+		log.Error("Failed to retrieve state file.", zap.Int("status-code", resp.StatusCode))
+		return nil, "", fmt.Errorf("%w: %s", ErrHTTPStatus, resp.Status)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Error("Failed to read state file.", zap.Error(err))
+		return nil, "", err
+	}
+	return content, resp.Header.Get("ETag"), nil
 }
 
-func (s *http) AddVersions(binaries ...config.Binary) error {
-	return nil
+func (s *http) put(file string, content []byte, etag string, exists bool) error {
+	log := s.log.With(zap.String("state-file", file))
+
+	req, err := s.newRequest(net_http.MethodPut, file, bytes.NewReader(content))
+	if err != nil {
+		log.Error("Failed to create HTTP request.", zap.Error(err))
+		return err
+	}
+	req.Header.Set("Content-Type", "application/yaml")
+	switch {
+	case exists && etag != "":
+		req.Header.Set("If-Match", etag)
+	case !exists:
+		req.Header.Set("If-None-Match", "*")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		log.Error("Failed to upload state file.", zap.Error(err))
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case net_http.StatusOK, net_http.StatusCreated, net_http.StatusNoContent:
+		return nil
+	case net_http.StatusPreconditionFailed:
+		log.Debug("The state file was modified concurrently.")
+		return fmt.Errorf("%w: %s", ErrConflict, file)
+	default:
+		log.Error("Failed to upload state file.", zap.Int("status-code", resp.StatusCode))
+		return fmt.Errorf("%w: %s", ErrHTTPStatus, resp.Status)
+	}
 }
 
-func (s *http) DeleteVersions(binaries ...config.Binary) error {
-	return nil
+func (s *http) newRequest(method string, file string, body io.Reader) (*net_http.Request, error) {
+	target, err := url.JoinPath(s.url, file)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := net_http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	return req, nil
 }
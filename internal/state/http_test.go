@@ -0,0 +1,177 @@
+package state
+
+import (
+	"io"
+	net_http "net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+)
+
+const testHTTPToken = "secret-token"
+
+// httpStateServer is a minimal in-memory implementation of the HTTP state protocol that honours conditional writes.
+type httpStateServer struct {
+	mu       sync.Mutex
+	files    map[string]string
+	versions map[string]int
+	noETags  bool
+}
+
+func newHTTPStateServer(t *testing.T, files map[string]string) (*httptest.Server, *httpStateServer) {
+	state := &httpStateServer{files: files, versions: map[string]int{}}
+	srv := httptest.NewServer(state)
+	t.Cleanup(srv.Close)
+	return srv, state
+}
+
+func (s *httpStateServer) ServeHTTP(w net_http.ResponseWriter, r *net_http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := strings.TrimPrefix(r.URL.Path, "/state/")
+	content, exists := s.files[name]
+	etag := `"` + strconv.Itoa(s.versions[name]) + `"`
+
+	switch r.Method {
+	case net_http.MethodGet:
+		if !exists {
+			w.WriteHeader(net_http.StatusNotFound)
+			return
+		}
+		if !s.noETags {
+			w.Header().Set("ETag", etag)
+		}
+		_, _ = io.WriteString(w, content)
+
+	case net_http.MethodPut:
+		if r.Header.Get("Authorization") != "Bearer "+testHTTPToken {
+			w.WriteHeader(net_http.StatusUnauthorized)
+			return
+		}
+		if (r.Header.Get("If-None-Match") == "*" && exists) || (r.Header.Get("If-Match") != "" && r.Header.Get("If-Match") != etag) {
+			w.WriteHeader(net_http.StatusPreconditionFailed)
+			return
+		}
+		raw, _ := io.ReadAll(r.Body)
+		s.files[name] = string(raw)
+		s.versions[name]++
+		w.WriteHeader(net_http.StatusNoContent)
+
+	default:
+		w.WriteHeader(net_http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *httpStateServer) tool(t *testing.T, name string) toolState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var state toolState
+	require.NoError(t, yaml.Unmarshal([]byte(s.files[name+".yaml"]), &state))
+	return state
+}
+
+func TestHTTPFetch(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := newHTTPStateServer(t, map[string]string{
+		"index.yaml": "tools: [foo, bar]\n",
+		"foo.yaml":   "name: foo\nrecommended_version: 1.2.0\nversions: [1.1.0, 1.2.0]\n",
+		"bar.yaml":   "name: bar\nversions: [0.1.0]\n",
+	})
+
+	target := memfs.New()
+	require.NoError(t, util.WriteFile(target, "stale.yaml", []byte("name: stale\n"), 0o644))
+
+	s := newHTTP(zap.NewNop(), &config.State{Type: config.StateTypeHTTP, URL: srv.URL + "/state"})
+	require.NoError(t, s.Fetch(target))
+
+	cache := &fileSystem{log: zap.NewNop(), refreshInterval: defaultRefreshInterval, storage: target}
+	tools, err := cache.AvailableTools()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"foo", "bar"}, tools)
+
+	recommended, err := cache.RecommendedVersion("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", recommended)
+}
+
+func TestHTTPUpdate(t *testing.T) {
+	t.Setenv("TOOLSHARE_TEST_STATE_TOKEN", testHTTPToken)
+
+	srv, server := newHTTPStateServer(t, map[string]string{
+		"index.yaml": "tools: [foo]\n",
+		"foo.yaml":   "name: foo\nrecommended_version: 1.1.0\nversions: [1.1.0]\n",
+	})
+	settings := &config.State{Type: config.StateTypeHTTP, URL: srv.URL + "/state", TokenEnv: "TOOLSHARE_TEST_STATE_TOKEN"}
+	s := newHTTP(zap.NewNop(), settings)
+
+	require.NoError(t, s.AddVersions(
+		config.Binary{Tool: "foo", Version: "1.2.0"},
+		config.Binary{Tool: "bar", Version: "0.1.0"},
+	))
+	assert.Equal(t, []string{"1.1.0", "1.2.0"}, server.tool(t, "foo").Versions)
+	assert.Equal(t, []string{"0.1.0"}, server.tool(t, "bar").Versions)
+	assert.Equal(t, "tools:\n- bar\n- foo\n", server.files["index.yaml"])
+
+	require.NoError(t, s.RecommendVersion(config.Binary{Tool: "foo", Version: "1.2.0"}))
+	assert.Equal(t, "1.2.0", server.tool(t, "foo").RecommendedVersion)
+
+	require.NoError(t, s.DeleteVersions(config.Binary{Tool: "foo", Version: "1.1.0"}))
+	assert.Equal(t, []string{"1.2.0"}, server.tool(t, "foo").Versions)
+
+	// Writes without valid credentials are rejected.
+	t.Setenv("TOOLSHARE_TEST_STATE_TOKEN", "")
+	unauthenticated := newHTTP(zap.NewNop(), settings)
+	assert.ErrorIs(t, unauthenticated.AddVersions(config.Binary{Tool: "foo", Version: "1.3.0"}), ErrHTTPStatus)
+}
+
+func TestHTTPUpdateWithoutETags(t *testing.T) {
+	t.Parallel()
+
+	srv, server := newHTTPStateServer(t, map[string]string{
+		"index.yaml": "tools: [foo]\n",
+		"foo.yaml":   "name: foo\nversions: [1.1.0]\n",
+	})
+	server.noETags = true
+	s := newHTTP(zap.NewNop(), &config.State{Type: config.StateTypeHTTP, URL: srv.URL + "/state"})
+	s.token = testHTTPToken
+
+	// Without revisions the existence of the index must still be known for new tools to be added to it.
+	require.NoError(t, s.AddVersions(config.Binary{Tool: "bar", Version: "0.1.0"}))
+	assert.Equal(t, []string{"0.1.0"}, server.tool(t, "bar").Versions)
+	assert.Equal(t, "tools:\n- bar\n- foo\n", server.files["index.yaml"])
+}
+
+func TestHTTPConcurrentUpdate(t *testing.T) {
+	t.Parallel()
+
+	srv, server := newHTTPStateServer(t, map[string]string{
+		"index.yaml": "tools: [foo]\n",
+		"foo.yaml":   "name: foo\nversions: [1.1.0]\n",
+	})
+	s := newHTTP(zap.NewNop(), &config.State{Type: config.StateTypeHTTP, URL: srv.URL + "/state"})
+	s.token = testHTTPToken
+
+	// Simulate a concurrent modification between the read and the write of the tool's state.
+	err := s.update([]string{"foo"}, func(tempState *fileSystem) error {
+		server.mu.Lock()
+		server.versions["foo.yaml"]++
+		server.mu.Unlock()
+		return tempState.AddVersions(config.Binary{Tool: "foo", Version: "1.2.0"})
+	})
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.Equal(t, []string{"1.1.0"}, server.tool(t, "foo").Versions)
+}
@@ -0,0 +1,204 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/goccy/go-yaml"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+)
+
+// Object-based states are stored as a flat set of files:
+//   - 'index.yaml' lists the names of all tools present in the state.
+//   - '<tool>.yaml' contains the state of an individual tool.
+const objectIndexFile = "index.yaml"
+
+const objectRequestTimeout = time.Minute
+
+// objectUpdateAttempts is the number of times an update is attempted when it conflicts with concurrent ones.
+const objectUpdateAttempts = 5
+
+var ErrConflict = errors.New("the remote state was modified concurrently")
+
+// objectStore provides access to the files of an object-based state. Implementations must return an error wrapping
+// os.ErrNotExist for files that do not exist and one wrapping ErrConflict when a conditional write fails.
+type objectStore interface {
+	// get returns the content of a file together with an opaque revision identifier, such as an ETag or generation.
+	get(name string) ([]byte, string, error)
+	// put writes the content of a file. When the file is known to exist the write must only succeed if the file's
+	// revision still matches the provided one, if any. Otherwise the write must only succeed if the file does not
+	// exist yet.
+	put(name string, content []byte, revision string, exists bool) error
+}
+
+type objectIndex struct {
+	Tools []string `json:"tools"`
+}
+
+type objectState struct {
+	log   *zap.Logger
+	store objectStore
+}
+
+func (s *objectState) Fetch(target billy.Filesystem) error {
+	index, _, _, err := s.readIndex()
+	if err != nil {
+		return err
+	}
+
+	state := memfs.New()
+	for _, tool := range index.Tools {
+		content, _, err := s.store.get(tool + ".yaml")
+		if err != nil {
+			return err
+		}
+		if err = util.WriteFile(state, tool+".yaml", content, 0o644); err != nil {
+			s.log.Error("Failed to buffer tool state.", zap.String("tool-name", tool), zap.Error(err))
+			return err
+		}
+	}
+
+	tempState := &fileSystem{
+		log:     s.log,
+		storage: state,
+	}
+	return tempState.Fetch(target)
+}
+
+func (s *objectState) RecommendVersion(binary config.Binary) error {
+	return s.update([]string{binary.Tool}, func(tempState *fileSystem) error {
+		return tempState.RecommendVersion(binary)
+	})
+}
+
+func (s *objectState) AddVersions(binaries ...config.Binary) error {
+	return s.update(toolNames(binaries), func(tempState *fileSystem) error {
+		return tempState.AddVersions(binaries...)
+	})
+}
+
+func (s *objectState) DeleteVersions(binaries ...config.Binary) error {
+	return s.update(toolNames(binaries), func(tempState *fileSystem) error {
+		return tempState.DeleteVersions(binaries...)
+	})
+}
+
+// update retrieves the state of the given tools, applies the modification and writes back the resulting state files
+// as well as the index if any new tools were added. Conflicts with concurrent updates are resolved by retrying the whole
+// update on top of the state as modified by those.
+func (s *objectState) update(tools []string, modify func(*fileSystem) error) error {
+	if len(tools) == 0 {
+		return nil
+	}
+
+	var err error
+	for attempt := 1; attempt <= objectUpdateAttempts; attempt++ {
+		if err = s.tryUpdate(tools, modify); !errors.Is(err, ErrConflict) {
+			return err
+		}
+		s.log.Debug("The remote state was modified concurrently. Retrying the update.", zap.Int("attempt", attempt))
+	}
+	s.log.Error("The remote state keeps being modified concurrently. Giving up on the update.", zap.Error(err))
+	return err
+}
+
+func (s *objectState) tryUpdate(tools []string, modify func(*fileSystem) error) error {
+	index, indexRevision, indexExists, err := s.readIndex()
+	if err != nil {
+		return err
+	}
+
+	// Whether a tool's file exists is determined from the file itself rather than from the index, as a previous update
+	// may have created the file without managing to add the tool to the index.
+	state := memfs.New()
+	revisions := map[string]string{}
+	for _, tool := range tools {
+		content, revision, err := s.store.get(tool + ".yaml")
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err = util.WriteFile(state, tool+".yaml", content, 0o644); err != nil {
+			s.log.Error("Failed to buffer tool state.", zap.String("tool-name", tool), zap.Error(err))
+			return err
+		}
+		revisions[tool] = revision
+	}
+
+	if err = modify(&fileSystem{log: s.log, storage: state}); err != nil {
+		return err
+	}
+
+	var newTools bool
+	for _, tool := range tools {
+		content, err := util.ReadFile(state, tool+".yaml")
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			s.log.Error("Failed to read modified tool state.", zap.String("tool-name", tool), zap.Error(err))
+			return err
+		}
+		revision, exists := revisions[tool]
+		if err = s.store.put(tool+".yaml", content, revision, exists); err != nil {
+			return err
+		}
+		if !slices.Contains(index.Tools, tool) {
+			index.Tools = append(index.Tools, tool)
+			newTools = true
+		}
+	}
+	if !newTools {
+		return nil
+	}
+
+	sort.Strings(index.Tools)
+	content, err := yaml.Marshal(index)
+	if err != nil {
+		s.log.Error("Failed to marshal the state index.", zap.Error(err))
+		return err
+	}
+	return s.store.put(objectIndexFile, content, indexRevision, indexExists)
+}
+
+// readIndex returns the state's index together with its revision and whether it exists. The latter can not be derived
+// from the revision as not all stores provide one, e.g. HTTP servers that do not set an 'ETag' header.
+func (s *objectState) readIndex() (*objectIndex, string, bool, error) {
+	content, revision, err := s.store.get(objectIndexFile)
+	if errors.Is(err, os.ErrNotExist) {
+		// A missing index denotes a new and hence empty state.
+		return &objectIndex{}, "", false, nil
+	} else if err != nil {
+		return nil, "", false, err
+	}
+
+	var index objectIndex
+	if err = yaml.Unmarshal(content, &index); err != nil {
+		s.log.Error("Unable to unmarshal the state index.", zap.Error(err))
+		return nil, "", false, err
+	}
+	return &index, revision, true, nil
+}
+
+func toolNames(binaries []config.Binary) []string {
+	var names []string
+	for _, binary := range binaries {
+		if !slices.Contains(names, binary.Tool) {
+			names = append(names, binary.Tool)
+		}
+	}
+	return names
+}
+
+func objectNotFound(name string) error {
+	return fmt.Errorf("%s: %w", name, os.ErrNotExist)
+}
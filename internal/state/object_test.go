@@ -0,0 +1,141 @@
+package state
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+)
+
+// testObjectState exercises an object-based state. The provided callback must modify the given file of the state
+// out-of-band in order to simulate a concurrent update.
+func testObjectState(t *testing.T, s *objectState, modifyConcurrently func(name string)) {
+	t.Helper()
+
+	require.NoError(t, s.AddVersions(
+		config.Binary{Tool: "foo", Version: "1.1.0"},
+		config.Binary{Tool: "foo", Version: "1.2.0"},
+		config.Binary{Tool: "bar", Version: "0.1.0"},
+	))
+	require.NoError(t, s.RecommendVersion(config.Binary{Tool: "foo", Version: "1.2.0"}))
+	require.NoError(t, s.DeleteVersions(config.Binary{Tool: "foo", Version: "1.1.0"}))
+
+	target := memfs.New()
+	require.NoError(t, s.Fetch(target))
+
+	cache := &fileSystem{log: zap.NewNop(), refreshInterval: defaultRefreshInterval, storage: target}
+	tools, err := cache.AvailableTools()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"foo", "bar"}, tools)
+
+	versions, err := cache.AvailableVersions("foo")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2.0"}, versions)
+
+	recommended, err := cache.RecommendedVersion("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", recommended)
+
+	// A modification that happens between reading and writing a tool's state must not be lost. The update is instead
+	// retried on top of it.
+	err = s.update([]string{"foo"}, concurrentlyOnce(modifyConcurrently, "foo.yaml", func(tempState *fileSystem) error {
+		return tempState.AddVersions(config.Binary{Tool: "foo", Version: "1.3.0"})
+	}))
+	require.NoError(t, err)
+	content, _, err := s.store.get("foo.yaml")
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "concurrent")
+	assert.Contains(t, string(content), "1.3.0")
+
+	// The same holds for the creation of new tools.
+	err = s.update([]string{"baz"}, concurrentlyOnce(modifyConcurrently, "baz.yaml", func(tempState *fileSystem) error {
+		return tempState.AddVersions(config.Binary{Tool: "baz", Version: "2.0.0"})
+	}))
+	require.NoError(t, err)
+	index, _, _, err := s.readIndex()
+	require.NoError(t, err)
+	assert.Contains(t, index.Tools, "baz")
+}
+
+// concurrentlyOnce wraps a modification so that the given file is modified concurrently on its first application only.
+func concurrentlyOnce(modifyConcurrently func(name string), name string, modify func(*fileSystem) error) func(*fileSystem) error {
+	var done bool
+	return func(tempState *fileSystem) error {
+		if !done {
+			modifyConcurrently(name)
+			done = true
+		}
+		return modify(tempState)
+	}
+}
+
+// memoryObjectStore is an object store held in memory that fails a configurable number of writes to each file with a
+// conflict.
+type memoryObjectStore struct {
+	files     map[string][]byte
+	revisions map[string]int
+	conflicts map[string]int
+}
+
+func (s *memoryObjectStore) get(name string) ([]byte, string, error) {
+	content, ok := s.files[name]
+	if !ok {
+		return nil, "", objectNotFound(name)
+	}
+	return content, strconv.Itoa(s.revisions[name]), nil
+}
+
+func (s *memoryObjectStore) put(name string, content []byte, revision string, exists bool) error {
+	if s.conflicts[name] > 0 {
+		s.conflicts[name]--
+		return fmt.Errorf("%w: %s", ErrConflict, name)
+	}
+	if _, found := s.files[name]; found != exists || (exists && revision != strconv.Itoa(s.revisions[name])) {
+		return fmt.Errorf("%w: %s", ErrConflict, name)
+	}
+	s.files[name] = content
+	s.revisions[name]++
+	return nil
+}
+
+func TestObjectStateIndexConflict(t *testing.T) {
+	t.Parallel()
+
+	store := &memoryObjectStore{files: map[string][]byte{}, revisions: map[string]int{}, conflicts: map[string]int{}}
+	s := &objectState{log: zap.NewNop(), store: store}
+
+	// A single conflict on the index is retried transparently.
+	store.conflicts[objectIndexFile] = 1
+	require.NoError(t, s.AddVersions(config.Binary{Tool: "foo", Version: "1.0.0"}))
+
+	// Persistent conflicts on the index leave the tool's state file behind without an index entry.
+	store.conflicts[objectIndexFile] = objectUpdateAttempts
+	require.ErrorIs(t, s.AddVersions(config.Binary{Tool: "bar", Version: "0.1.0"}), ErrConflict)
+	require.Contains(t, store.files, "bar.yaml")
+
+	// The next update of that tool must still succeed and add it to the index.
+	require.NoError(t, s.AddVersions(config.Binary{Tool: "bar", Version: "0.2.0"}))
+
+	index, _, _, err := s.readIndex()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar", "foo"}, index.Tools)
+
+	versions, err := (&fileSystem{log: zap.NewNop(), storage: fetchState(t, s)}).AvailableVersions("bar")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0.1.0", "0.2.0"}, versions)
+}
+
+func fetchState(t *testing.T, s *objectState) billy.Filesystem {
+	t.Helper()
+
+	target := memfs.New()
+	require.NoError(t, s.Fetch(target))
+	return target
+}
@@ -0,0 +1,109 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	net_http "net/http"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_config "github.com/aws/aws-sdk-go-v2/config"
+	s3_lib "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithy_http "github.com/aws/smithy-go/transport/http"
+	"go.uber.org/zap"
+
+	"github.com/Helcaraxan/toolshare/internal/config"
+)
+
+// s3 is a state stored as objects in an S3 bucket. Writes are conditional on the ETag of each object so that
+// concurrent modifications are detected rather than silently overwritten.
+type s3 struct {
+	objectState
+
+	client *s3_lib.Client
+	bucket string
+	prefix string
+}
+
+func newS3(log *zap.Logger, settings *config.State) *s3 {
+	log = log.With(zap.String("state-bucket", fmt.Sprintf("s3://%s/%s", settings.Bucket, settings.Prefix)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), objectRequestTimeout)
+	defer cancel()
+
+	cfg, err := aws_config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatal("Failed to load AWS configuration from environment.", zap.Error(err))
+	}
+
+	s := &s3{
+		client: s3_lib.NewFromConfig(cfg),
+		bucket: settings.Bucket,
+		prefix: settings.Prefix,
+	}
+	s.objectState = objectState{log: log, store: s}
+	return s
+}
+
+func (s *s3) get(name string) ([]byte, string, error) {
+	log := s.log.With(zap.String("state-file", name))
+
+	ctx, cancel := context.WithTimeout(context.Background(), objectRequestTimeout)
+	defer cancel()
+
+	out, err := s.client.GetObject(ctx, &s3_lib.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, name)),
+	})
+	if err != nil {
+		var s3err *types.NoSuchKey
+		if errors.As(err, &s3err) {
+			return nil, "", objectNotFound(name)
+		}
+		log.Error("Failed to lookup state file on S3.", zap.Error(err))
+		return nil, "", err
+	}
+	defer out.Body.Close()
+
+	content, err := io.ReadAll(out.Body)
+	if err != nil {
+		log.Error("Failed to read state file from S3.", zap.Error(err))
+		return nil, "", err
+	}
+	return content, aws.ToString(out.ETag), nil
+}
+
+func (s *s3) put(name string, content []byte, revision string, exists bool) error {
+	log := s.log.With(zap.String("state-file", name))
+
+	input := &s3_lib.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(path.Join(s.prefix, name)),
+		Body:        bytes.NewReader(content),
+		ContentType: aws.String("application/yaml"),
+	}
+	switch {
+	case exists && revision != "":
+		input.IfMatch = aws.String(revision)
+	case !exists:
+		input.IfNoneMatch = aws.String("*")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), objectRequestTimeout)
+	defer cancel()
+
+	if _, err := s.client.PutObject(ctx, input); err != nil {
+		var respErr *smithy_http.ResponseError
+		if errors.As(err, &respErr) && respErr.HTTPStatusCode() == net_http.StatusPreconditionFailed {
+			log.Debug("The state file was modified concurrently.")
+			return fmt.Errorf("%w: %s", ErrConflict, name)
+		}
+		log.Error("Failed to write state file to S3.", zap.Error(err))
+		return err
+	}
+	return nil
+}
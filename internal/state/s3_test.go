@@ -0,0 +1,72 @@
+package state
+
+import (
+	"bytes"
+	"encoding/hex"
+	net_http "net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3_lib "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestS3(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bucketName = "test-bucket"
+		prefix     = "toolshare/state"
+	)
+
+	backend := s3mem.New()
+	require.NoError(t, backend.CreateBucket(bucketName))
+
+	srv := httptest.NewServer(withS3Preconditions(backend, gofakes3.New(backend).Server()))
+	t.Cleanup(srv.Close)
+
+	s := &s3{
+		client: s3_lib.New(s3_lib.Options{
+			BaseEndpoint: aws.String(srv.URL),
+			Credentials:  aws.AnonymousCredentials{},
+			Region:       "local",
+			UsePathStyle: true,
+		}),
+		bucket: bucketName,
+		prefix: prefix,
+	}
+	s.objectState = objectState{log: zap.NewNop(), store: s}
+
+	testObjectState(t, &s.objectState, func(name string) {
+		content := []byte("name: concurrent\n")
+		_, err := backend.PutObject(bucketName, path.Join(prefix, name), map[string]string{}, bytes.NewReader(content), int64(len(content)))
+		require.NoError(t, err)
+	})
+}
+
+// withS3Preconditions enforces the 'If-Match' and 'If-None-Match' preconditions on object uploads which are not
+// supported by gofakes3 itself.
+func withS3Preconditions(backend *s3mem.Backend, next net_http.Handler) net_http.Handler {
+	return net_http.HandlerFunc(func(w net_http.ResponseWriter, r *net_http.Request) {
+		ifMatch, ifNoneMatch := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+		if r.Method == net_http.MethodPut && (ifMatch != "" || ifNoneMatch != "") {
+			bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+			var etag string
+			if obj, err := backend.HeadObject(bucket, key); err == nil {
+				etag = `"` + hex.EncodeToString(obj.Hash) + `"`
+			}
+			if (ifNoneMatch == "*" && etag != "") || (ifMatch != "" && ifMatch != etag) {
+				w.WriteHeader(net_http.StatusPreconditionFailed)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
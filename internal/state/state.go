@@ -1,27 +1,38 @@
 package state
 
 import (
+	"errors"
 	"time"
 
 	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/go-git/go-billy/v5/osfs"
 	"go.uber.org/zap"
 
 	"github.com/Helcaraxan/toolshare/internal/config"
 )
 
+var (
+	ErrNoRemote           = errors.New("no remote state configured")
+	ErrUnknownVersion     = errors.New("version is not available in the state")
+	ErrRecommendedVersion = errors.New("version is recommended by the state")
+)
+
 var (
 	// To guarantee that implementations remain compatible with the interface.
 	_ Cache = &fileSystem{}
 
+	_ State = &gcs{}
 	_ State = &git{}
 	_ State = &http{}
+	_ State = &s3{}
 	_ State = &fileSystem{}
 )
 
 type Cache interface {
 	AvailableTools() ([]string, error)
 	AvailableVersions(tool string) ([]string, error)
+	DeniedVersions(tool string) ([]DeniedVersion, error)
 	RecommendedVersion(tool string) (string, error)
 	Refresh(force bool) error
 }
@@ -33,22 +44,66 @@ type State interface {
 	DeleteVersions(binaries ...config.Binary) error
 }
 
+const defaultRefreshInterval = 24 * time.Hour
+
 func NewCache(log *zap.Logger, localRoot string, settings *config.State) Cache {
+	refreshInterval := settings.RefreshInterval
+	if refreshInterval == 0 {
+		refreshInterval = defaultRefreshInterval
+	}
+
 	cache := &fileSystem{
 		log:             log,
-		refreshInterval: settings.RefreshInterval,
+		refreshInterval: refreshInterval,
 		storage:         osfs.New(localRoot),
 	}
 
-	if settings.Local != "" {
-		cache.remote = &fileSystem{
+	cache.remote = newRemote(log, settings)
+	return cache
+}
+
+// New returns the remote state selected by the given settings.
+func New(log *zap.Logger, settings *config.State) (State, error) {
+	remote := newRemote(log, settings)
+	if remote == nil {
+		log.Error("The state configuration does not designate a remote state.", zap.String("type", string(settings.Type)))
+		return nil, ErrNoRemote
+	}
+	return remote, nil
+}
+
+// Snapshot retrieves the current content of a remote state into memory, bypassing the local state cache.
+func Snapshot(log *zap.Logger, remote State) (Cache, error) {
+	storage := memfs.New()
+	if err := remote.Fetch(storage); err != nil {
+		return nil, err
+	}
+	return &fileSystem{
+		log:             log,
+		refreshInterval: defaultRefreshInterval,
+		storage:         storage,
+	}, nil
+}
+
+func newRemote(log *zap.Logger, settings *config.State) State {
+	switch settings.Type {
+	case config.StateTypeGCS:
+		return newGCS(log, settings)
+	case config.StateTypeGit:
+		return &git{log: log, url: settings.URL}
+	case config.StateTypeHTTP:
+		return newHTTP(log, settings)
+	case config.StateTypeS3:
+		return newS3(log, settings)
+	default:
+		if settings.Local == "" {
+			return nil
+		}
+		return &fileSystem{
 			log:     log,
 			storage: osfs.New(settings.Local),
 		}
-		return cache
 	}
-
-	return cache
 }
 
 type refreshState struct {
@@ -56,7 +111,14 @@ type refreshState struct {
 }
 
 type toolState struct {
-	Name               string   `json:"name"`
-	RecommendedVersion string   `json:"recommended_version"`
-	Versions           []string `json:"versions"`
+	Name               string          `json:"name"`
+	RecommendedVersion string          `json:"recommended_version"`
+	Versions           []string        `json:"versions"`
+	Denied             []DeniedVersion `json:"denied,omitempty"`
+}
+
+// DeniedVersion is a version of a tool that must not be used, e.g. due to a known vulnerability.
+type DeniedVersion struct {
+	Version string `json:"version"`
+	Reason  string `json:"reason"`
 }
@@ -0,0 +1,261 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidConstraint = errors.New("invalid version constraint")
+
+// Pins that follow the newest release of a tool's source, either excluding or including pre-releases.
+const (
+	Latest           = "latest"
+	LatestPrerelease = "latest-prerelease"
+)
+
+// Constraint restricts the versions of a tool that may be used. It consists of a comma-separated list of comparisons
+// which must all be satisfied:
+//
+//   - '^1.64' allows any version that does not change the left-most non-zero component, i.e. '>=1.64.0,<2.0.0'.
+//   - '~3.10.0' allows patch releases if a minor version is specified, i.e. '>=3.10.0,<3.11.0'.
+//   - '>=', '>', '<=', '<' and '=' compare against the given version. Versions with omitted components, such as
+//     '<=1.64', cover all versions with that prefix.
+//   - 'latest' and 'latest-prerelease' allow any version but are only resolved against the releases of a tool's source.
+//
+// Pre-releases only satisfy a constraint if one of its comparisons itself refers to a pre-release, or if it contains
+// 'latest-prerelease'.
+type Constraint struct {
+	exprs      []string
+	bounds     []bound
+	prerelease bool
+	latest     bool
+}
+
+type bound struct {
+	op      string
+	version string
+}
+
+// IsConstraint reports whether a pin is a version constraint rather than an exact version.
+func IsConstraint(pin string) bool {
+	pin = strings.TrimSpace(pin)
+	return pin == Latest || pin == LatestPrerelease || strings.Contains(pin, ",") || strings.IndexAny(pin, "^~<>=") == 0
+}
+
+// ParseConstraint parses a constraint such as '^1.64', '~3.10.0' or '>=1.11,<2'.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{}
+	for _, expr := range strings.Split(s, ",") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			return nil, fmt.Errorf("%w %q: empty comparison", ErrInvalidConstraint, s)
+		}
+		if expr == Latest || expr == LatestPrerelease {
+			c.exprs = append(c.exprs, expr)
+			c.latest = true
+			c.prerelease = c.prerelease || expr == LatestPrerelease
+			continue
+		}
+
+		op := ""
+		for _, candidate := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(expr, candidate) {
+				op = candidate
+				break
+			}
+		}
+		v := strings.TrimSpace(strings.TrimPrefix(expr, op))
+		bounds, err := expand(op, v)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %w", ErrInvalidConstraint, s, err)
+		}
+
+		c.exprs = append(c.exprs, op+v)
+		c.bounds = append(c.bounds, bounds...)
+		c.prerelease = c.prerelease || IsPrerelease(v)
+	}
+	return c, nil
+}
+
+// expand translates a comparison into bounds on full versions.
+func expand(op string, v string) ([]bound, error) {
+	components, partial, err := parseComponents(v)
+	if err != nil {
+		return nil, err
+	}
+	lower := bound{op: ">=", version: v}
+	if partial {
+		lower.version = format(components)
+	}
+
+	switch op {
+	case "^":
+		// The upper bound increments the left-most non-zero component amongst those that were specified.
+		idx := 0
+		for idx < len(components)-1 && components[idx] == 0 {
+			idx++
+		}
+		return []bound{lower, {op: "<", version: bump(components, idx)}}, nil
+	case "~":
+		return []bound{lower, {op: "<", version: bump(components, min(1, len(components)-1))}}, nil
+	case ">=":
+		return []bound{lower}, nil
+	case ">":
+		if partial {
+			return []bound{{op: ">=", version: bump(components, len(components)-1)}}, nil
+		}
+		return []bound{{op: ">", version: v}}, nil
+	case "<=":
+		if partial {
+			return []bound{{op: "<", version: bump(components, len(components)-1)}}, nil
+		}
+		return []bound{{op: "<=", version: v}}, nil
+	case "<":
+		return []bound{{op: "<", version: lower.version}}, nil
+	default:
+		if partial {
+			return []bound{lower, {op: "<", version: bump(components, len(components)-1)}}, nil
+		}
+		return []bound{{op: "=", version: v}}, nil
+	}
+}
+
+// parseComponents returns the leading major, minor and patch components of a version. A version is partial if it
+// omits any of these and has no further suffix.
+func parseComponents(v string) ([]int, bool, error) {
+	rest := strings.TrimPrefix(v, "v")
+	var components []int
+	for len(components) < 3 {
+		end := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if end < 0 {
+			end = len(rest)
+		}
+		n, err := strconv.Atoi(rest[:end])
+		if err != nil {
+			break
+		}
+		components = append(components, n)
+		rest = rest[end:]
+		if !strings.HasPrefix(rest, ".") || len(components) == 3 {
+			break
+		}
+		rest = rest[1:]
+	}
+	if len(components) == 0 {
+		return nil, false, fmt.Errorf("%q is not a version", v)
+	}
+	return components, len(components) < 3 && rest == "", nil
+}
+
+func bump(components []int, idx int) string {
+	bumped := make([]int, idx+1)
+	copy(bumped, components)
+	bumped[idx]++
+	return format(bumped)
+}
+
+func format(components []int) string {
+	full := [3]int{}
+	copy(full[:], components)
+	return fmt.Sprintf("%d.%d.%d", full[0], full[1], full[2])
+}
+
+// IsPrerelease reports whether a version contains non-numeric segments, such as '1.2.0-rc1'.
+func IsPrerelease(v string) bool {
+	for _, segment := range versionSegments(v) {
+		if _, err := strconv.Atoi(segment); err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Constraint) String() string {
+	if c == nil {
+		return ""
+	}
+	return strings.Join(c.exprs, ", ")
+}
+
+// Latest reports whether the constraint contains a 'latest' or 'latest-prerelease' pin.
+func (c *Constraint) Latest() bool {
+	return c != nil && c.latest
+}
+
+// Prereleases reports whether pre-releases may satisfy the constraint.
+func (c *Constraint) Prereleases() bool {
+	return c.prerelease
+}
+
+// Check reports whether the version satisfies the constraint. Whether the version is a pre-release is inferred from it
+// containing non-numeric segments.
+func (c *Constraint) Check(v string) bool {
+	return c.CheckRelease(v, IsPrerelease(v))
+}
+
+// CheckRelease reports whether the version of a release satisfies the constraint. Whether the release is a pre-release
+// is given by the caller, e.g. as reported by the tool's source, rather than inferred from its version. This allows for
+// tags with non-numeric segments, such as 'jq-1.7.1', to be treated as the stable releases they are.
+func (c *Constraint) CheckRelease(v string, prerelease bool) bool {
+	if !c.prerelease && prerelease {
+		return false
+	}
+	// Versions with omitted components, such as '1.20', are equivalent to those with zero-valued ones.
+	if components, partial, err := parseComponents(v); err == nil && partial {
+		v = format(components)
+	}
+	for _, b := range c.bounds {
+		cmp := Compare(v, b.version)
+		var ok bool
+		switch b.op {
+		case ">=":
+			ok = cmp >= 0
+		case ">":
+			ok = cmp > 0
+		case "<=":
+			ok = cmp <= 0
+		case "<":
+			ok = cmp < 0
+		case "=":
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Highest returns the newest of the given versions that satisfies the constraint, or an empty string if none does.
+func (c *Constraint) Highest(versions []string) string {
+	return c.HighestRelease(versions, IsPrerelease)
+}
+
+// HighestRelease behaves like Highest but determines whether a version is a pre-release with the given function.
+func (c *Constraint) HighestRelease(versions []string, isPrerelease func(v string) bool) string {
+	var highest string
+	for _, v := range versions {
+		if c.CheckRelease(v, isPrerelease(v)) && (highest == "" || Compare(v, highest) > 0) {
+			highest = v
+		}
+	}
+	return highest
+}
+
+// And returns a constraint that is satisfied only by versions that satisfy both constraints. Either may be nil.
+func (c *Constraint) And(other *Constraint) *Constraint {
+	switch {
+	case c == nil:
+		return other
+	case other == nil:
+		return c
+	}
+	return &Constraint{
+		exprs:      append(append([]string{}, c.exprs...), other.exprs...),
+		bounds:     append(append([]bound{}, c.bounds...), other.bounds...),
+		prerelease: c.prerelease || other.prerelease,
+		latest:     c.latest || other.latest,
+	}
+}
@@ -0,0 +1,103 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsConstraint(t *testing.T) {
+	t.Parallel()
+
+	for _, pin := range []string{"^1.64", "~3.10.0", ">=1.11,<2", "<2", "=1.2.3", "1.2, 1.3", "latest", "latest-prerelease"} {
+		assert.True(t, IsConstraint(pin), pin)
+	}
+	for _, pin := range []string{"1.64.2", "v1.2.3", "2024-01-15", "latest-1.2"} {
+		assert.False(t, IsConstraint(pin), pin)
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		matching    []string
+		nonMatching []string
+	}{
+		"^1.64":             {matching: []string{"1.64.0", "v1.64.3", "1.99.0"}, nonMatching: []string{"1.63.9", "2.0.0", "1.65.0-rc1"}},
+		"^0.3":              {matching: []string{"0.3.0", "0.3.7"}, nonMatching: []string{"0.2.9", "0.4.0", "1.0.0"}},
+		"^0.0.3":            {matching: []string{"0.0.3"}, nonMatching: []string{"0.0.4", "0.1.0"}},
+		"~3.10.0":           {matching: []string{"3.10.0", "3.10.12"}, nonMatching: []string{"3.9.9", "3.11.0"}},
+		"~3":                {matching: []string{"3.0.0", "3.99.1"}, nonMatching: []string{"2.9.9", "4.0.0"}},
+		">=1.11,<2":         {matching: []string{"1.11.0", "1.20", "1.99.99"}, nonMatching: []string{"1.10.9", "2.0.0"}},
+		">1.64":             {matching: []string{"1.65.0"}, nonMatching: []string{"1.64.0", "1.64.9"}},
+		">1.64.1":           {matching: []string{"1.64.2"}, nonMatching: []string{"1.64.1"}},
+		"<=1.64":            {matching: []string{"1.64.9", "1.0.0"}, nonMatching: []string{"1.65.0"}},
+		"=1.64":             {matching: []string{"1.64.0", "1.64.5"}, nonMatching: []string{"1.63.0", "1.65.0"}},
+		"=1.2.3":            {matching: []string{"1.2.3", "v1.2.3"}, nonMatching: []string{"1.2.4"}},
+		"latest":            {matching: []string{"0.0.1", "99.0.0"}, nonMatching: []string{"2.0.0-rc1"}},
+		"latest-prerelease": {matching: []string{"0.0.1", "2.0.0-rc1"}},
+		"latest, <2":        {matching: []string{"1.99.0"}, nonMatching: []string{"2.0.0"}},
+		"^2.0.0-rc1":        {matching: []string{"2.0.0-rc1", "2.0.0-rc2", "2.0.0", "2.1.0"}, nonMatching: []string{"2.0.0-beta1", "3.0.0"}},
+	}
+
+	for expr, testcase := range testcases {
+		c, err := ParseConstraint(expr)
+		require.NoError(t, err, expr)
+		for _, v := range testcase.matching {
+			assert.True(t, c.Check(v), "%q should satisfy %q", v, expr)
+		}
+		for _, v := range testcase.nonMatching {
+			assert.False(t, c.Check(v), "%q should not satisfy %q", v, expr)
+		}
+	}
+}
+
+func TestConstraintInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{"^", ">=foo", "1.2,", "~x.y"} {
+		_, err := ParseConstraint(expr)
+		require.ErrorIs(t, err, ErrInvalidConstraint, expr)
+	}
+}
+
+func TestConstraintHighest(t *testing.T) {
+	t.Parallel()
+
+	outer, err := ParseConstraint(">=1.11,<2")
+	require.NoError(t, err)
+	inner, err := ParseConstraint("~1.20")
+	require.NoError(t, err)
+
+	versions := []string{"1.10.0", "1.20.3", "1.21.0-rc1", "1.21.2", "1.20.10", "2.0.0"}
+	assert.Equal(t, "1.21.2", outer.Highest(versions))
+	assert.Equal(t, "1.20.10", inner.And(outer).Highest(versions))
+	assert.Equal(t, "~1.20, >=1.11, <2", inner.And(outer).String())
+	assert.Empty(t, inner.And(outer).Highest([]string{"1.10.0", "2.0.0"}))
+	assert.False(t, inner.And(outer).Latest())
+
+	latest, err := ParseConstraint("latest-prerelease")
+	require.NoError(t, err)
+	assert.True(t, latest.And(outer).Latest())
+	assert.True(t, latest.And(outer).Prereleases())
+	assert.Equal(t, "2.0.0", latest.Highest(versions))
+}
+
+func TestConstraintHighestRelease(t *testing.T) {
+	t.Parallel()
+
+	latest, err := ParseConstraint("latest")
+	require.NoError(t, err)
+
+	// Tags with non-numeric segments look like pre-releases unless the source reports otherwise.
+	versions := []string{"jq-1.6", "jq-1.7.1", "jq-1.8.0rc1"}
+	assert.Empty(t, latest.Highest(versions))
+
+	prereleases := map[string]bool{"jq-1.8.0rc1": true}
+	isPrerelease := func(v string) bool { return prereleases[v] }
+	assert.Equal(t, "jq-1.7.1", latest.HighestRelease(versions, isPrerelease))
+	assert.False(t, latest.CheckRelease("jq-1.8.0rc1", true))
+	assert.True(t, latest.CheckRelease("release-1.2", false))
+}
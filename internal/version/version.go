@@ -0,0 +1,86 @@
+// Package version orders tool versions and matches them against version constraints.
+package version
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Sort orders versions from oldest to newest. Numeric segments are compared by value rather than lexicographically so
+// that, for example, "1.10.0" is considered newer than "1.9.0".
+func Sort(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return Compare(versions[i], versions[j]) < 0
+	})
+}
+
+// Compare returns a negative number if version a is older than b, a positive number if it is newer and zero if both
+// are equivalent. A leading 'v' is ignored.
+func Compare(a, b string) int {
+	as, bs := versionSegments(a), versionSegments(b)
+	for idx := 0; idx < len(as) && idx < len(bs); idx++ {
+		an, aErr := strconv.Atoi(as[idx])
+		bn, bErr := strconv.Atoi(bs[idx])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return an - bn
+			}
+		case aErr == nil:
+			return 1
+		case bErr == nil:
+			return -1
+		default:
+			if c := strings.Compare(as[idx], bs[idx]); c != 0 {
+				return c
+			}
+		}
+	}
+	// When one version is a prefix of the other, a trailing non-numeric segment denotes a pre-release (e.g. "1.0.0-rc1")
+	// which precedes the shorter version whereas a trailing numeric segment (e.g. "1.0.0.1") succeeds it.
+	switch {
+	case len(as) > len(bs):
+		if _, err := strconv.Atoi(as[len(bs)]); err != nil {
+			return -1
+		}
+		return 1
+	case len(as) < len(bs):
+		if _, err := strconv.Atoi(bs[len(as)]); err != nil {
+			return 1
+		}
+		return -1
+	default:
+		return 0
+	}
+}
+
+func versionSegments(version string) []string {
+	version = strings.TrimPrefix(version, "v")
+
+	var (
+		segments []string
+		current  strings.Builder
+		isDigit  bool
+	)
+	for _, r := range version {
+		if !unicode.IsDigit(r) && !unicode.IsLetter(r) {
+			if current.Len() > 0 {
+				segments = append(segments, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		if current.Len() > 0 && unicode.IsDigit(r) != isDigit {
+			segments = append(segments, current.String())
+			current.Reset()
+		}
+		isDigit = unicode.IsDigit(r)
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		segments = append(segments, current.String())
+	}
+	return segments
+}
@@ -0,0 +1,15 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortVersions(t *testing.T) {
+	t.Parallel()
+
+	versions := []string{"1.10.0", "v1.9.0", "1.2.0-rc1", "1.2.0", "1.2.0.1", "0.9"}
+	Sort(versions)
+	assert.Equal(t, []string{"0.9", "1.2.0-rc1", "1.2.0", "1.2.0.1", "v1.9.0", "1.10.0"}, versions)
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -31,23 +34,43 @@ https://github.com/Helcaraxan/toolshare/docs/setup.md
 		},
 	}
 
-	registerRootFlags(rootCmd, opts)
+	if err := registerRootFlags(rootCmd, opts); err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
 
 	rootCmd.AddCommand(
+		driver.Add(opts),
+		driver.Cache(opts),
 		driver.Download(opts),
 		driver.Env(opts),
 		driver.Invoke(opts),
+		driver.Lock(opts),
+		driver.Mirror(opts),
+		driver.Pin(opts),
+		driver.State(opts),
 		driver.Sync(opts),
+		driver.Upgrade(opts),
 		driver.Versions(opts),
 	)
 
-	if err := rootCmd.Execute(); err != nil {
+	// An interrupt cancels any in-flight operation, such as a download, allowing for a clean abort. A second interrupt
+	// terminates the process immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Printf("%v\n", err)
+		stop()
 		os.Exit(1)
 	}
+	stop()
 }
 
-func registerRootFlags(cmd *cobra.Command, opts *driver.CommonOpts) {
+func registerRootFlags(cmd *cobra.Command, opts *driver.CommonOpts) error {
 	var defaultVal []string
 	if envFlag, ok := os.LookupEnv("TOOLSHARE_VERBOSE"); ok {
 		defaultVal = strings.Split(envFlag, ",")
@@ -61,4 +84,20 @@ func registerRootFlags(cmd *cobra.Command, opts *driver.CommonOpts) {
 		"Verbose output. See 'toolshare --help' for more information.",
 	)
 	cmd.Flag("verbose").NoOptDefVal = "all"
+
+	var defaultTimeout time.Duration
+	if envFlag, ok := os.LookupEnv("TOOLSHARE_TIMEOUT"); ok {
+		var err error
+		if defaultTimeout, err = time.ParseDuration(envFlag); err != nil {
+			return fmt.Errorf("invalid argument %q for TOOLSHARE_TIMEOUT: %w", envFlag, err)
+		}
+	}
+
+	cmd.PersistentFlags().DurationVar(
+		&opts.Timeout,
+		"timeout",
+		defaultTimeout,
+		"Overall deadline for fetching tools, e.g. '5m'. Overrides the 'timeout' configuration setting when set.",
+	)
+	return nil
 }